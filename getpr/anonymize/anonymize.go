@@ -0,0 +1,267 @@
+// 抽出したレビュー情報を社外やチーム間で共有できるよう、人と URL を仮名に置き換え、コメントの機密情報を伏せるパッケージ。
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+)
+
+// 伏せた箇所に書く文字列
+const Redacted = "[REDACTED]"
+
+// 既定で伏せる機密情報のパターン
+var defaultPatterns = []string{
+	// GitHubのアクセストークン
+	`\b(?:gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})`,
+	// GitLabのアクセストークン
+	`\bglpat-[A-Za-z0-9_-]{20,}`,
+	// AWSのアクセスキー
+	`\bAKIA[0-9A-Z]{16}\b`,
+	// Slackのトークン
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+	// Authorizationヘッダーのトークン
+	`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{8,}=*`,
+	// 「password: xxx」のようなパスワードやシークレットの記述
+	`(?i)\b(?:password|passwd|pwd|secret|token|api[_-]?key)\s*[:=]\s*\S+`,
+	// 秘密鍵
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// 伏せる対象から除くGitホスティングサービスのSaaS版のホスト名
+var publicHosts = map[string]bool{"github.com": true, "api.github.com": true, "gitlab.com": true, "gitea.com": true}
+
+// 仮名と元の値の対応表。仮名化を元に戻せるよう、抽出結果とは別のファイルに保存する。
+type Keys struct {
+	// URLやIDから仮名を作る際の秘密の値
+	Salt string `json:"salt"`
+	// ユーザー名ごとの仮名の記号（A、B、…）
+	People map[string]string `json:"people"`
+	// 仮名ごとの元のURLやID
+	Ids map[string]string `json:"ids"`
+}
+
+// 対応表のファイルを読み込む。ファイルが存在しない場合は新しい対応表を返す。
+func LoadKeys(path string) (*Keys, error) {
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, errors.New("匿名化の対応表を作成できません。")
+		}
+		return &Keys{Salt: hex.EncodeToString(salt), People: map[string]string{}, Ids: map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, errors.New("匿名化の対応表のファイルを読み込めません。anonymize-keysの設定を見直してください。")
+	}
+	var keys Keys
+	if err := json.Unmarshal(bs, &keys); err != nil || keys.Salt == "" {
+		return nil, errors.New("匿名化の対応表のファイルの形式が不正です。")
+	}
+	if keys.People == nil {
+		keys.People = map[string]string{}
+	}
+	if keys.Ids == nil {
+		keys.Ids = map[string]string{}
+	}
+	return &keys, nil
+}
+
+// 対応表をファイルへ保存する。
+func (keys *Keys) Save(path string) error {
+	bs, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, bs, 0600); err != nil {
+		return errors.New("匿名化の対応表を保存できません。anonymize-keysの設定を見直してください。")
+	}
+	return nil
+}
+
+// ユーザー名の仮名の記号を返す。初めて現れたユーザーには、対応表に登録済みの数に応じてA、B、…、Z、AA、…の順に割り当てる。
+func (keys *Keys) person(login string) string {
+	if code, ok := keys.People[login]; ok {
+		return code
+	}
+	code := ""
+	for n := len(keys.People); ; n = n/26 - 1 {
+		code = string(rune('A'+n%26)) + code
+		if n < 26 {
+			break
+		}
+	}
+	keys.People[login] = code
+	return code
+}
+
+// URLやIDを、対応表の秘密の値を使った推測できない仮名に置き換える。同じ値は常に同じ仮名になる。
+func (keys *Keys) id(prefix string, value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(keys.Salt))
+	mac.Write([]byte(value))
+	id := prefix + "-" + hex.EncodeToString(mac.Sum(nil))[:12]
+	keys.Ids[id] = value
+	return id
+}
+
+// 人とURLを仮名に置き換え、コメントの機密情報を伏せる処理。
+type Anonymizer struct {
+	keys     *Keys
+	patterns []*regexp.Regexp
+}
+
+// 対応表と、既定のパターンに加えて伏せるパターンから構築する。
+// endpointがSaaS版以外であれば、社内のホスト名とみなしてそのホスト名も伏せる。
+func New(keys *Keys, patterns []string, endpoint string) (*Anonymizer, error) {
+	anonymizer := &Anonymizer{keys: keys}
+	all := append(append([]string{}, defaultPatterns...), patterns...)
+	if u, err := url.Parse(endpoint); err == nil && u.Hostname() != "" && !publicHosts[u.Hostname()] {
+		all = append(all, `(?i)\b`+regexp.QuoteMeta(u.Hostname())+`\b`)
+	}
+	for _, pattern := range all {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.New("機密情報のパターンに不正な正規表現が指定されています。redact-patternsの設定を見直してください。")
+		}
+		anonymizer.patterns = append(anonymizer.patterns, compiled)
+	}
+	return anonymizer, nil
+}
+
+// JSONファイルから伏せるパターンの一覧を読み込む。パスが空の場合は空の一覧を返す。
+func LoadPatterns(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("機密情報のパターンのファイルを読み込めません。redact-patternsの設定を見直してください。")
+	}
+	var patterns []string
+	if err := json.Unmarshal(bs, &patterns); err != nil {
+		return nil, errors.New("機密情報のパターンのファイルの形式が不正です。正規表現の文字列の配列を書いてください。")
+	}
+	return patterns, nil
+}
+
+// 本文の中で単語として置き換える氏名や表示名の最短の文字数。短い名前は普通の単語と区別できないため置き換えない。
+const minNameLength = 3
+
+// コメント本文のURL。リンクの括弧や引用符、HTMLのタグの区切りは含めない。
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+
+// レビュー情報の人とURL、IDを仮名に置き換え、コメント本文と警告から機密情報を伏せる。
+//
+// 人はレビュアーであればReviewer-A、レビュイーであればReviewee-Aのように、対応表で割り当てた記号に役割を付けた名前にする。
+// コメント本文では@ユーザー名と、minNameLength文字以上の氏名や表示名を同じ名前に置き換え、URLも仮名にする。
+// ユーザー名だけの単語は普通の単語と区別できないため置き換えない。所属は組織ごとの分析に使えるよう残す。
+func (anonymizer *Anonymizer) Apply(pr *review.PullRequest) {
+	// コメント本文で置き換える@ユーザー名と氏名
+	words := make(map[string]string)
+	// ツールが書き出した警告で置き換えるユーザー名と氏名
+	warningWords := make(map[string]string)
+	participant := func(p *review.Participant) {
+		if p.Login == "" {
+			return
+		}
+		role := "Reviewer"
+		if p.Reviewee {
+			role = "Reviewee"
+		}
+		label := role + "-" + anonymizer.keys.person(p.Login)
+		words["@"+p.Login] = "@" + label
+		warningWords[p.Login] = label
+		for _, name := range []string{p.Name, p.DisplayName} {
+			if name == "" || name == p.Login {
+				continue
+			}
+			warningWords[name] = label
+			if len([]rune(name)) >= minNameLength {
+				words[name] = label
+			}
+		}
+		p.Login = label
+		p.Name = ""
+		p.DisplayName = ""
+	}
+	participant(&pr.Author)
+	for i := range pr.Threads {
+		for j := range pr.Threads[i].Comments {
+			participant(&pr.Threads[i].Comments[j].Author)
+		}
+	}
+
+	pr.Url = anonymizer.keys.id("pr", pr.Url)
+	for i := range pr.Threads {
+		thread := &pr.Threads[i]
+		thread.Id = anonymizer.keys.id("thread", thread.Id)
+		thread.Url = anonymizer.keys.id("url", thread.Url)
+		for j := range thread.Comments {
+			comment := &thread.Comments[j]
+			comment.Id = anonymizer.keys.id("comment", comment.Id)
+			comment.Url = anonymizer.keys.id("url", comment.Url)
+			comment.Body = anonymizer.text(comment.Body, words)
+			comment.Html = anonymizer.text(comment.Html, words)
+		}
+	}
+	for i, warning := range pr.Warnings {
+		pr.Warnings[i] = anonymizer.text(warning, warningWords)
+	}
+}
+
+// テキストのURLを仮名に置き換えて機密情報を伏せ、wordsのキーの単語を値に置き換える。
+func (anonymizer *Anonymizer) text(s string, words map[string]string) string {
+	if s == "" {
+		return s
+	}
+	s = urlPattern.ReplaceAllStringFunc(s, func(u string) string {
+		// 文末の句読点はURLに含めない
+		trimmed := strings.TrimRight(u, ".,;:!?")
+		return anonymizer.keys.id("url", trimmed) + u[len(trimmed):]
+	})
+	for _, pattern := range anonymizer.patterns {
+		s = pattern.ReplaceAllLiteralString(s, Redacted)
+	}
+	// 長い単語から置き換え、別の単語の一部を置き換えないようにする
+	keys := make([]string, 0, len(words))
+	for word := range words {
+		keys = append(keys, word)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	for _, word := range keys {
+		s = replaceName(s, word, words[word])
+	}
+	return s
+}
+
+// 単語の一部でない箇所の名前を置き換える。
+func replaceName(s, name, label string) string {
+	pattern := regexp.MustCompile(`(^|[^\p{L}\p{N}_.-])` + regexp.QuoteMeta(name) + `($|[^\p{L}\p{N}_-])`)
+	// 隣り合う名前を取りこぼさないよう、置き換えがなくなるまで繰り返す
+	for {
+		replaced := pattern.ReplaceAllString(s, "${1}"+strings.ReplaceAll(label, "$", "$$")+"${2}")
+		if replaced == s {
+			return s
+		}
+		s = replaced
+	}
+}
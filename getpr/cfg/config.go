@@ -1,107 +1,245 @@
-package cfg
-
-import (
-	"errors"
-	"flag"
-)
-
-// ツールの設定を保持する構造体。
-type Config struct {
-	Target           string
-	Endpoint         string
-	AccessToken      string
-	Org              string
-	Repo             string
-	Pull             string
-	PostScriptPrefix string
-	Delimiter        string
-	ReviewTimes      string
-	UseDiffCount     bool
-	CsvFile          string
-	UseSjisFile      bool
-	UseSjisStdErr    bool
-	Proxy            string
-	PageSize         int
-}
-
-// コマンドライン引数をパースするための設定を行う。
-func (config *Config) ConfigureFlag() {
-	flag.StringVar(&config.Target, "target", "", "Gitホスティングサービス。github、gitlab、gitbucketのいずれかの値。")
-	flag.StringVar(&config.Endpoint, "endpoint", "", "APIのルートURI。GitHub、GitLabのSaaS版は設定不要。")
-	flag.StringVar(&config.AccessToken, "access-token", "", "APIを使用するためのアクセストークン。GitBucketはユーザー名とパスワードをコロンで繋いだものを設定する。")
-	flag.StringVar(&config.Org, "org", "", "オーガニゼーション。GitLabは設定不要。")
-	flag.StringVar(&config.Repo, "repo", "", "リポジトリ名（GitHub、GitBucket）、またはプロジェクトID（GitLab）。")
-	flag.StringVar(&config.Pull, "pull", "", "プルリクエスト（マージリクエスト）のID。")
-	flag.StringVar(&config.PostScriptPrefix, "post-script-prefix", "(追記)", "スレッド形式のコメントをまとめる際、2つめ以降のコメントに付けるプレフィックス。")
-	flag.StringVar(&config.Delimiter, "delimiter", "~~", "レビュアーのコメントとレビュイーのコメントを分けるデリミタ。")
-	flag.StringVar(&config.ReviewTimes, "review-times", "1", "レビュー回数。")
-	flag.BoolVar(&config.UseDiffCount, "use-diff-count", true, "差分の行数カウントを使用するかどうかを切り替えるフラグ。")
-	flag.StringVar(&config.CsvFile, "csv-file", "", "出力するCSVファイルのパス。")
-	flag.BoolVar(&config.UseSjisFile, "use-sjis-file", true, "出力するCSVファイルの文字コードをShift_JISにするフラグ。このフラグがfalseの場合、CSVファイルはUTF-8で出力される。")
-	flag.BoolVar(&config.UseSjisStdErr, "use-sjis-stderr", true, "標準エラー出力へ書き出す文字コードをShift_JISにするフラグ。このフラグがfalseの場合、標準エラー出力へはUTF-8で出力される。")
-	flag.StringVar(&config.Proxy, "proxy", "", "プロキシ。http://proxy.example.com:3128 といった形式で設定する。")
-	flag.IntVar(&config.PageSize, "page-size", 100, "ページングを行う場合の1ページあたりのサイズ。")
-}
-
-const (
-	github    = "github"
-	gitlab    = "gitlab"
-	gitbucket = "gitbucket"
-)
-
-// バリデーションを行う。
-func (config *Config) Validate() error {
-	if len(config.Target) == 0 {
-		return errors.New("Gitホスティングサービスを設定してください。")
-	}
-	if config.Target != github && config.Target != gitlab && config.Target != gitbucket {
-		return errors.New("Gitホスティングサービスはgithub、gitlab、gitbucketのいずれかを設定してください。")
-	}
-	if config.Target == gitbucket && len(config.Endpoint) == 0 {
-		return errors.New("エンドポイントを設定してください。")
-	}
-	if len(config.AccessToken) == 0 {
-		return errors.New("アクセストークンを設定してください。")
-	}
-	if (config.Target == github || config.Target == gitbucket) && len(config.Org) == 0 {
-		return errors.New("オーガニゼーションを設定してください。")
-	}
-	if len(config.Repo) == 0 {
-		switch config.Target {
-		case github, gitbucket:
-			return errors.New("リポジトリ名を設定してください。")
-		case gitlab:
-			return errors.New("プロジェクトIDを設定してください。")
-		}
-	}
-	if len(config.Pull) == 0 {
-		switch config.Target {
-		case github, gitbucket:
-			return errors.New("プルリクエストのIDを設定してください。")
-		case gitlab:
-			return errors.New("マージリクエストのIDを設定してください。")
-		}
-	}
-	if config.Target != gitlab && len(config.Delimiter) == 0 {
-		return errors.New("デリミタを設定してください。")
-	}
-	if len(config.ReviewTimes) == 0 {
-		return errors.New("レビュー回数を設定してください。")
-	}
-	if len(config.CsvFile) == 0 {
-		return errors.New("CSVファイルのパスを設定してください。")
-	}
-	if !(1 <= config.PageSize && config.PageSize <= 100) {
-		return errors.New("ページサイズは1〜100の値を設定してください。")
-	}
-	return nil
-}
-
-// Gitホスティングサービスに応じて適切なエンドポイントを設定する。
-func (config *Config) SetupEndpoint() {
-	if config.Target == github {
-		config.Endpoint = "https://api.github.com/graphql"
-	} else if config.Target == gitlab && len(config.Endpoint) == 0 {
-		config.Endpoint = "https://gitlab.com/api/v4"
-	}
-}
+package cfg
+
+import (
+	"errors"
+	"flag"
+	"regexp"
+	"time"
+
+	// 実行環境にタイムゾーンデータベースがない場合（Windowsなど）に備えて埋め込む
+	_ "time/tzdata"
+)
+
+// ツールの設定を保持する構造体。
+type Config struct {
+	Target               string
+	Endpoint             string
+	AccessToken          string
+	Org                  string
+	Repo                 string
+	Pull                 string
+	PostScriptPrefix     string
+	Delimiter            string
+	ExtraDelimiters      []string
+	DelimiterPattern     string
+	ReviewTimes          string
+	AllReviewTimes       bool
+	UseDiffCount         bool
+	CsvFile              string
+	JsonFile             string
+	UseSjisFile          bool
+	UseSjisStdErr        bool
+	Proxy                string
+	PageSize             int
+	Reviewees            string
+	IncludeAssignees     bool
+	IncludeCommitAuthors bool
+	EstimateReviewTime   bool
+	IdleGap              int
+	Timezone             string
+	Timeout              int
+	RequestTimeout       int
+	Concurrency          int
+	CacheDir             string
+	FromCache            bool
+	Record               string
+	Replay               string
+	TagRules             string
+	StripTags            bool
+	BodyFormat           string
+	StripQuotes          bool
+	UserDirectory        string
+	UseHostNames         bool
+	Anonymize            bool
+	AnonymizeKeys        string
+	RedactPatterns       string
+	SinceState           string
+	PostSummary          bool
+}
+
+// 指定されたFlagSetにコマンドライン引数をパースするための設定を行う。
+func (config *Config) ConfigureFlag(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&config.Target, "target", "", "Gitホスティングサービス。github、gitlab、gitbucket、giteaのいずれかの値。")
+	flagSet.StringVar(&config.Endpoint, "endpoint", "", "APIのルートURI。GitHub、GitLab、GiteaのSaaS版は設定不要。")
+	flagSet.StringVar(&config.AccessToken, "access-token", "", "APIを使用するためのアクセストークン。GitBucketはユーザー名とパスワードをコロンで繋いだものを設定する。")
+	flagSet.StringVar(&config.Org, "org", "", "オーガニゼーション。GitLabは設定不要。")
+	flagSet.StringVar(&config.Repo, "repo", "", "リポジトリ名（GitHub、GitBucket、Gitea）、またはプロジェクトID（GitLab）。")
+	flagSet.StringVar(&config.Pull, "pull", "", "プルリクエスト（マージリクエスト）のID。")
+	flagSet.StringVar(&config.PostScriptPrefix, "post-script-prefix", "(追記)", "スレッド形式のコメントをまとめる際、2つめ以降のコメントに付けるプレフィックス。")
+	flagSet.StringVar(&config.Delimiter, "delimiter", "~~", "レビュアーのコメントとレビュイーのコメントを分けるデリミタ。")
+	flagSet.Func("extra-delimiter", "delimiterに加えてデリミタとみなす文字列。繰り返し指定できる。", func(value string) error {
+		config.ExtraDelimiters = append(config.ExtraDelimiters, value)
+		return nil
+	})
+	flagSet.StringVar(&config.DelimiterPattern, "delimiter-pattern", "", "デリミタとみなす行の正規表現。前後の空白を除いた行全体に一致するものをデリミタとみなす。")
+	flagSet.StringVar(&config.ReviewTimes, "review-times", "1", "レビュー回数。")
+	flagSet.BoolVar(&config.AllReviewTimes, "all-review-times", false, "全てのレビュー回のレビュー日時コメントを読み取り、指摘を作成日時からレビュー回へ割り当てるフラグ。このフラグがtrueの場合、review-timesは無視される。")
+	flagSet.BoolVar(&config.UseDiffCount, "use-diff-count", true, "差分の行数カウントを使用するかどうかを切り替えるフラグ。")
+	flagSet.StringVar(&config.CsvFile, "csv-file", "", "出力するCSVファイルのパス。")
+	flagSet.StringVar(&config.JsonFile, "json-file", "", "スレッド内の個々のコメントを含むレビュー情報を書き出すJSONファイルのパス。省略した場合はJSONファイルを書き出さない。")
+	flagSet.BoolVar(&config.UseSjisFile, "use-sjis-file", true, "出力するCSVファイルの文字コードをShift_JISにするフラグ。このフラグがfalseの場合、CSVファイルはUTF-8で出力される。")
+	flagSet.BoolVar(&config.UseSjisStdErr, "use-sjis-stderr", true, "標準エラー出力へ書き出す文字コードをShift_JISにするフラグ。このフラグがfalseの場合、標準エラー出力へはUTF-8で出力される。")
+	flagSet.StringVar(&config.Proxy, "proxy", "", "プロキシ。http://proxy.example.com:3128 といった形式で設定する。")
+	flagSet.IntVar(&config.PageSize, "page-size", 100, "ページングを行う場合の1ページあたりのサイズ。")
+	flagSet.StringVar(&config.Reviewees, "reviewees", "", "プルリクエスト作成者以外にレビュイーとみなすユーザー名。複数指定する場合はカンマで区切る。")
+	flagSet.BoolVar(&config.IncludeAssignees, "include-assignees", false, "プルリクエストのアサイン先をレビュイーとみなすフラグ。GitBucketでは無視される。")
+	flagSet.BoolVar(&config.IncludeCommitAuthors, "include-commit-authors", false, "プルリクエストに含まれるコミットの作成者をレビュイーとみなすフラグ。GitBucketでは無視される。")
+	flagSet.BoolVar(&config.EstimateReviewTime, "estimate-review-time", false, "レビュー日時コメントが見つからない場合に、レビュアーのコメントの投稿日時からレビュー時間を推定するフラグ。")
+	flagSet.IntVar(&config.IdleGap, "idle-gap", 30, "レビュー時間を推定する際、同じレビューとみなすコメントの投稿間隔の上限（分）。")
+	flagSet.IntVar(&config.Timeout, "timeout", 0, "処理全体の制限時間（秒）。0の場合は制限しない。")
+	flagSet.IntVar(&config.RequestTimeout, "request-timeout", 60, "1回のHTTPリクエストの制限時間（秒）。0の場合は制限しない。")
+	flagSet.IntVar(&config.Concurrency, "concurrency", 4, "APIへ並行して送信するリクエストの数の上限。")
+	flagSet.StringVar(&config.CacheDir, "cache-dir", "", "APIのレスポンスを保存するディレクトリ。指定した場合、保存したレスポンスのETagやLast-Modifiedを使って条件付きリクエストを送信する。")
+	flagSet.BoolVar(&config.FromCache, "from-cache", false, "APIへリクエストを送信せず、cache-dirに保存したレスポンスだけを使用するフラグ。")
+	flagSet.StringVar(&config.Record, "record", "", "APIとのやり取りを記録するディレクトリ。アクセストークンやパスワード、Cookieは伏せて記録する。")
+	flagSet.StringVar(&config.Replay, "replay", "", "recordで記録したやり取りを再生するディレクトリ。指定した場合、APIへリクエストを送信しない。")
+	flagSet.StringVar(&config.TagRules, "tag-rules", "", "指摘の重要度と種別を読み取るタグの規則を書いたJSONファイルのパス。省略した場合は既定の規則を使用する。")
+	flagSet.BoolVar(&config.StripTags, "strip-tags", false, "重要度や種別として読み取ったタグをコメント本文から取り除くフラグ。")
+	flagSet.StringVar(&config.BodyFormat, "body-format", BodyMarkdown, "CSVファイルへ書き出すコメント本文の形式。markdown、plain、htmlのいずれかの値。")
+	flagSet.BoolVar(&config.StripQuotes, "strip-quotes", false, "スレッド形式の指摘の対応内容から、引用（行頭が>）の行を取り除くフラグ。")
+	flagSet.StringVar(&config.UserDirectory, "user-directory", "", "ユーザー名と氏名、所属を「ユーザー名,氏名,所属」の形式で書いたCSVファイルのパス。指定した場合、CSVファイルにはユーザー名の代わりに氏名と所属を書き出す。")
+	flagSet.BoolVar(&config.UseHostNames, "use-host-names", false, "user-directoryに登録されていないユーザーの名前に、Gitホスティングサービスの表示名を使用するフラグ。GitBucketでは無視される。")
+	flagSet.BoolVar(&config.Anonymize, "anonymize", false, "ユーザー名とURLを仮名に置き換え、コメント本文のアクセストークンやパスワード、社内のホスト名を伏せて書き出すフラグ。")
+	flagSet.StringVar(&config.AnonymizeKeys, "anonymize-keys", "", "仮名と元の値の対応表を保存するJSONファイルのパス。ファイルが存在する場合は、同じユーザーやURLに前回と同じ仮名を割り当てる。")
+	flagSet.StringVar(&config.RedactPatterns, "redact-patterns", "", "anonymizeで既定のものに加えて伏せる正規表現の配列を書いたJSONファイルのパス。")
+	flagSet.StringVar(&config.SinceState, "since-state", "", "書き出した指摘を記録する状態のJSONファイルのパス。指定した場合、前回の抽出から新しい指摘や返信、解決状態の変化、編集があった指摘だけを変更種別を付けて書き出す。")
+	flagSet.BoolVar(&config.PostSummary, "post-summary", false, "抽出後に、指摘件数や解決状況、レビュー時間、欠陥密度のまとめをプルリクエストにコメントとして投稿するフラグ。2回目以降は投稿したコメントを更新する。GitBucketでは指定できない。")
+	flagSet.StringVar(&config.Timezone, "timezone", "Asia/Tokyo", "日時を扱う際のタイムゾーン。CSVファイルへ書き出す日時と、レビュー日時コメントの日時の解釈に使用する。")
+}
+
+const (
+	github    = "github"
+	gitlab    = "gitlab"
+	gitbucket = "gitbucket"
+	gitea     = "gitea"
+)
+
+// コメント本文の形式
+const (
+	// 取得したまま。GitHubとGitLab、Giteaはマークダウン、GitBucketはHTMLをパースして得たテキスト。
+	BodyMarkdown = "markdown"
+	// 装飾のないテキスト
+	BodyPlain = "plain"
+	// HTML
+	BodyHtml = "html"
+)
+
+// delimiterとextra-delimiterで指定された、空でないデリミタの一覧を返す。
+func (config *Config) Delimiters() []string {
+	delimiters := make([]string, 0, 1+len(config.ExtraDelimiters))
+	for _, delimiter := range append([]string{config.Delimiter}, config.ExtraDelimiters...) {
+		if len(delimiter) > 0 {
+			delimiters = append(delimiters, delimiter)
+		}
+	}
+	return delimiters
+}
+
+// バリデーションを行う。
+func (config *Config) Validate() error {
+	if len(config.Target) == 0 {
+		return errors.New("Gitホスティングサービスを設定してください。")
+	}
+	if config.Target != github && config.Target != gitlab && config.Target != gitbucket && config.Target != gitea {
+		return errors.New("Gitホスティングサービスはgithub、gitlab、gitbucket、giteaのいずれかを設定してください。")
+	}
+	if config.Target == gitbucket && len(config.Endpoint) == 0 {
+		return errors.New("エンドポイントを設定してください。")
+	}
+	if len(config.AccessToken) == 0 {
+		return errors.New("アクセストークンを設定してください。")
+	}
+	if config.Target != gitlab && len(config.Org) == 0 {
+		return errors.New("オーガニゼーションを設定してください。")
+	}
+	if len(config.Repo) == 0 {
+		switch config.Target {
+		case github, gitbucket, gitea:
+			return errors.New("リポジトリ名を設定してください。")
+		case gitlab:
+			return errors.New("プロジェクトIDを設定してください。")
+		}
+	}
+	if len(config.Pull) == 0 {
+		switch config.Target {
+		case github, gitbucket, gitea:
+			return errors.New("プルリクエストのIDを設定してください。")
+		case gitlab:
+			return errors.New("マージリクエストのIDを設定してください。")
+		}
+	}
+	if config.Target != gitlab && len(config.Delimiters()) == 0 && len(config.DelimiterPattern) == 0 {
+		return errors.New("デリミタを設定してください。")
+	}
+	if _, err := regexp.Compile(config.DelimiterPattern); err != nil {
+		return errors.New("デリミタの正規表現に不正な値が指定されています。")
+	}
+	if len(config.ReviewTimes) == 0 {
+		return errors.New("レビュー回数を設定してください。")
+	}
+	if !(1 <= config.PageSize && config.PageSize <= 100) {
+		return errors.New("ページサイズは1〜100の値を設定してください。")
+	}
+	if config.EstimateReviewTime && config.IdleGap < 1 {
+		return errors.New("コメントの投稿間隔の上限は1以上の値を設定してください。")
+	}
+	if config.Concurrency < 1 {
+		return errors.New("並行して送信するリクエストの数の上限は1以上の値を設定してください。")
+	}
+	if config.Timeout < 0 || config.RequestTimeout < 0 {
+		return errors.New("制限時間は0以上の値を設定してください。")
+	}
+	if config.FromCache && len(config.CacheDir) == 0 {
+		return errors.New("from-cacheを指定する場合はキャッシュのディレクトリを設定してください。")
+	}
+	if len(config.Record) != 0 && len(config.Replay) != 0 {
+		return errors.New("recordとreplayは同時に指定できません。")
+	}
+	if config.BodyFormat != "" && config.BodyFormat != BodyMarkdown && config.BodyFormat != BodyPlain && config.BodyFormat != BodyHtml {
+		return errors.New("コメント本文の形式はmarkdown、plain、htmlのいずれかを設定してください。")
+	}
+	if config.PostSummary && config.Target == gitbucket {
+		return errors.New("GitBucketではpost-summaryを指定できません。")
+	}
+	if config.PostSummary && (len(config.Replay) != 0 || config.FromCache) {
+		return errors.New("post-summaryはreplayやfrom-cacheと同時に指定できません。")
+	}
+	if config.Anonymize && len(config.AnonymizeKeys) == 0 {
+		return errors.New("anonymizeを指定する場合は対応表のファイルのパスを設定してください。")
+	}
+	if config.PostSummary && config.Anonymize {
+		return errors.New("post-summaryはanonymizeと同時に指定できません。")
+	}
+	if _, err := time.LoadLocation(config.Timezone); err != nil {
+		return errors.New("タイムゾーンに不正な値が指定されています。Asia/Tokyoのような形式で設定してください。")
+	}
+	return nil
+}
+
+// 出力先の設定のバリデーションを行う。
+func (config *Config) ValidateOutput() error {
+	if len(config.CsvFile) == 0 {
+		return errors.New("CSVファイルのパスを設定してください。")
+	}
+	return nil
+}
+
+// 設定されたタイムゾーンを返す。タイムゾーンが不正な場合はローカルタイムゾーンを返す。
+func (config *Config) Location() *time.Location {
+	loc, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Gitホスティングサービスに応じて適切なエンドポイントを設定する。
+func (config *Config) SetupEndpoint() {
+	if config.Target == github && len(config.Endpoint) == 0 {
+		config.Endpoint = "https://api.github.com/graphql"
+	} else if config.Target == gitlab && len(config.Endpoint) == 0 {
+		config.Endpoint = "https://gitlab.com/api/v4"
+	} else if config.Target == gitea && len(config.Endpoint) == 0 {
+		config.Endpoint = "https://gitea.com/api/v1"
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/Fintan-contents/review-support-tool/getpr"
+	"github.com/Fintan-contents/review-support-tool/getpr/csv"
+	"github.com/Fintan-contents/review-support-tool/getpr/diff"
+	"github.com/Fintan-contents/review-support-tool/getpr/json"
+)
+
+// json-fileで書き出した同じプルリクエストの2つのJSONファイルを比較し、その間のレビューの動きをCSV形式で書き出す。
+func runDiff(args []string) error {
+	flagSet := flag.NewFlagSet(os.Args[0]+" diff", flag.ExitOnError)
+	csvFile := flagSet.String("csv-file", "", "比較の結果を書き出すCSVファイルのパス。省略した場合は標準出力へ書き出す。")
+	flagSet.BoolVar(&config.UseSjisStdErr, "use-sjis-stderr", true, "標準エラー出力へ書き出す文字コードをShift_JISにするフラグ。このフラグがfalseの場合、標準エラー出力へはUTF-8で出力される。")
+	useSjisFile := flagSet.Bool("use-sjis-file", true, "出力するCSVの文字コードをShift_JISにするフラグ。このフラグがfalseの場合、UTF-8で出力される。")
+	flagSet.StringVar(&config.Timezone, "timezone", "Asia/Tokyo", "CSVファイルへ書き出す日時のタイムゾーン。")
+	flagSet.Usage = func() {
+		io.WriteString(flagSet.Output(), "Usage: "+flagSet.Name()+" [options] 古いJSONファイル 新しいJSONファイル\n")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+	if flagSet.NArg() != 2 {
+		return errors.New("json-fileで書き出した古いJSONファイルと新しいJSONファイルのパスを指定してください。")
+	}
+
+	older, err := json.ReadJson(flagSet.Arg(0))
+	if err != nil {
+		return errors.New("JSONファイルを読み込めません。" + flagSet.Arg(0))
+	}
+	newer, err := json.ReadJson(flagSet.Arg(1))
+	if err != nil {
+		return errors.New("JSONファイルを読み込めません。" + flagSet.Arg(1))
+	}
+	d, err := diff.Compare(older, newer)
+	if err != nil {
+		return err
+	}
+	d.In(config.Location())
+
+	if *csvFile == "" {
+		return csv.WriteDiff(os.Stdout, d, *useSjisFile)
+	}
+	// 書き出しに失敗した場合は書きかけのファイルを削除する
+	return getpr.WriteFile(*csvFile, func(file io.Writer) error {
+		return csv.WriteDiff(file, d, *useSjisFile)
+	})
+}
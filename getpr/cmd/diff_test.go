@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/internal/fakeserver"
+)
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	scenario := newScenario()
+	server := servers["github"](scenario)
+	defer server.Close()
+	extract := func(name string) string {
+		jsonFile := filepath.Join(dir, name+".json")
+		err := run(append(baseArgs("github", server.URL, filepath.Join(dir, name+".csv")), "-all-review-times", "-json-file", jsonFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return jsonFile
+	}
+	older := extract("older")
+
+	scenario.Threads[1].Resolved = true
+	scenario.Threads[1].Comments = append(scenario.Threads[1].Comments, fakeserver.Comment{Author: "author", Body: "共通化しました。", CreatedAt: at(14, 0)})
+	scenario.Comments = append(scenario.Comments,
+		fakeserver.Comment{Author: "alice", Body: "[must] テストを追加してください。", CreatedAt: at(14, 30)},
+		fakeserver.Comment{Author: "alice", Body: "レビュー2回目\n2024/5/1\n14:00\n14:30\n30", CreatedAt: at(14, 30)},
+	)
+	newer := extract("newer")
+
+	csvFile := filepath.Join(dir, "diff.csv")
+	if err := runDiff([]string{"-csv-file", csvFile, "-use-sjis-file=false", older, newer}); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filepath.Join("testdata", "diff.csv"), actual)
+}
+
+func TestRunDiffWithoutJsonFiles(t *testing.T) {
+	err := runDiff([]string{"older.json"})
+	if err == nil || err.Error() != "json-fileで書き出した古いJSONファイルと新しいJSONファイルのパスを指定してください。" {
+		t.Errorf("JSONファイルが2つ指定されなければエラーになる想定です。実際の値：%v", err)
+	}
+}
@@ -1,69 +1,131 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strings"
 
+	"github.com/Fintan-contents/review-support-tool/getpr"
 	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
-	"github.com/Fintan-contents/review-support-tool/getpr/csv"
-	"github.com/Fintan-contents/review-support-tool/getpr/git"
 	"golang.org/x/text/encoding/japanese"
 	"golang.org/x/text/transform"
 )
 
-var config *cfg.Config
-
-func init() {
-	config = &cfg.Config{}
-	config.ConfigureFlag()
-}
+var config = &cfg.Config{}
 
 // Gitからレビュー情報を取得し、csvファイルに出力する。
+// 1つめの引数がmetricsの場合は、抽出したJSONファイルからレビューの指標を算出する。
+// 1つめの引数がdiffの場合は、同じプルリクエストの2つのJSONファイルを比較する。
+// 1つめの引数がserveの場合は、Webhookを受け付けるたびにレビュー情報を抽出する。
 func main() {
-	err := run()
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "metrics" {
+		err = runMetrics(os.Args[2:])
+	} else if len(os.Args) > 1 && os.Args[1] == "diff" {
+		err = runDiff(os.Args[2:])
+	} else if len(os.Args) > 1 && os.Args[1] == "serve" {
+		err = runServe(os.Args[2:])
+	} else {
+		err = run(os.Args[1:])
+	}
 	if err != nil {
-		var writer io.Writer
-		if config.UseSjisStdErr {
-			writer = transform.NewWriter(os.Stderr, japanese.ShiftJIS.NewEncoder())
-		} else {
-			writer = os.Stderr
-		}
-		fmt.Fprint(writer, err)
+		fmt.Fprint(stderr(), err)
 		os.Exit(1)
 		return
 	}
 }
 
-func run() error {
-	flag.Parse()
-
-	if err := config.Validate(); err != nil {
-		return err
+// 設定に応じた文字コードで標準エラー出力へ書き出すWriterを返す。
+func stderr() io.Writer {
+	if config.UseSjisStdErr {
+		return transform.NewWriter(os.Stderr, japanese.ShiftJIS.NewEncoder())
 	}
+	return os.Stderr
+}
 
-	config.SetupEndpoint()
+// コマンドライン引数に従ってレビュー情報を抽出し、ファイルへ書き出す。
+func run(args []string) error {
+	flagSet := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.ConfigureFlag(flagSet)
+	flagSet.Parse(args)
 
-	// 構造体の準備をする
-	httpClient, err := git.BuildHttpClient(config)
-	if err != nil {
+	if err := config.Validate(); err != nil {
 		return err
 	}
-	gitService, err := git.BuildGitService(config, httpClient)
-	if err != nil {
+	if err := config.ValidateOutput(); err != nil {
 		return err
 	}
 
-	// プルリクエストの情報を取得する
-	data, err := gitService.ParsePullRequest()
+	// プルリクエストの情報を取得してCSVへ書き出す
+	options := newOptions(config)
+	options.Writers = []getpr.Writer{&getpr.CsvFileWriter{Path: config.CsvFile, UseSjis: config.UseSjisFile}}
+	if len(config.JsonFile) > 0 {
+		options.Writers = append(options.Writers, &getpr.JsonFileWriter{Path: config.JsonFile})
+	}
+	// Ctrl+Cで中断できるようにする。中断した場合、書きかけのファイルは削除される
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := getpr.Extract(ctx, options)
 	if err != nil {
 		return err
 	}
 
-	// CSVへ書き出す
-	if err := csv.WriteCsv(config.CsvFile, data, config.UseSjisFile); err != nil {
-		return err
+	// 警告は処理を止めずに標準エラー出力へ書き出す
+	for _, warning := range result.Warnings {
+		fmt.Fprintln(stderr(), "警告："+warning)
 	}
 	return nil
 }
+
+// コマンドライン引数の設定を抽出の設定に変換する。出力先の設定は含まないため、Writersを設定すること。
+func newOptions(config *cfg.Config) getpr.Options {
+	requestTimeout := config.RequestTimeout
+	if requestTimeout == 0 {
+		// コマンドライン引数の0は制限しないことを表す
+		requestTimeout = -1
+	}
+	return getpr.Options{
+		Target:               config.Target,
+		Endpoint:             config.Endpoint,
+		AccessToken:          config.AccessToken,
+		Org:                  config.Org,
+		Repo:                 config.Repo,
+		Pull:                 config.Pull,
+		PostScriptPrefix:     config.PostScriptPrefix,
+		Delimiter:            config.Delimiter,
+		ExtraDelimiters:      config.ExtraDelimiters,
+		DelimiterPattern:     config.DelimiterPattern,
+		ReviewTimes:          config.ReviewTimes,
+		AllReviewTimes:       config.AllReviewTimes,
+		PageSize:             config.PageSize,
+		Reviewees:            strings.Split(config.Reviewees, ","),
+		IncludeAssignees:     config.IncludeAssignees,
+		IncludeCommitAuthors: config.IncludeCommitAuthors,
+		EstimateReviewTime:   config.EstimateReviewTime,
+		IdleGap:              config.IdleGap,
+		Timezone:             config.Timezone,
+		Timeout:              config.Timeout,
+		RequestTimeout:       requestTimeout,
+		Concurrency:          config.Concurrency,
+		Proxy:                config.Proxy,
+		CacheDir:             config.CacheDir,
+		FromCache:            config.FromCache,
+		Record:               config.Record,
+		Replay:               config.Replay,
+		TagRules:             config.TagRules,
+		StripTags:            config.StripTags,
+		BodyFormat:           config.BodyFormat,
+		StripQuotes:          config.StripQuotes,
+		UserDirectory:        config.UserDirectory,
+		UseHostNames:         config.UseHostNames,
+		Anonymize:            config.Anonymize,
+		AnonymizeKeys:        config.AnonymizeKeys,
+		RedactPatterns:       config.RedactPatterns,
+		SinceState:           config.SinceState,
+		PostSummary:          config.PostSummary,
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/Fintan-contents/review-support-tool/getpr"
+	"github.com/Fintan-contents/review-support-tool/getpr/csv"
+	"github.com/Fintan-contents/review-support-tool/getpr/json"
+	"github.com/Fintan-contents/review-support-tool/getpr/metrics"
+)
+
+// json-fileで書き出したJSONファイルを読み込み、プルリクエストごとの指標と合計をCSV形式で書き出す。
+func runMetrics(args []string) error {
+	flagSet := flag.NewFlagSet(os.Args[0]+" metrics", flag.ExitOnError)
+	csvFile := flagSet.String("csv-file", "", "指標を書き出すCSVファイルのパス。省略した場合は標準出力へ書き出す。")
+	flagSet.BoolVar(&config.UseSjisStdErr, "use-sjis-stderr", true, "標準エラー出力へ書き出す文字コードをShift_JISにするフラグ。このフラグがfalseの場合、標準エラー出力へはUTF-8で出力される。")
+	useSjisFile := flagSet.Bool("use-sjis-file", true, "出力するCSVの文字コードをShift_JISにするフラグ。このフラグがfalseの場合、UTF-8で出力される。")
+	flagSet.Usage = func() {
+		io.WriteString(flagSet.Output(), "Usage: "+flagSet.Name()+" [options] JSONファイル...\n")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+	if flagSet.NArg() == 0 {
+		return errors.New("json-fileで書き出したJSONファイルのパスを指定してください。")
+	}
+
+	rows := make([]*metrics.Metrics, 0, flagSet.NArg())
+	for _, path := range flagSet.Args() {
+		pr, err := json.ReadJson(path)
+		if err != nil {
+			return errors.New("JSONファイルを読み込めません。" + path)
+		}
+		rows = append(rows, metrics.Compute(pr))
+	}
+	total := metrics.Aggregate(rows)
+
+	if *csvFile == "" {
+		return csv.WriteMetrics(os.Stdout, rows, total, *useSjisFile)
+	}
+	// 書き出しに失敗した場合は書きかけのファイルを削除する
+	return getpr.WriteFile(*csvFile, func(file io.Writer) error {
+		return csv.WriteMetrics(file, rows, total, *useSjisFile)
+	})
+}
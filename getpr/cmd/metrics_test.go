@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunMetrics(t *testing.T) {
+	dir := t.TempDir()
+	jsonFiles := make([]string, 0)
+	for _, target := range []string{"github", "gitlab"} {
+		server := servers[target](newManyPagesScenario())
+		jsonFile := filepath.Join(dir, target+".json")
+		err := run(append(baseArgs(target, server.URL, filepath.Join(dir, target+".csv")), "-json-file", jsonFile))
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		jsonFiles = append(jsonFiles, jsonFile)
+	}
+
+	csvFile := filepath.Join(dir, "metrics.csv")
+	if err := runMetrics(append([]string{"-csv-file", csvFile, "-use-sjis-file=false"}, jsonFiles...)); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "metrics.csv")
+	if *update {
+		if err := os.WriteFile(golden, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("CSVファイルが期待通りではありません。\n期待値：\n%s\n実際の値：\n%s", expected, actual)
+	}
+}
+
+func TestRunMetricsWithoutJsonFile(t *testing.T) {
+	err := runMetrics([]string{})
+	if err == nil || err.Error() != "json-fileで書き出したJSONファイルのパスを指定してください。" {
+		t.Errorf("JSONファイルの指定がなければエラーになる想定です。実際の値：%v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Fintan-contents/review-support-tool/getpr"
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/webhook"
+)
+
+// Webhookの本文の上限（バイト）
+const maxPayloadSize = 25 << 20
+
+// GitHub、GitLab、GiteaのWebhookを受け付けるHTTPサーバーを起動し、プルリクエストのマージやクローズ、レビューのたびにレビュー情報を抽出し直す。
+func runServe(args []string) error {
+	flagSet := flag.NewFlagSet(os.Args[0]+" serve", flag.ExitOnError)
+	config.ConfigureFlag(flagSet)
+	listen := flagSet.String("listen", ":8080", "Webhookを受け付けるアドレス。")
+	secret := flagSet.String("webhook-secret", "", "Webhookの署名（GitHub、Gitea）やトークン（GitLab）を検証するためのシークレット。")
+	outputDir := flagSet.String("output-dir", "", "抽出したCSVファイルとJSONファイルを書き出すディレクトリ。")
+	flagSet.Parse(args)
+
+	server, err := newWebhookServer(config, *secret, *outputDir)
+	if err != nil {
+		return err
+	}
+	httpServer := &http.Server{Addr: *listen, Handler: server}
+	// Ctrl+Cで停止する。抽出中の処理は中断し、終わるまで待つ
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+	fmt.Fprintln(stderr(), "Webhookを受け付けます："+*listen)
+	err = httpServer.ListenAndServe()
+	server.Shutdown()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Webhookを受け付けてレビュー情報を抽出するハンドラー
+type webhookServer struct {
+	config    cfg.Config
+	secret    string
+	outputDir string
+	// 同じファイルへ同時に書き出さないよう、抽出を1件ずつ実行する
+	mutex   sync.Mutex
+	running sync.WaitGroup
+	// 抽出に使用するcontext。停止したときに取り消す。
+	ctx    context.Context
+	cancel context.CancelFunc
+	// 停止したかどうかと、抽出の開始を停止と排他的に行うためのロック
+	closed bool
+	lock   sync.Mutex
+}
+
+// 設定を検証してハンドラーを構築する。
+func newWebhookServer(config *cfg.Config, secret string, outputDir string) (*webhookServer, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("Webhookのシークレットを設定してください。")
+	}
+	if info, err := os.Stat(outputDir); len(outputDir) == 0 || err != nil || !info.IsDir() {
+		return nil, errors.New("出力先のディレクトリを設定してください。")
+	}
+	if config.Target == "gitbucket" {
+		return nil, errors.New("serveはGitHub、GitLab、GiteaのWebhookのみ受け付けます。")
+	}
+	// プルリクエストはWebhookごとに決まるため、仮の値で残りの設定を検証する
+	validated := *config
+	validated.Org, validated.Repo, validated.Pull = "-", "-", "-"
+	if err := validated.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webhookServer{config: *config, secret: secret, outputDir: outputDir, ctx: ctx, cancel: cancel}, nil
+}
+
+// Webhookを検証し、対象のイベントであれば抽出を開始して202を返す。抽出の結果はログへ書き出す。
+//
+// Webhookの送信元は応答を長く待たないため、抽出は応答を返した後に実行する。
+func (server *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POSTで送信してください。", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "Webhookの本文を読み込めません。", http.StatusBadRequest)
+		return
+	}
+	event, err := webhook.Parse(r.Header, body, server.secret)
+	if errors.Is(err, webhook.ErrUnauthorized) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if event == nil {
+		// 対象外のイベントは受け取ったことだけを返す
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if event.Target != server.config.Target {
+		http.Error(w, event.Target+"のWebhookは受け付けていません。targetの設定を見直してください。", http.StatusBadRequest)
+		return
+	}
+	// 停止と同時に受け付けた抽出を待ち漏らさないよう、停止の確認と同じロックの中で待つ対象に加える
+	server.lock.Lock()
+	if server.closed {
+		server.lock.Unlock()
+		http.Error(w, "停止中のため受け付けられません。", http.StatusServiceUnavailable)
+		return
+	}
+	server.running.Add(1)
+	server.lock.Unlock()
+	go func() {
+		defer server.running.Done()
+		server.extract(event)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// 実行中の抽出が全て終わるまで待つ。
+func (server *webhookServer) Wait() {
+	server.running.Wait()
+}
+
+// 新しい抽出を受け付けないようにし、実行中の抽出を中断して終わるまで待つ。
+func (server *webhookServer) Shutdown() {
+	server.lock.Lock()
+	server.closed = true
+	server.lock.Unlock()
+	server.cancel()
+	server.running.Wait()
+}
+
+// イベントのプルリクエストからレビュー情報を抽出し、出力先のディレクトリへCSVファイルとJSONファイルを書き出す。
+func (server *webhookServer) extract(event *webhook.Event) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	config := server.config
+	config.Org, config.Repo, config.Pull = event.Org, event.Repo, event.Pull
+	name := outputName(event)
+	options := newOptions(&config)
+	options.Writers = []getpr.Writer{
+		&getpr.CsvFileWriter{Path: filepath.Join(server.outputDir, name+".csv"), UseSjis: config.UseSjisFile},
+		&getpr.JsonFileWriter{Path: filepath.Join(server.outputDir, name+".json")},
+	}
+	result, err := getpr.Extract(server.ctx, options)
+	if err != nil {
+		fmt.Fprintln(stderr(), "エラー："+event.Action+" "+name+" "+err.Error())
+		return
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintln(stderr(), "警告："+name+" "+warning)
+	}
+	fmt.Fprintln(stderr(), "抽出しました："+event.Action+" "+name)
+}
+
+// 出力するファイルの名前として、オーガニゼーションとリポジトリ、プルリクエストのIDをハイフンで繋ぐ。
+func outputName(event *webhook.Event) string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{event.Org, event.Repo, event.Pull} {
+		if len(part) > 0 {
+			parts = append(parts, strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(part))
+		}
+	}
+	return strings.Join(parts, "-")
+}
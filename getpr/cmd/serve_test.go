@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+)
+
+const webhookSecret = "secret"
+
+// 記録したWebhookを送信するリクエストを構築する。GitHubは本文に署名し、GitLabはトークンを設定する。
+func newWebhookRequest(t *testing.T, target string, event string, payload string) *http.Request {
+	body, err := os.ReadFile(filepath.Join("testdata", "webhook", payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	switch target {
+	case "github":
+		mac := hmac.New(sha256.New, []byte(webhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	case "gitlab":
+		req.Header.Set("X-Gitlab-Event", event)
+		req.Header.Set("X-Gitlab-Token", webhookSecret)
+	}
+	return req
+}
+
+// コマンドライン引数から、テスト間で共有しない設定を構築する。
+func newServeConfig(t *testing.T, target string, endpoint string) *cfg.Config {
+	config := &cfg.Config{}
+	flagSet := flag.NewFlagSet("serve", flag.ContinueOnError)
+	config.ConfigureFlag(flagSet)
+	if err := flagSet.Parse(baseArgs(target, endpoint, "")); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestServe(t *testing.T) {
+	fixtures := []struct {
+		target  string
+		event   string
+		payload string
+		output  string
+	}{
+		{"github", "pull_request", "github-pull_request-closed.json", "org-repo-1"},
+		{"github", "pull_request_review", "github-pull_request_review-submitted.json", "org-repo-1"},
+		{"gitlab", "Merge Request Hook", "gitlab-merge_request-merge.json", "12-1"},
+		{"gitlab", "Note Hook", "gitlab-note-merge_request.json", "12-1"},
+	}
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.payload, func(t *testing.T) {
+			server := servers[fixture.target](newScenario())
+			defer server.Close()
+			dir := t.TempDir()
+			handler, err := newWebhookServer(newServeConfig(t, fixture.target, server.URL), webhookSecret, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, newWebhookRequest(t, fixture.target, fixture.event, fixture.payload))
+			handler.Wait()
+			if recorder.Code != http.StatusAccepted {
+				t.Fatalf("期待値は %v ですが実際には %v でした", http.StatusAccepted, recorder.Code)
+			}
+			if _, err := os.Stat(filepath.Join(dir, fixture.output+".json")); err != nil {
+				t.Errorf("JSONファイルが書き出されていません: %v", err)
+			}
+			actual, err := os.ReadFile(filepath.Join(dir, fixture.output+".csv"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Webhookで抽出した結果は、コマンドラインで抽出した結果と同じになる
+			assertGolden(t, filepath.Join("testdata", fixture.target+"-basic.csv"), actual)
+		})
+	}
+}
+
+func TestServeRejected(t *testing.T) {
+	server := servers["github"](newScenario())
+	defer server.Close()
+	dir := t.TempDir()
+	handler, err := newWebhookServer(newServeConfig(t, "github", server.URL), webhookSecret, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := newWebhookRequest(t, "github", "pull_request", "github-pull_request-closed.json")
+	forged.Header.Set("X-Hub-Signature-256", "sha256=00")
+	ignored := newWebhookRequest(t, "github", "pull_request", "github-pull_request-opened.json")
+	otherTarget := newWebhookRequest(t, "gitlab", "Merge Request Hook", "gitlab-merge_request-merge.json")
+	fixtures := []struct {
+		name     string
+		req      *http.Request
+		expected int
+	}{
+		{"forged", forged, http.StatusUnauthorized},
+		{"ignored", ignored, http.StatusNoContent},
+		{"other-target", otherTarget, http.StatusBadRequest},
+	}
+	for _, fixture := range fixtures {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, fixture.req)
+		if recorder.Code != fixture.expected {
+			t.Errorf("%s: 期待値は %v ですが実際には %v でした", fixture.name, fixture.expected, recorder.Code)
+		}
+	}
+	handler.Wait()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("抽出しない想定ですが、ファイルが書き出されました: %v", entries)
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	server := servers["github"](newScenario())
+	defer server.Close()
+	dir := t.TempDir()
+	handler, err := newWebhookServer(newServeConfig(t, "github", server.URL), webhookSecret, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.Shutdown()
+	if handler.ctx.Err() == nil {
+		t.Error("停止したら抽出のcontextを取り消す想定です")
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newWebhookRequest(t, "github", "pull_request", "github-pull_request-closed.json"))
+	handler.Wait()
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("期待値は %v ですが実際には %v でした", http.StatusServiceUnavailable, recorder.Code)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("停止後は抽出しない想定ですが、ファイルが書き出されました: %v", entries)
+	}
+}
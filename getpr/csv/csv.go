@@ -1,120 +1,404 @@
-package csv
-
-import (
-	"encoding/csv"
-	"errors"
-	"io"
-	"os"
-	"strconv"
-	"strings"
-
-	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
-	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/transform"
-)
-
-// 指定されたパスにCSVデータを書き出す。
-func WriteCsv(csvFile string, data *CsvData, useSjis bool) error {
-	file, err := os.Create(csvFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	return writeCsvFile(data, file, useSjis)
-}
-
-func writeCsvFile(csvData *CsvData, file *os.File, useSjis bool) error {
-	var writer *csv.Writer
-	if useSjis {
-		writer = csv.NewWriter(transform.NewWriter(file, japanese.ShiftJIS.NewEncoder()))
-	} else {
-		writer = csv.NewWriter(file)
-	}
-	writer.UseCRLF = true
-	if csvData != nil {
-		writer.Write([]string{
-			strconv.Itoa(csvData.CsvHeader.Additions),
-			strconv.Itoa(csvData.CsvHeader.Deletions),
-			csvData.CsvHeader.ReviewTime.ReviewDate,
-			csvData.CsvHeader.ReviewTime.ReviewStartTime,
-			csvData.CsvHeader.ReviewTime.ReviewEndTime,
-			csvData.CsvHeader.ReviewTime.ReviewMinutes,
-		})
-		for _, csvReviewComment := range csvData.CsvReviewComments {
-			writer.Write([]string{
-				csvReviewComment.Url,
-				escape(csvReviewComment.ReviewerComment),
-				csvReviewComment.Reviewer,
-				escape(csvReviewComment.RevieweeComment),
-				csvReviewComment.Reviewee,
-				strconv.FormatBool(csvReviewComment.Resolved),
-				strconv.FormatBool(csvReviewComment.HasResolvedStatus),
-			})
-		}
-		writer.Flush()
-	}
-	err := writer.Error()
-	if err != nil && strings.HasPrefix(err.Error(), "encoding: rune not supported by encoding.") {
-		return errors.New("絵文字のようなShift_JISで扱えない文字が含まれています。")
-	}
-	return err
-}
-
-// 改行をエスケープして1行のテキストに変換する。
-func escape(input string) string {
-	builder := strings.Builder{}
-	reader := strings.NewReader(input)
-	for {
-		r, _, err := reader.ReadRune()
-		if err == io.EOF {
-			return builder.String()
-		}
-		if r == '\\' {
-			builder.WriteRune('\\')
-			builder.WriteRune('\\')
-		} else if r == '\n' {
-			builder.WriteRune('\\')
-			builder.WriteRune('n')
-		} else if r == '\r' {
-			// CRは無視する
-		} else {
-			builder.WriteRune(r)
-		}
-	}
-}
-
-// ヘッダー
-type CsvHeader struct {
-	// 追加行数。GitBucketは常に0。
-	Additions int
-	// 削除行数。GitBucketは常に0。
-	Deletions int
-	// レビュー日時情報
-	ReviewTime rvtime.ReviewTime
-}
-
-// レビュー指摘事項・対応内容
-type CsvReviewComment struct {
-	// 指摘のURL
-	Url string
-	// レビュー指摘事項。GitHubとGitLabはAPIで取得したマークダウン、GitBucketはAPIが使えないためHTMLをパースして得たテキスト。改行はエスケープして1行にする。
-	ReviewerComment string
-	// レビュアーのユーザー名。
-	Reviewer string
-	// 対応内容。GitHubとGitLabはAPIで取得したマークダウン、GitBucketはAPIが使えないためHTMLをパースして得たテキスト。改行はエスケープして1行にする。
-	RevieweeComment string
-	// レビュイーのユーザー名。
-	Reviewee string
-	// APIで取得できる指摘の解決状態。GitHubの通常コメントと、GitBucketは指摘の解決状態を取得できないので`false`を返す。
-	Resolved bool
-	// APIで指摘の解決状態を取得できる場合は`true`を返す。GitHubの通常コメントと、GitBucketは指摘の解決状態を取得できないので`false`を返す。
-	HasResolvedStatus bool
-}
-
-// CSVデータ
-type CsvData struct {
-	// ヘッダー
-	CsvHeader CsvHeader
-	// レビュー指摘事項・対応内容
-	CsvReviewComments []CsvReviewComment
-}
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/markup"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/reviewee"
+	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
+	"github.com/Fintan-contents/review-support-tool/getpr/text"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// 指定されたWriterにCSVデータを書き出す。
+func Write(w io.Writer, csvData *CsvData, useSjis bool) error {
+	writer := newWriter(w, useSjis)
+	if csvData != nil {
+		if len(csvData.CsvRoundHeaders) == 0 {
+			writeHeader(writer, csvData.CsvHeader)
+			for _, csvReviewComment := range csvData.CsvReviewComments {
+				writeReviewComment(writer, csvReviewComment)
+			}
+		} else {
+			// レビュー回ごとにヘッダーと指摘を書き出す
+			for _, csvHeader := range csvData.CsvRoundHeaders {
+				writeHeader(writer, csvHeader)
+				for _, csvReviewComment := range csvData.CsvReviewComments {
+					if csvReviewComment.ReviewTimes == csvHeader.ReviewTimes {
+						writeReviewComment(writer, csvReviewComment)
+					}
+				}
+			}
+		}
+		writer.Flush()
+	}
+	return writerError(writer)
+}
+
+// 改行をCRLFにして、指定された文字コードで書き出すCSVのWriterを返す。
+func newWriter(w io.Writer, useSjis bool) *csv.Writer {
+	var writer *csv.Writer
+	if useSjis {
+		writer = csv.NewWriter(transform.NewWriter(w, japanese.ShiftJIS.NewEncoder()))
+	} else {
+		writer = csv.NewWriter(w)
+	}
+	writer.UseCRLF = true
+	return writer
+}
+
+// 書き出しで発生したエラーを返す。Shift_JISで扱えない文字が原因の場合は利用者向けのメッセージにする。
+func writerError(writer *csv.Writer) error {
+	err := writer.Error()
+	if err != nil && strings.HasPrefix(err.Error(), "encoding: rune not supported by encoding.") {
+		return errors.New("絵文字のようなShift_JISで扱えない文字が含まれています。")
+	}
+	return err
+}
+
+func writeHeader(writer *csv.Writer, csvHeader CsvHeader) {
+	writer.Write([]string{
+		strconv.Itoa(csvHeader.Additions),
+		strconv.Itoa(csvHeader.Deletions),
+		csvHeader.ReviewTime.ReviewDate,
+		csvHeader.ReviewTime.ReviewStartTime,
+		csvHeader.ReviewTime.ReviewEndTime,
+		csvHeader.ReviewTime.ReviewMinutes,
+		csvHeader.ReviewTimes,
+		joinSessions(csvHeader.Sessions),
+		strconv.FormatBool(csvHeader.Estimated),
+	})
+}
+
+// レビュー実施記録を「日付 開始時刻-終了時刻」の形式にしてセミコロンで繋ぐ。
+func joinSessions(sessions []rvtime.Session) string {
+	values := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		values = append(values, session.String())
+	}
+	return strings.Join(values, ";")
+}
+
+func writeReviewComment(writer *csv.Writer, csvReviewComment CsvReviewComment) {
+	writer.Write([]string{
+		csvReviewComment.Url,
+		escape(csvReviewComment.ReviewerComment),
+		csvReviewComment.Reviewer,
+		escape(csvReviewComment.RevieweeComment),
+		csvReviewComment.Reviewee,
+		strconv.FormatBool(csvReviewComment.Resolved),
+		strconv.FormatBool(csvReviewComment.HasResolvedStatus),
+		csvReviewComment.ReviewTimes,
+		formatTime(csvReviewComment.CreatedAt),
+		formatTime(csvReviewComment.UpdatedAt),
+		csvReviewComment.Severity,
+		csvReviewComment.Category,
+		csvReviewComment.ReviewerDepartment,
+		csvReviewComment.RevieweeDepartment,
+		csvReviewComment.Change,
+	})
+}
+
+// 日時を "yyyy/MM/dd HH:mm:ss" 形式の文字列に変換する。ゼロ値の場合は空文字列を返す。
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006/01/02 15:04:05")
+}
+
+// 改行をエスケープして1行のテキストに変換する。
+func escape(input string) string {
+	builder := strings.Builder{}
+	reader := strings.NewReader(input)
+	for {
+		r, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return builder.String()
+		}
+		if r == '\\' {
+			builder.WriteRune('\\')
+			builder.WriteRune('\\')
+		} else if r == '\n' {
+			builder.WriteRune('\\')
+			builder.WriteRune('n')
+		} else if r == '\r' {
+			// CRは無視する
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+}
+
+// ヘッダー
+type CsvHeader struct {
+	// 追加行数。GitBucketは常に0。
+	Additions int
+	// 削除行数。GitBucketは常に0。
+	Deletions int
+	// レビュー日時情報
+	ReviewTime rvtime.ReviewTime
+	// レビュー回数
+	ReviewTimes string
+	// レビュー実施記録の一覧
+	Sessions []rvtime.Session
+	// レビュー日時をコメントの投稿日時から推定した場合はtrue
+	Estimated bool
+}
+
+// レビュー指摘事項・対応内容
+type CsvReviewComment struct {
+	// 指摘のURL
+	Url string
+	// レビュー指摘事項。GitHubとGitLabはAPIで取得したマークダウン、GitBucketはAPIが使えないためHTMLをパースして得たテキスト。body-formatを指定した場合はその形式に変換する。改行はエスケープして1行にする。
+	ReviewerComment string
+	// レビュアーのユーザー名。user-directoryやuse-host-namesを指定した場合は氏名。
+	Reviewer string
+	// 対応内容。GitHubとGitLabはAPIで取得したマークダウン、GitBucketはAPIが使えないためHTMLをパースして得たテキスト。body-formatを指定した場合はその形式に変換する。改行はエスケープして1行にする。
+	RevieweeComment string
+	// レビュイーのユーザー名。user-directoryやuse-host-namesを指定した場合は氏名。スレッドで複数のレビュイーが回答した場合はカンマで繋ぐ。
+	Reviewee string
+	// APIで取得できる指摘の解決状態。GitHubの通常コメントと、GitBucketは指摘の解決状態を取得できないので`false`を返す。
+	Resolved bool
+	// APIで指摘の解決状態を取得できる場合は`true`を返す。GitHubの通常コメントと、GitBucketは指摘の解決状態を取得できないので`false`を返す。
+	HasResolvedStatus bool
+	// 指摘が属するレビュー回数。
+	ReviewTimes string
+	// 指摘の作成日時。スレッド形式の場合は最初のコメントの作成日時。取得できない場合はゼロ値。
+	CreatedAt time.Time
+	// 指摘の更新日時。スレッド形式の場合はスレッド内のコメントの作成・更新や解決のうち最も新しい日時。取得できない場合はゼロ値。
+	UpdatedAt time.Time
+	// 指摘の重要度。スレッド形式の場合はレビュアーのコメントのうち最初に読み取れたもの。
+	Severity string
+	// 指摘の種別。スレッド形式の場合はレビュアーのコメントのうち最初に読み取れたもの。
+	Category string
+	// レビュアーの所属。user-directoryに所属が書かれている場合のみ設定される。
+	ReviewerDepartment string
+	// レビュイーの所属。複数のレビュイーが回答した場合は重複を除いてカンマで繋ぐ。
+	RevieweeDepartment string
+	// 前回の抽出からの変更種別。new、reply、status、editedのいずれか。since-stateを指定した場合のみ設定される。
+	Change string
+}
+
+// CSVデータ
+type CsvData struct {
+	// ヘッダー
+	CsvHeader CsvHeader
+	// レビュー回ごとのヘッダー。全てのレビュー回を抽出する場合のみ設定される。
+	CsvRoundHeaders []CsvHeader
+	// レビュー指摘事項・対応内容
+	CsvReviewComments []CsvReviewComment
+}
+
+// レビュー情報からCSVデータを構築する。レビュー回の割り当てはreview.Analyzeで済ませておくこと。
+//
+// レビュー日時のスレッドは書き出さない。
+// スレッド形式の場合はレビュアーとレビュイーのコメントをそれぞれpost-script-prefixで繋ぎ、通常のコメントはデリミタで分割する。
+func Build(pr *review.PullRequest, config *cfg.Config) *CsvData {
+	csvData := &CsvData{
+		CsvHeader:         newCsvHeader(pr, config.ReviewTimes),
+		CsvReviewComments: make([]CsvReviewComment, 0, len(pr.Threads)),
+	}
+	if config.AllReviewTimes {
+		csvData.CsvRoundHeaders = make([]CsvHeader, 0, len(pr.Rounds))
+		for _, round := range pr.Rounds {
+			csvData.CsvRoundHeaders = append(csvData.CsvRoundHeaders, newCsvHeader(pr, round.ReviewTimes))
+		}
+		if len(csvData.CsvRoundHeaders) > 0 {
+			csvData.CsvHeader = csvData.CsvRoundHeaders[0]
+		}
+	}
+	// 正規表現はValidateで検証済み
+	delimiter, _ := text.NewDelimiter(config.Delimiters(), config.DelimiterPattern)
+	for _, thread := range pr.Threads {
+		if !thread.IsFinding() {
+			continue
+		}
+		// since-stateを指定した場合は前回の抽出から変化のない指摘を書き出さない
+		if len(config.SinceState) != 0 && len(thread.Change) == 0 {
+			continue
+		}
+		var csvReviewComment CsvReviewComment
+		var ok bool
+		if thread.Threaded {
+			csvReviewComment, ok = buildThreadComment(pr, thread, config)
+		} else {
+			csvReviewComment, ok = buildSingleComment(pr, thread, delimiter, config)
+		}
+		if ok {
+			csvData.CsvReviewComments = append(csvData.CsvReviewComments, csvReviewComment)
+		}
+	}
+	csvData.In(config.Location())
+	return csvData
+}
+
+// 指定されたレビュー回数のヘッダーを構築する。レビュー回が見つからなければレビュー日時は空にする。
+func newCsvHeader(pr *review.PullRequest, reviewTimes string) CsvHeader {
+	round, _ := pr.Round(reviewTimes)
+	return CsvHeader{
+		Additions:   pr.DiffStats.Additions,
+		Deletions:   pr.DiffStats.Deletions,
+		ReviewTime:  round.ReviewTime,
+		ReviewTimes: reviewTimes,
+		Sessions:    round.Sessions,
+		Estimated:   round.Estimated,
+	}
+}
+
+// スレッド形式の指摘を構築する。コメントが1件もなければfalseを返す。
+//
+// 変更の提案は「提案: `コード`」の形式にし、署名は取り除く。GitBucketのようにHTMLを取得できる場合はHTMLに対して処理する。strip-quotesを指定した場合はレビュイーのコメントから引用を取り除く。
+func buildThreadComment(pr *review.PullRequest, thread review.Thread, config *cfg.Config) (CsvReviewComment, bool) {
+	if len(thread.Comments) == 0 {
+		return CsvReviewComment{}, false
+	}
+	reviewerComments := make([]string, 0)
+	revieweeComments := make([]string, 0)
+	respondents := &reviewee.Respondents{}
+	departments := &reviewee.Respondents{}
+	reviewer := thread.Reviewer()
+	for _, comment := range thread.Comments {
+		if len(comment.Body) == 0 {
+			continue
+		}
+		if len(comment.Html) != 0 {
+			// GitBucketは書き出す本文をHTMLから変換するため、HTMLに対して処理してから本文を取り出し直す
+			comment.Html = markup.StripSignature(markup.RenderSuggestions(comment.Html))
+			comment.Body = markup.ScrapeText(comment.Html)
+		} else {
+			comment.Body = text.StripSignature(text.RenderSuggestions(comment.Body))
+		}
+		body := formatBody(comment, config.BodyFormat)
+		if comment.Author.Reviewee {
+			if config.StripQuotes {
+				body = stripQuotes(body, config.BodyFormat)
+			}
+			if len(body) == 0 {
+				continue
+			}
+			revieweeComments = append(revieweeComments, body)
+			respondents.Add(comment.Author.Label())
+			if len(comment.Author.Department) != 0 {
+				departments.Add(comment.Author.Department)
+			}
+		} else {
+			reviewerComments = append(reviewerComments, body)
+		}
+	}
+	postScriptSeparator := text.Crlf + config.PostScriptPrefix
+	severity, category := thread.Tag()
+	return CsvReviewComment{
+		Url:                thread.Url,
+		ReviewerComment:    strings.Join(reviewerComments, postScriptSeparator),
+		Reviewer:           reviewer.Label(),
+		RevieweeComment:    strings.Join(revieweeComments, postScriptSeparator),
+		Reviewee:           respondents.String(pr.Author.Label()),
+		Resolved:           thread.Resolved,
+		HasResolvedStatus:  thread.HasResolvedStatus,
+		ReviewTimes:        thread.ReviewTimes,
+		CreatedAt:          thread.CreatedAt(),
+		UpdatedAt:          thread.UpdatedAt(),
+		Severity:           severity,
+		Category:           category,
+		ReviewerDepartment: reviewer.Department,
+		RevieweeDepartment: departments.String(pr.Author.Department),
+		Change:             thread.Change,
+	}, true
+}
+
+// 通常のコメントをデリミタで分割して指摘を構築する。本文が空の場合はfalseを返す。
+// レビュアーとレビュイーのコメントが交互に書かれている場合は、スレッド形式と同じくそれぞれpost-script-prefixで繋ぐ。
+func buildSingleComment(pr *review.PullRequest, thread review.Thread, delimiter *text.Delimiter, config *cfg.Config) (CsvReviewComment, bool) {
+	if len(thread.Comments) == 0 || len(thread.Comments[0].Body) == 0 {
+		return CsvReviewComment{}, false
+	}
+	comment := thread.Comments[0]
+	parts := splitBody(comment, delimiter, config.BodyFormat)
+	reviewerComment, revieweeComment := text.Alternate(parts, text.Crlf+config.PostScriptPrefix)
+	if len(reviewerComment) == 0 && len(revieweeComment) == 0 {
+		return CsvReviewComment{}, false
+	}
+	return CsvReviewComment{
+		Url:             thread.Url,
+		ReviewerComment: reviewerComment,
+		Reviewer:        comment.Author.Label(),
+		RevieweeComment: revieweeComment,
+		Reviewee:        pr.Author.Label(),
+		// 通常のコメントでは解決状態は取得できない
+		Resolved:           false,
+		HasResolvedStatus:  false,
+		ReviewTimes:        thread.ReviewTimes,
+		CreatedAt:          thread.CreatedAt(),
+		UpdatedAt:          thread.UpdatedAt(),
+		Severity:           comment.Severity,
+		Category:           comment.Category,
+		ReviewerDepartment: comment.Author.Department,
+		RevieweeDepartment: pr.Author.Department,
+		Change:             thread.Change,
+	}, true
+}
+
+// コメント本文を指定された形式に変換する。GitBucketのようにHTMLを取得できる場合はHTMLから変換する。
+func formatBody(comment review.Comment, format string) string {
+	switch format {
+	case cfg.BodyPlain:
+		if len(comment.Html) != 0 {
+			return markup.HtmlToPlain(comment.Html)
+		}
+		return markup.MarkdownToPlain(comment.Body)
+	case cfg.BodyHtml:
+		if len(comment.Html) != 0 {
+			return comment.Html
+		}
+		return markup.MarkdownToHtml(comment.Body)
+	default:
+		return comment.Body
+	}
+}
+
+// 指定された形式のコメント本文から引用を取り除く。
+func stripQuotes(body string, format string) string {
+	if format == cfg.BodyHtml {
+		return markup.StripQuotes(body)
+	}
+	return text.StripQuotes(body)
+}
+
+// 通常のコメントの本文をデリミタで分割し、指定された形式に変換する。
+// 変換するとコードブロックの区別がなくなったりデリミタの行が段落に含まれたりするため、取得したまま分割してから変換する。
+// GitBucketのようにHTMLを取得できる場合は、strip-tagsでタグを取り除いた後のHTMLを分割する。
+func splitBody(comment review.Comment, delimiter *text.Delimiter, format string) []string {
+	if len(comment.Html) == 0 {
+		parts := delimiter.Split(comment.Body)
+		for i, part := range parts {
+			parts[i] = formatBody(review.Comment{Body: part}, format)
+		}
+		return parts
+	}
+	parts := markup.SplitHtml(comment.Html, delimiter.Match)
+	for i, part := range parts {
+		parts[i] = formatBody(review.Comment{Body: markup.ScrapeText(part), Html: part}, format)
+	}
+	return parts
+}
+
+// 指摘の日時を指定されたタイムゾーンに変換する。
+func (csvData *CsvData) In(loc *time.Location) {
+	for i, csvReviewComment := range csvData.CsvReviewComments {
+		if !csvReviewComment.CreatedAt.IsZero() {
+			csvData.CsvReviewComments[i].CreatedAt = csvReviewComment.CreatedAt.In(loc)
+		}
+		if !csvReviewComment.UpdatedAt.IsZero() {
+			csvData.CsvReviewComments[i].UpdatedAt = csvReviewComment.UpdatedAt.In(loc)
+		}
+	}
+}
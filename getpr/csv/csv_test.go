@@ -1,9 +1,13 @@
 package csv
 
 import (
+	"bytes"
 	"fmt"
-	"strings"
 	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
 )
 
 func TestEscape(t *testing.T) {
@@ -25,13 +29,39 @@ func TestEscape(t *testing.T) {
 	}
 }
 
-func TestWriteCsv(t *testing.T) {
-	err := WriteCsv("testwritecsv.csv", nil, false)
-	if err == nil {
-		t.Fail()
-		return
-	} else if !strings.Contains(err.Error(), "testwritecsv.csv: is a directory") {
-		t.Error(err)
-		return
+func TestWriteCsvRounds(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	config := &cfg.Config{AllReviewTimes: true, PostScriptPrefix: "(追記)", Delimiter: "~~", Timezone: "Asia/Tokyo"}
+	pr := &review.PullRequest{
+		Author:    review.Participant{Login: "author", Reviewee: true},
+		DiffStats: review.DiffStats{Additions: 10, Deletions: 2},
+		Threads: []review.Thread{
+			{Comments: []review.Comment{{Body: "レビュー2回目\n2023/4/20\n13:00-14:00"}}},
+			{Comments: []review.Comment{{Body: "レビュー1回目\n2023/4/14\n9:00-10:00"}}},
+			{Comments: []review.Comment{{Body: "レビュー2回目\n2023/4/21 9:00-9:30"}}},
+			{Url: "u1", Threaded: true, Comments: []review.Comment{
+				{Author: review.Participant{Login: "r1"}, Body: "指摘1", CreatedAt: time.Date(2023, 4, 21, 0, 0, 0, 0, loc)},
+				{Author: review.Participant{Login: "author", Reviewee: true}, Body: "対応1", CreatedAt: time.Date(2023, 4, 21, 1, 0, 0, 0, loc)},
+				{Author: review.Participant{Login: "r1"}, Body: "指摘2", CreatedAt: time.Date(2023, 4, 21, 2, 0, 0, 0, loc)},
+			}},
+			{Url: "u2", Comments: []review.Comment{
+				{Author: review.Participant{Login: "r2"}, Body: "指摘3\r\n~~\r\n対応3", CreatedAt: time.Date(2023, 4, 14, 10, 0, 0, 0, loc)},
+			}},
+			{Url: "u3", Threaded: true, Comments: []review.Comment{{Author: review.Participant{Login: "r1"}, Body: "指摘4"}}},
+		},
+	}
+	review.Analyze(pr, review.AnalyzeOptions{AllReviewTimes: true, Location: loc})
+
+	buf := &bytes.Buffer{}
+	if err := Write(buf, Build(pr, config), false); err != nil {
+		t.Fatal(err)
+	}
+	expected := "10,2,2023/4/14,9:00,10:00,60,1,2023/4/14 9:00-10:00,false\r\n" +
+		"u2,指摘3,r2,対応3,author,false,false,1,2023/04/14 10:00:00,2023/04/14 10:00:00,,,,,\r\n" +
+		"10,2,2023/4/20,13:00,9:30,90,2,2023/4/20 13:00-14:00;2023/4/21 9:00-9:30,false\r\n" +
+		"u1,指摘1\\n(追記)指摘2,r1,対応1,author,false,false,2,2023/04/21 00:00:00,2023/04/21 02:00:00,,,,,\r\n" +
+		"u3,指摘4,r1,,author,false,false,2,,,,,,,\r\n"
+	if buf.String() != expected {
+		t.Errorf(`Expected is "%v" but actual is "%v"`, expected, buf.String())
 	}
 }
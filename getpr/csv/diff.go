@@ -0,0 +1,54 @@
+package csv
+
+import (
+	"io"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/diff"
+	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
+)
+
+// 比較の結果の見出し
+var diffHeader = []string{
+	"change",
+	"url",
+	"author",
+	"severity",
+	"at",
+	"body",
+}
+
+// 比較の結果をCSV形式で書き出す。1行目に見出しを、続けてレビュー日時が変わったレビュー回と指摘の動きを書き出す。
+func WriteDiff(w io.Writer, d *diff.Diff, useSjis bool) error {
+	writer := newWriter(w, useSjis)
+	writer.Write(diffHeader)
+	for _, round := range d.Rounds {
+		writer.Write([]string{
+			"reviewTime",
+			d.Url,
+			"",
+			"",
+			"",
+			"レビュー" + round.ReviewTimes + "回目 " + formatReviewTime(round.Old) + " -> " + formatReviewTime(round.New),
+		})
+	}
+	for _, change := range d.Changes {
+		writer.Write([]string{
+			change.Kind,
+			change.Url,
+			change.Author,
+			change.Severity,
+			formatTime(change.At),
+			escape(change.Body),
+		})
+	}
+	writer.Flush()
+	return writerError(writer)
+}
+
+// レビュー日時を「2024/5/1 10:00-11:00 60分」の形式にする。レビュー回がない場合は「なし」にする。
+func formatReviewTime(reviewTime *rvtime.ReviewTime) string {
+	if reviewTime == nil {
+		return "なし"
+	}
+	return reviewTime.ReviewDate + " " + reviewTime.ReviewStartTime + "-" + reviewTime.ReviewEndTime + " " + reviewTime.ReviewMinutes + "分"
+}
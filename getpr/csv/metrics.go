@@ -0,0 +1,58 @@
+package csv
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/metrics"
+)
+
+// 指標の見出し
+var metricsHeader = []string{
+	"url",
+	"findings",
+	"additions",
+	"deletions",
+	"reviewMinutes",
+	"findingsPerKloc",
+	"findingsPerHour",
+	"medianFirstResponseMinutes",
+	"resolvedRate",
+	"findingsByReviewer",
+}
+
+// 指標をCSV形式で書き出す。1行目に見出しを、プルリクエストごとの行の後に合計の行を書き出す。
+func WriteMetrics(w io.Writer, rows []*metrics.Metrics, total *metrics.Metrics, useSjis bool) error {
+	writer := newWriter(w, useSjis)
+	writer.Write(metricsHeader)
+	for _, row := range rows {
+		writeMetrics(writer, row.Url, row)
+	}
+	writeMetrics(writer, "合計", total)
+	writer.Flush()
+	return writerError(writer)
+}
+
+func writeMetrics(writer *csv.Writer, label string, m *metrics.Metrics) {
+	writer.Write([]string{
+		label,
+		strconv.Itoa(m.Findings),
+		strconv.Itoa(m.Additions),
+		strconv.Itoa(m.Deletions),
+		strconv.Itoa(m.ReviewMinutes),
+		formatFloat(m.FindingsPerKloc),
+		formatFloat(m.FindingsPerHour),
+		formatFloat(m.MedianFirstResponseMinutes),
+		formatFloat(m.ResolvedRate),
+		m.JoinFindingsByReviewer(),
+	})
+}
+
+// 小数第2位までの文字列に変換する。算出できない場合は空文字列を返す。
+func formatFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', 2, 64)
+}
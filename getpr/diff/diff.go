@@ -0,0 +1,169 @@
+// 同じプルリクエストを異なる時点で抽出したレビュー情報を比較し、その間のレビューの動きを求めるパッケージ。
+package diff
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
+)
+
+// 動きの種別
+const (
+	// 新しい指摘
+	Raised = "raised"
+	// 指摘の解決
+	Resolved = "resolved"
+	// 解決した指摘の再開
+	Reopened = "reopened"
+	// レビュイーの新しい回答
+	Answered = "answered"
+)
+
+// 比較の結果
+type Diff struct {
+	// プルリクエストのURL
+	Url string
+	// レビュー日時が変わったレビュー回の一覧。新しい抽出結果のレビュー回の順に並べ、なくなったレビュー回は最後に並べる。
+	Rounds []RoundChange
+	// 指摘の動きの一覧。新しい抽出結果のスレッドの順に並べる。
+	Changes []Change
+}
+
+// レビュー回のレビュー日時の変化
+type RoundChange struct {
+	// レビュー回数
+	ReviewTimes string
+	// 古い抽出結果のレビュー日時。レビュー回がなかった場合はnil。
+	Old *rvtime.ReviewTime
+	// 新しい抽出結果のレビュー日時。レビュー回がなくなった場合はnil。
+	New *rvtime.ReviewTime
+}
+
+// 指摘の動き
+type Change struct {
+	// 動きの種別
+	Kind string
+	// 指摘のURL
+	Url string
+	// 指摘したレビュアー、または回答したレビュイー
+	Author string
+	// 指摘、または回答のコメント本文
+	Body string
+	// 指摘や回答の作成日時、または解決日時。取得できない場合はゼロ値。
+	At time.Time
+	// 指摘の重要度
+	Severity string
+}
+
+// 古い抽出結果と新しい抽出結果を比較する。異なるプルリクエストの場合はエラーを返す。
+//
+// レビュー日時はレビュー回ごとに、日付、開始時刻、終了時刻、レビュー時間のいずれかが変わったものを求める。
+// 指摘はCSVやmetricsと同じくreview.Thread.IsFindingで判定する。
+// 通常のコメントはデリミタの後に回答を書き足すため、回答は検出しない。
+func Compare(older *review.PullRequest, newer *review.PullRequest) (*Diff, error) {
+	if older.Url != newer.Url {
+		return nil, errors.New("異なるプルリクエストのJSONファイルは比較できません。")
+	}
+	diff := &Diff{
+		Url:     newer.Url,
+		Rounds:  compareRounds(older.Rounds, newer.Rounds),
+		Changes: make([]Change, 0),
+	}
+	previous := make(map[string]review.Thread, len(older.Threads))
+	for _, thread := range older.Threads {
+		previous[thread.Id] = thread
+	}
+	for _, thread := range newer.Threads {
+		if !thread.IsFinding() {
+			continue
+		}
+		first := firstComment(thread)
+		severity, _ := thread.Tag()
+		before, existed := previous[thread.Id]
+		if !existed {
+			diff.add(Raised, thread, first, first.CreatedAt, severity)
+		}
+		if thread.Threaded {
+			known := make(map[string]bool, len(before.Comments))
+			for _, comment := range before.Comments {
+				known[comment.Id] = true
+			}
+			for _, comment := range thread.Comments {
+				if comment.Author.Reviewee && len(comment.Body) > 0 && !known[comment.Id] {
+					diff.add(Answered, thread, comment, comment.CreatedAt, severity)
+				}
+			}
+		}
+		if thread.HasResolvedStatus && thread.Resolved != (existed && before.Resolved) {
+			if thread.Resolved {
+				var resolvedAt time.Time
+				if thread.ResolvedAt != nil {
+					resolvedAt = *thread.ResolvedAt
+				}
+				diff.add(Resolved, thread, first, resolvedAt, severity)
+			} else if existed {
+				diff.add(Reopened, thread, first, time.Time{}, severity)
+			}
+		}
+	}
+	return diff, nil
+}
+
+func (diff *Diff) add(kind string, thread review.Thread, comment review.Comment, at time.Time, severity string) {
+	diff.Changes = append(diff.Changes, Change{
+		Kind:     kind,
+		Url:      thread.Url,
+		Author:   comment.Author.Label(),
+		Body:     comment.Body,
+		At:       at,
+		Severity: severity,
+	})
+}
+
+// 本文が空でない最初のコメントを返す。全てのコメントの本文が空であれば先頭のコメントを返す。
+func firstComment(thread review.Thread) review.Comment {
+	for _, comment := range thread.Comments {
+		if len(comment.Body) > 0 {
+			return comment
+		}
+	}
+	return thread.Comments[0]
+}
+
+// レビュー回ごとにレビュー日時を比較し、変わったものを返す。
+func compareRounds(older []review.ReviewRound, newer []review.ReviewRound) []RoundChange {
+	previous := make(map[string]rvtime.ReviewTime, len(older))
+	for _, round := range older {
+		previous[round.ReviewTimes] = round.ReviewTime
+	}
+	changes := make([]RoundChange, 0)
+	current := make(map[string]bool, len(newer))
+	for _, round := range newer {
+		current[round.ReviewTimes] = true
+		reviewTime := round.ReviewTime
+		before, existed := previous[round.ReviewTimes]
+		if !existed {
+			changes = append(changes, RoundChange{ReviewTimes: round.ReviewTimes, New: &reviewTime})
+		} else if before != reviewTime {
+			changes = append(changes, RoundChange{ReviewTimes: round.ReviewTimes, Old: &before, New: &reviewTime})
+		}
+	}
+	for _, round := range older {
+		if !current[round.ReviewTimes] {
+			reviewTime := round.ReviewTime
+			changes = append(changes, RoundChange{ReviewTimes: round.ReviewTimes, Old: &reviewTime})
+		}
+	}
+	return changes
+}
+
+// 日時を指定されたタイムゾーンに変換する。
+func (diff *Diff) In(loc *time.Location) {
+	for i := range diff.Changes {
+		if !diff.Changes[i].At.IsZero() {
+			diff.Changes[i].At = diff.Changes[i].At.In(loc)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2024, 5, 1, hour, minute, 0, 0, time.UTC)
+}
+
+var (
+	alice  = review.Participant{Login: "alice"}
+	author = review.Participant{Login: "author", Reviewee: true}
+)
+
+func newPullRequest(minutes string, threads ...review.Thread) *review.PullRequest {
+	return &review.PullRequest{
+		Url:     "u",
+		Threads: threads,
+		Rounds:  []review.ReviewRound{{ReviewTimes: "1", ReviewTime: rvtime.ReviewTime{ReviewMinutes: minutes}}},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	older := newPullRequest("30",
+		review.Thread{Id: "t1", Url: "u1", Threaded: true, HasResolvedStatus: true, Comments: []review.Comment{
+			{Id: "c1", Author: alice, Body: "指摘1", CreatedAt: at(10, 0)},
+		}},
+		review.Thread{Id: "t2", Url: "u2", Threaded: true, HasResolvedStatus: true, Resolved: true, Comments: []review.Comment{
+			{Id: "c2", Author: alice, Body: "指摘2", CreatedAt: at(10, 0)},
+		}},
+	)
+	resolvedAt := at(12, 0)
+	newer := newPullRequest("60",
+		review.Thread{Id: "t1", Url: "u1", Threaded: true, HasResolvedStatus: true, Resolved: true, ResolvedAt: &resolvedAt, Comments: []review.Comment{
+			{Id: "c1", Author: alice, Body: "指摘1", CreatedAt: at(10, 0)},
+			{Id: "c3", Author: author, Body: "対応1", CreatedAt: at(11, 0)},
+		}},
+		review.Thread{Id: "t2", Url: "u2", Threaded: true, HasResolvedStatus: true, Comments: []review.Comment{
+			{Id: "c2", Author: alice, Body: "指摘2", CreatedAt: at(10, 0)},
+		}},
+		review.Thread{Id: "t3", Url: "u3", Comments: []review.Comment{
+			{Id: "c4", Author: alice, Body: "[must] 指摘3", Severity: "must", CreatedAt: at(13, 0)},
+		}},
+		review.Thread{Id: "t4", Url: "u4", Comments: []review.Comment{
+			{Id: "c5", Author: author, Body: "質問です。", CreatedAt: at(13, 0)},
+		}},
+	)
+	diff, err := Compare(older, newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Rounds) != 1 || diff.Rounds[0].Old.ReviewMinutes != "30" || diff.Rounds[0].New.ReviewMinutes != "60" {
+		t.Errorf("レビュー時間の変化が期待通りではありません。実際の値：%+v", diff.Rounds)
+	}
+	expected := []Change{
+		{Kind: Answered, Url: "u1", Author: "author", Body: "対応1", At: at(11, 0)},
+		{Kind: Resolved, Url: "u1", Author: "alice", Body: "指摘1", At: at(12, 0)},
+		{Kind: Reopened, Url: "u2", Author: "alice", Body: "指摘2"},
+		{Kind: Raised, Url: "u3", Author: "alice", Body: "[must] 指摘3", At: at(13, 0), Severity: "must"},
+		// metricsやCSVと同じく、レビュイーが始めたスレッドも指摘とみなす
+		{Kind: Raised, Url: "u4", Author: "author", Body: "質問です。", At: at(13, 0)},
+	}
+	if !reflect.DeepEqual(diff.Changes, expected) {
+		t.Errorf("期待値は %v ですが実際には %v でした", expected, diff.Changes)
+	}
+}
+
+func TestCompareRounds(t *testing.T) {
+	round := func(times, date, start, end, minutes string) review.ReviewRound {
+		return review.ReviewRound{ReviewTimes: times, ReviewTime: rvtime.ReviewTime{ReviewDate: date, ReviewStartTime: start, ReviewEndTime: end, ReviewMinutes: minutes}}
+	}
+	older := &review.PullRequest{Url: "u", Rounds: []review.ReviewRound{
+		round("1", "2024/5/1", "10:00", "11:00", "60"),
+		round("2", "2024/5/2", "10:00", "11:00", "60"),
+		round("3", "2024/5/3", "10:00", "11:00", "60"),
+	}}
+	newer := &review.PullRequest{Url: "u", Rounds: []review.ReviewRound{
+		round("1", "2024/5/1", "10:00", "11:00", "60"),
+		// レビュー時間の合計が変わらなくても、日時が変われば検出する
+		round("2", "2024/5/2", "13:00", "14:00", "60"),
+		round("4", "2024/5/4", "10:00", "10:30", "30"),
+	}}
+	diff, err := Compare(older, newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []RoundChange{
+		{ReviewTimes: "2", Old: &older.Rounds[1].ReviewTime, New: &newer.Rounds[1].ReviewTime},
+		{ReviewTimes: "4", New: &newer.Rounds[2].ReviewTime},
+		{ReviewTimes: "3", Old: &older.Rounds[2].ReviewTime},
+	}
+	if !reflect.DeepEqual(diff.Rounds, expected) {
+		t.Errorf("期待値は %+v ですが実際には %+v でした", expected, diff.Rounds)
+	}
+}
+
+func TestCompareDifferentPullRequest(t *testing.T) {
+	other := newPullRequest("0")
+	other.Url = "other"
+	_, err := Compare(newPullRequest("0"), other)
+	if err == nil || err.Error() != "異なるプルリクエストのJSONファイルは比較できません。" {
+		t.Errorf("期待したエラーが発生しませんでした: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Gitホスティングサービスのユーザー名を、レビュー記録に書く氏名や所属に読み替えるパッケージ。
+package directory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// ユーザーの氏名と所属
+type User struct {
+	// 氏名
+	Name string
+	// 所属する組織や部署。空の場合もある。
+	Department string
+}
+
+// ユーザー名から氏名と所属を引くためのユーザーディレクトリ。
+type Directory struct {
+	users map[string]User
+}
+
+// CSVファイルからユーザーディレクトリを読み込む。パスが空の場合はnilを返す。
+func Load(path string) (*Directory, error) {
+	if path == "" {
+		return nil, nil
+	}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("ユーザーディレクトリのファイルを読み込めません。user-directoryの設定を見直してください。")
+	}
+	return Read(bytes.NewReader(bs))
+}
+
+// 「ユーザー名,氏名,所属」の形式のCSVからユーザーディレクトリを読み込む。所属の列は省略できる。
+// 1行目の先頭がloginの場合は見出しの行とみなして読み飛ばす。
+// Excelで作成したファイルを扱えるよう、UTF-8として不正な場合はShift_JISとして読み込み、BOMは取り除く。
+func Read(r io.Reader) (*Directory, error) {
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.New("ユーザーディレクトリのファイルを読み込めません。user-directoryの設定を見直してください。")
+	}
+	if !utf8.Valid(bs) {
+		if bs, _, err = transform.Bytes(japanese.ShiftJIS.NewDecoder(), bs); err != nil {
+			return nil, errors.New("ユーザーディレクトリのファイルの文字コードはUTF-8かShift_JISにしてください。")
+		}
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(bs, []byte("\uFEFF"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.New("ユーザーディレクトリのファイルの形式が不正です。")
+	}
+	directory := &Directory{users: make(map[string]User, len(records))}
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "login") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, errors.New("ユーザーディレクトリのファイルの形式が不正です。ユーザー名と氏名を設定してください。")
+		}
+		login := strings.TrimSpace(record[0])
+		if login == "" {
+			continue
+		}
+		user := User{Name: strings.TrimSpace(record[1])}
+		if len(record) >= 3 {
+			user.Department = strings.TrimSpace(record[2])
+		}
+		directory.users[login] = user
+	}
+	return directory, nil
+}
+
+// ユーザー名からユーザーを引く。登録されていなければfalseを返す。
+func (directory *Directory) Lookup(login string) (User, bool) {
+	user, ok := directory.users[login]
+	return user, ok
+}
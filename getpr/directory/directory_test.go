@@ -0,0 +1,45 @@
+package directory
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestRead(t *testing.T) {
+	sjis, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte("login,name,department\r\ntnakamura-xyz,中村 太郎,開発部\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := []struct {
+		name  string
+		input []byte
+	}{
+		{"UTF-8", []byte("\uFEFFlogin,name,department\ntnakamura-xyz, 中村 太郎 ,開発部\nsuzuki,鈴木 花子\n")},
+		{"Shift_JIS", sjis},
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			directory, err := Read(bytes.NewReader(fixture.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			user, ok := directory.Lookup("tnakamura-xyz")
+			if !ok || user.Name != "中村 太郎" || user.Department != "開発部" {
+				t.Errorf("期待値は %v ですが実際には %v でした", User{"中村 太郎", "開発部"}, user)
+			}
+			if _, ok := directory.Lookup("login"); ok {
+				t.Error("見出しの行がユーザーとして読み込まれました")
+			}
+		})
+	}
+}
+
+func TestReadError(t *testing.T) {
+	_, err := Read(strings.NewReader("tnakamura-xyz\n"))
+	if err == nil || err.Error() != "ユーザーディレクトリのファイルの形式が不正です。ユーザー名と氏名を設定してください。" {
+		t.Errorf("期待したエラーが発生しませんでした: %v", err)
+	}
+}
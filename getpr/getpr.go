@@ -0,0 +1,395 @@
+// プルリクエスト/マージリクエストからレビュー情報を抽出する処理を、コマンドラインを介さずに利用するためのパッケージ。
+package getpr
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/anonymize"
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/csv"
+	"github.com/Fintan-contents/review-support-tool/getpr/directory"
+	"github.com/Fintan-contents/review-support-tool/getpr/git"
+	"github.com/Fintan-contents/review-support-tool/getpr/git/request"
+	"github.com/Fintan-contents/review-support-tool/getpr/json"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/state"
+	"github.com/Fintan-contents/review-support-tool/getpr/summary"
+	"github.com/Fintan-contents/review-support-tool/getpr/tag"
+)
+
+// 抽出の設定。ゼロ値の項目は、コメントに書いた既定値（コマンドライン引数を省略した場合と同じ値）を使用する。
+// 各項目の意味は、同名のコマンドライン引数と同じ。
+type Options struct {
+	// Gitホスティングサービス。github、gitlab、gitbucket、giteaのいずれかの値。必須。
+	Target string
+	// APIのルートURI。GitHub、GitLab、GiteaのSaaS版は省略できる。
+	Endpoint string
+	// APIを使用するためのアクセストークン。GitBucketはユーザー名とパスワードをコロンで繋いだもの。必須。
+	AccessToken string
+	// オーガニゼーション。GitLabは不要。
+	Org string
+	// リポジトリ名（GitHub、GitBucket、Gitea）、またはプロジェクトID（GitLab）。必須。
+	Repo string
+	// プルリクエスト（マージリクエスト）のID。必須。
+	Pull string
+
+	// スレッド形式のコメントをまとめる際、2つめ以降のコメントに付けるプレフィックス。既定値は「(追記)」。
+	PostScriptPrefix string
+	// レビュアーのコメントとレビュイーのコメントを分けるデリミタ。既定値は「~~」。
+	Delimiter string
+	// Delimiterに加えてデリミタとみなす文字列
+	ExtraDelimiters []string
+	// デリミタとみなす行の正規表現
+	DelimiterPattern string
+	// レビュー回数。既定値は「1」。
+	ReviewTimes string
+	// 全てのレビュー回のレビュー日時コメントを読み取る場合はtrue
+	AllReviewTimes bool
+	// ページングを行う場合の1ページあたりのサイズ。既定値は100。
+	PageSize int
+	// プルリクエスト作成者以外にレビュイーとみなすユーザー名
+	Reviewees []string
+	// アサイン先をレビュイーとみなす場合はtrue
+	IncludeAssignees bool
+	// コミットの作成者をレビュイーとみなす場合はtrue
+	IncludeCommitAuthors bool
+	// レビュー日時コメントがない場合にレビュー時間を推定する場合はtrue
+	EstimateReviewTime bool
+	// レビュー時間を推定する際、同じレビューとみなすコメントの投稿間隔の上限（分）。既定値は30。
+	IdleGap int
+	// 日時を扱う際のタイムゾーン。既定値は「Asia/Tokyo」。
+	Timezone string
+	// 処理全体の制限時間（秒）。0の場合は制限しない。
+	Timeout int
+	// 1回のHTTPリクエストの制限時間（秒）。既定値は60。負の値の場合は制限しない。HttpClientを指定した場合は使用しない。
+	RequestTimeout int
+	// APIへ並行して送信するリクエストの数の上限。既定値は4。
+	Concurrency int
+	// プロキシ。HttpClientを指定した場合は使用しない。
+	Proxy string
+	// APIのレスポンスを保存するディレクトリ
+	CacheDir string
+	// APIへリクエストを送信せず、CacheDirに保存したレスポンスだけを使用する場合はtrue
+	FromCache bool
+	// APIとのやり取りを記録するディレクトリ
+	Record string
+	// Recordで記録したやり取りを再生するディレクトリ
+	Replay string
+	// 指摘の重要度と種別を読み取るタグの規則を書いたJSONファイルのパス。省略した場合は既定の規則を使用する。
+	TagRules string
+	// 読み取ったタグをコメント本文から取り除く場合はtrue
+	StripTags bool
+	// CSVへ書き出すコメント本文の形式。markdown、plain、htmlのいずれかの値。既定値は「markdown」。
+	BodyFormat string
+	// スレッド形式の指摘の対応内容から引用を取り除く場合はtrue
+	StripQuotes bool
+	// ユーザー名と氏名、所属を書いたCSVファイルのパス
+	UserDirectory string
+	// UserDirectoryに登録されていないユーザーの名前に表示名を使用する場合はtrue
+	UseHostNames bool
+	// ユーザー名とURLを仮名に置き換え、機密情報を伏せる場合はtrue
+	Anonymize bool
+	// 仮名と元の値の対応表を保存するJSONファイルのパス。Anonymizeを指定した場合は必須。
+	AnonymizeKeys string
+	// Anonymizeで既定のものに加えて伏せる正規表現の配列を書いたJSONファイルのパス
+	RedactPatterns string
+	// 書き出した指摘を記録する状態のJSONファイルのパス
+	SinceState string
+	// 抽出後にまとめのコメントをプルリクエストへ投稿する場合はtrue
+	PostSummary bool
+
+	// APIの呼び出しに使用するHTTPクライアント。nilの場合はProxyとRequestTimeoutに従って構築する。
+	HttpClient *http.Client
+	// 抽出結果の書き出し先。抽出に成功した場合に順番に呼び出される。
+	Writers []Writer
+}
+
+// 抽出の設定を、既定値を補ったツールの設定に変換する。
+func (options Options) config() *cfg.Config {
+	config := &cfg.Config{
+		Target:               options.Target,
+		Endpoint:             options.Endpoint,
+		AccessToken:          options.AccessToken,
+		Org:                  options.Org,
+		Repo:                 options.Repo,
+		Pull:                 options.Pull,
+		PostScriptPrefix:     defaultString(options.PostScriptPrefix, "(追記)"),
+		Delimiter:            options.Delimiter,
+		ExtraDelimiters:      options.ExtraDelimiters,
+		DelimiterPattern:     options.DelimiterPattern,
+		ReviewTimes:          defaultString(options.ReviewTimes, "1"),
+		AllReviewTimes:       options.AllReviewTimes,
+		PageSize:             defaultInt(options.PageSize, 100),
+		Reviewees:            strings.Join(options.Reviewees, ","),
+		IncludeAssignees:     options.IncludeAssignees,
+		IncludeCommitAuthors: options.IncludeCommitAuthors,
+		EstimateReviewTime:   options.EstimateReviewTime,
+		IdleGap:              defaultInt(options.IdleGap, 30),
+		Timezone:             defaultString(options.Timezone, "Asia/Tokyo"),
+		Timeout:              options.Timeout,
+		RequestTimeout:       defaultInt(options.RequestTimeout, 60),
+		Concurrency:          defaultInt(options.Concurrency, 4),
+		Proxy:                options.Proxy,
+		CacheDir:             options.CacheDir,
+		FromCache:            options.FromCache,
+		Record:               options.Record,
+		Replay:               options.Replay,
+		TagRules:             options.TagRules,
+		StripTags:            options.StripTags,
+		BodyFormat:           defaultString(options.BodyFormat, cfg.BodyMarkdown),
+		StripQuotes:          options.StripQuotes,
+		UserDirectory:        options.UserDirectory,
+		UseHostNames:         options.UseHostNames,
+		Anonymize:            options.Anonymize,
+		AnonymizeKeys:        options.AnonymizeKeys,
+		RedactPatterns:       options.RedactPatterns,
+		SinceState:           options.SinceState,
+		PostSummary:          options.PostSummary,
+	}
+	// デリミタの正規表現だけを指定した場合は既定のデリミタを使用しない
+	if len(config.Delimiter) == 0 && len(config.ExtraDelimiters) == 0 && len(config.DelimiterPattern) == 0 {
+		config.Delimiter = "~~"
+	}
+	// ツールの設定では0が無制限のため、負の値を0に読み替える
+	if config.RequestTimeout < 0 {
+		config.RequestTimeout = 0
+	}
+	return config
+}
+
+func defaultString(value string, defaultValue string) string {
+	if len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func defaultInt(value int, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// 抽出結果
+type Result struct {
+	// Gitホスティングサービスに依存しないレビュー情報
+	PullRequest *review.PullRequest
+	// CSVファイルへ書き出す内容
+	CsvData *csv.CsvData
+	// 抽出時の警告メッセージ
+	Warnings []string
+}
+
+// 抽出結果の書き出し先
+type Writer interface {
+	// 抽出結果を書き出す。contextがキャンセルされた場合は書き出しを中断してエラーを返す。
+	Write(ctx context.Context, result *Result) error
+}
+
+// プルリクエストの情報を取得してレビュー情報を抽出し、Writersへ書き出す。
+// contextがキャンセルされた場合やTimeoutを超えた場合は処理を中断してエラーを返す。
+func Extract(ctx context.Context, options Options) (*Result, error) {
+	config := options.config()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config.SetupEndpoint()
+	tagRules, err := tag.LoadRules(config.TagRules)
+	if err != nil {
+		return nil, err
+	}
+	users, err := directory.Load(config.UserDirectory)
+	if err != nil {
+		return nil, err
+	}
+	var exported *state.State
+	if len(config.SinceState) != 0 {
+		exported, err = state.Load(config.SinceState)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var keys *anonymize.Keys
+	var anonymizer *anonymize.Anonymizer
+	if config.Anonymize {
+		patterns, err := anonymize.LoadPatterns(config.RedactPatterns)
+		if err != nil {
+			return nil, err
+		}
+		keys, err = anonymize.LoadKeys(config.AnonymizeKeys)
+		if err != nil {
+			return nil, err
+		}
+		anonymizer, err = anonymize.New(keys, patterns, config.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	httpClient := options.HttpClient
+	if httpClient == nil {
+		var err error
+		httpClient, err = git.BuildHttpClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	gitService, err := git.BuildGitService(config, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// プルリクエストの情報を取得する
+	pr, err := gitService.ParsePullRequest(ctx)
+	// レスポンスの読み込み中に中断された場合もあるため、エラーの内容によらず中断を優先して知らせる
+	if ctx.Err() != nil {
+		return nil, request.Error(ctx, ctx.Err())
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 以前に投稿したまとめのコメントは指摘とみなさない
+	summaryId := summary.Exclude(pr)
+	review.Analyze(pr, review.AnalyzeOptions{
+		ReviewTimes:        config.ReviewTimes,
+		AllReviewTimes:     config.AllReviewTimes,
+		EstimateReviewTime: config.EstimateReviewTime,
+		IdleGap:            time.Duration(config.IdleGap) * time.Minute,
+		Location:           config.Location(),
+	})
+	review.Classify(pr, tagRules, config.StripTags)
+	review.ResolveUsers(pr, users, config.UseHostNames)
+	// 匿名化するとスレッドのIDが変わるため、先に変更種別を設定する
+	if exported != nil {
+		exported.Mark(pr)
+	}
+	if anonymizer != nil {
+		anonymizer.Apply(pr)
+		if err := keys.Save(config.AnonymizeKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{
+		PullRequest: pr,
+		CsvData:     csv.Build(pr, config),
+		Warnings:    pr.Warnings,
+	}
+	for _, writer := range options.Writers {
+		if err := writer.Write(ctx, result); err != nil {
+			if ctx.Err() != nil {
+				return nil, request.Error(ctx, ctx.Err())
+			}
+			return nil, err
+		}
+	}
+	// 書き出しに失敗した指摘を次回も書き出せるよう、全ての書き出しが終わってから状態を保存する
+	if exported != nil {
+		if err := exported.Save(config.SinceState); err != nil {
+			return nil, err
+		}
+	}
+	if config.PostSummary {
+		// GitBucketはValidateで除外済み
+		commenter := gitService.(git.Commenter)
+		if err := commenter.PostComment(ctx, summaryId, summary.Build(pr)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// CSV形式の書き出し先
+type CsvWriter struct {
+	// 書き出し先
+	Writer io.Writer
+	// 文字コードをShift_JISにする場合はtrue。falseの場合はUTF-8で書き出す。
+	UseSjis bool
+}
+
+// 抽出結果をCSV形式で書き出す。
+func (w *CsvWriter) Write(ctx context.Context, result *Result) error {
+	return csv.Write(&contextWriter{ctx, w.Writer}, result.CsvData, w.UseSjis)
+}
+
+// JSON形式の書き出し先
+type JsonWriter struct {
+	// 書き出し先
+	Writer io.Writer
+}
+
+// 抽出結果をJSON形式で書き出す。
+func (w *JsonWriter) Write(ctx context.Context, result *Result) error {
+	return json.Write(&contextWriter{ctx, w.Writer}, result.PullRequest)
+}
+
+// CSVファイルの書き出し先
+type CsvFileWriter struct {
+	// CSVファイルのパス
+	Path string
+	// 文字コードをShift_JISにする場合はtrue。falseの場合はUTF-8で書き出す。
+	UseSjis bool
+}
+
+// 抽出結果をCSVファイルへ書き出す。書き出しに失敗した場合は書きかけのファイルを削除する。
+func (w *CsvFileWriter) Write(ctx context.Context, result *Result) error {
+	return WriteFile(w.Path, func(file io.Writer) error {
+		return (&CsvWriter{Writer: file, UseSjis: w.UseSjis}).Write(ctx, result)
+	})
+}
+
+// JSONファイルの書き出し先
+type JsonFileWriter struct {
+	// JSONファイルのパス
+	Path string
+}
+
+// 抽出結果をJSONファイルへ書き出す。書き出しに失敗した場合は書きかけのファイルを削除する。
+func (w *JsonFileWriter) Write(ctx context.Context, result *Result) error {
+	return WriteFile(w.Path, func(file io.Writer) error {
+		return (&JsonWriter{Writer: file}).Write(ctx, result)
+	})
+}
+
+// ファイルを作成してwriteで書き出す。書き出しに失敗した場合は書きかけのファイルを削除する。
+// 抽出結果以外をファイルへ書き出す場合にも使用できる。
+func WriteFile(path string, write func(file io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// 書き込みのたびにcontextのキャンセルを確認するWriter。
+type contextWriter struct {
+	ctx    context.Context
+	writer io.Writer
+}
+
+func (w *contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.writer.Write(p)
+}
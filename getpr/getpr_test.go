@@ -0,0 +1,154 @@
+package getpr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/csv"
+)
+
+func TestExtract(t *testing.T) {
+	responses := map[string]string{
+		"/projects/1/merge_requests/2": `{"author": {"username": "author"}, "web_url": "https://gitlab.example.com/mr/2"}`,
+		"/projects/1/merge_requests/2/discussions": `[
+			{"id": "d1", "notes": [{"id": 10, "body": "レビュー1回目\n2024/5/1\n10:00\n11:00\n60", "author": {"username": "alice"}, "created_at": "2024-05-01T02:00:00Z"}]},
+			{"id": "d2", "notes": [
+				{"id": 11, "body": "指摘", "author": {"username": "alice"}, "created_at": "2024-05-01T01:00:00Z"},
+				{"id": 12, "body": "対応", "author": {"username": "author"}, "created_at": "2024-05-01T03:00:00Z", "resolved": true}
+			]}
+		]`,
+		"/projects/1/merge_requests/2/changes": `{"changes": [{"diff": "@@ -1 +1 @@\n-a\n+b\n+c\n"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	// 省略した項目は既定値を使用する
+	options := Options{Target: "gitlab", Endpoint: server.URL, AccessToken: "token", Repo: "1", Pull: "2"}
+	options.HttpClient = server.Client()
+	buf := &bytes.Buffer{}
+	options.Writers = []Writer{&CsvWriter{Writer: buf}}
+
+	result, err := Extract(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("警告は発生しない想定です。実際の値：%v", result.Warnings)
+	}
+	expected := strings.Join([]string{
+		"2,1,2024/5/1,10:00,11:00,60,1,2024/5/1 10:00-11:00,false",
+		"https://gitlab.example.com/mr/2#note_11,指摘,alice,対応,author,true,true,1,2024/05/01 10:00:00,2024/05/01 12:00:00,,,,,",
+		"",
+	}, "\r\n")
+	if buf.String() != expected {
+		t.Errorf(`Expected is "%v" but actual is "%v"`, expected, buf.String())
+	}
+}
+
+func TestExtractInvalidOptions(t *testing.T) {
+	_, err := Extract(context.Background(), Options{})
+	if err == nil || err.Error() != "Gitホスティングサービスを設定してください。" {
+		t.Errorf("設定のバリデーションエラーになる想定です。実際の値：%v", err)
+	}
+}
+
+func TestExtractCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	options := Options{Target: "gitlab", Endpoint: server.URL, AccessToken: "token", Repo: "1", Pull: "2"}
+	csvFile := filepath.Join(t.TempDir(), "canceled.csv")
+	options.Writers = []Writer{&CsvFileWriter{Path: csvFile}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := Extract(ctx, options)
+	if err == nil || err.Error() != "処理を中断しました。" {
+		t.Errorf("中断のエラーになる想定です。実際の値：%v", err)
+	}
+	if _, err := os.Stat(csvFile); !os.IsNotExist(err) {
+		t.Errorf("CSVファイルは作成されない想定です。")
+	}
+}
+
+func TestCsvFileWriterCanceled(t *testing.T) {
+	csvFile := filepath.Join(t.TempDir(), "canceled.csv")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writer := &CsvFileWriter{Path: csvFile}
+	if err := writer.Write(ctx, &Result{CsvData: &csv.CsvData{}}); err == nil {
+		t.Errorf("中断のエラーになる想定です。")
+	}
+	if _, err := os.Stat(csvFile); !os.IsNotExist(err) {
+		t.Errorf("書きかけのCSVファイルは削除される想定です。")
+	}
+}
+
+func TestWriteFileError(t *testing.T) {
+	// 書き出し先がディレクトリの場合はファイルを作成できない
+	err := WriteFile(t.TempDir(), func(file io.Writer) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("ファイルを作成できないエラーになる想定です。実際の値：%v", err)
+	}
+}
+
+func TestWriteFilePartial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "partial.csv")
+	failed := errors.New("書き出しに失敗しました。")
+	err := WriteFile(path, func(file io.Writer) error {
+		file.Write([]byte("途中まで"))
+		return failed
+	})
+	if err != failed {
+		t.Errorf("期待値は %v ですが実際には %v でした", failed, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("書きかけのファイルは削除される想定です。")
+	}
+
+	if err := WriteFile(path, func(file io.Writer) error {
+		_, err := file.Write([]byte("完了"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if bs, err := os.ReadFile(path); err != nil || string(bs) != "完了" {
+		t.Errorf("期待値は %v ですが実際には %v でした", "完了", string(bs))
+	}
+}
+
+func TestCsvFileWriterError(t *testing.T) {
+	writer := &CsvFileWriter{Path: t.TempDir()}
+	err := writer.Write(context.Background(), &Result{CsvData: &csv.CsvData{}})
+	if err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("ファイルを作成できないエラーになる想定です。実際の値：%v", err)
+	}
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	config := Options{DelimiterPattern: "={3,}", RequestTimeout: -1}.config()
+	if config.Delimiter != "" || config.RequestTimeout != 0 || config.PageSize != 100 || config.Concurrency != 4 || config.IdleGap != 30 || config.ReviewTimes != "1" {
+		t.Errorf("既定値が期待通りではありません。実際の値：%+v", config)
+	}
+	if config := (Options{}).config(); config.Delimiter != "~~" || config.RequestTimeout != 60 || config.Timezone != "Asia/Tokyo" || config.BodyFormat != "markdown" {
+		t.Errorf("既定値が期待通りではありません。実際の値：%+v", config)
+	}
+}
@@ -0,0 +1,167 @@
+// APIのレスポンスをディレクトリへ保存し、再実行時に再利用するためのパッケージ。
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// オフラインで実行した際に、リクエストに対応するレスポンスが保存されていない場合のエラー。
+var ErrNotCached = errors.New("キャッシュにレスポンスが保存されていません。from-cacheを指定せずに一度実行してください。")
+
+// レスポンスの保存に失敗した場合のエラー。
+var ErrSave = errors.New("キャッシュへの保存に失敗しました。cache-dirの設定を見直してください。")
+
+// レスポンスをディレクトリへ保存するRoundTripper。
+//
+// 成功したレスポンスをリクエストごとに保存し、GETリクエストでは保存したレスポンスのETagとLast-Modifiedを
+// If-None-MatchとIf-Modified-Sinceに設定して送信する。304 Not Modifiedが返った場合は保存したレスポンスを返す。
+// Offlineがtrueの場合はリクエストを送信せず、保存したレスポンスだけを返す。
+type Transport struct {
+	// レスポンスを保存するディレクトリ
+	Dir string
+	// リクエストを送信せずに保存したレスポンスを返す場合はtrue
+	Offline bool
+	// リクエストの送信に使用するRoundTripper。nilの場合はhttp.DefaultTransportを使用する。
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := t.path(req)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := load(path, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Offline {
+		if cached == nil {
+			return nil, ErrNotCached
+		}
+		return cached, nil
+	}
+
+	if cached != nil && req.Method == http.MethodGet {
+		// RoundTripperはリクエストを変更してはならないため、複製してから条件付きリクエストにする
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" && req.Header.Get("If-None-Match") == "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" && req.Header.Get("If-Modified-Since") == "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		if cached != nil {
+			cached.Body.Close()
+		}
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		return cached, nil
+	}
+	if cached != nil {
+		cached.Body.Close()
+	}
+	// 失敗したレスポンスは保存しない
+	if !(200 <= resp.StatusCode && resp.StatusCode <= 399) || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err := save(path, resp, body); err != nil {
+		return nil, ErrSave
+	}
+	return resp, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// リクエストに対応するレスポンスの保存先を返す。
+// GraphQLは同じURLへクエリーを送信するため、メソッドとURLに加えてリクエストボディからキーを求める。
+func (t *Transport) path(req *http.Request) (string, error) {
+	hash := sha256.New()
+	io.WriteString(hash, req.Method+"\n"+req.URL.String()+"\n")
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		if _, err := io.Copy(hash, body); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(t.Dir, hex.EncodeToString(hash.Sum(nil))), nil
+}
+
+// 保存したレスポンスを読み込む。保存されていない場合はnilを返す。
+func load(path string, req *http.Request) (*http.Response, error) {
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(bs)), req)
+	if err != nil {
+		// 壊れたキャッシュは保存されていないものとして扱う
+		return nil, nil
+	}
+	return resp, nil
+}
+
+// レスポンスを保存する。並行して保存しても壊れないように、一時ファイルへ書き出してから置き換える。
+func save(path string, resp *http.Response, body []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	stored := *resp
+	stored.Header = resp.Header.Clone()
+	// セッションを再利用されないように、Cookieは保存しない
+	stored.Header.Del("Set-Cookie")
+	stored.Body = io.NopCloser(bytes.NewReader(body))
+	stored.ContentLength = int64(len(body))
+	stored.TransferEncoding = nil
+	stored.Close = false
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	err = stored.Write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-Total-Pages", "1")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "body:"+r.URL.Query().Get("page"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	get := func(transport *Transport, page string) (string, error) {
+		client := &http.Client{Transport: transport}
+		resp, err := client.Get(server.URL + "/discussions?page=" + page)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Total-Pages") != "1" {
+			t.Errorf("保存したレスポンスのステータスやヘッダーが期待通りではありません。実際の値：%v %v", resp.StatusCode, resp.Header)
+		}
+		bs, err := io.ReadAll(resp.Body)
+		return string(bs), err
+	}
+
+	online := &Transport{Dir: dir}
+	for i := 0; i < 2; i++ {
+		body, err := get(online, "1")
+		if err != nil || body != "body:1" {
+			t.Fatalf("レスポンスが期待通りではありません。実際の値：%v %v", body, err)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("2回目は条件付きリクエストを送信する想定です。リクエスト数：%v 304の数：%v", requests, notModified)
+	}
+
+	offline := &Transport{Dir: dir, Offline: true}
+	body, err := get(offline, "1")
+	if err != nil || body != "body:1" {
+		t.Errorf("オフラインでは保存したレスポンスを返す想定です。実際の値：%v %v", body, err)
+	}
+	if requests != 2 {
+		t.Errorf("オフラインではリクエストを送信しない想定です。リクエスト数：%v", requests)
+	}
+	if _, err := get(offline, "2"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("保存されていないレスポンスはエラーになる想定です。実際の値：%v", err)
+	}
+}
+
+func TestTransportRequestBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	post := func(transport *Transport, query string) (*http.Response, string, error) {
+		client := &http.Client{Transport: transport}
+		resp, err := client.Post(server.URL+"/graphql", "application/json", strings.NewReader(query))
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		bs, err := io.ReadAll(resp.Body)
+		return resp, string(bs), err
+	}
+
+	// 同じURLでもリクエストボディが異なれば別のレスポンスとして保存する
+	for _, query := range []string{"query A", "query B"} {
+		if _, _, err := post(&Transport{Dir: dir}, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, query := range []string{"query A", "query B"} {
+		resp, body, err := post(&Transport{Dir: dir, Offline: true}, query)
+		if err != nil || body != query {
+			t.Errorf("リクエストボディに対応するレスポンスを返す想定です。期待値：%v 実際の値：%v %v", query, body, err)
+			continue
+		}
+		if len(resp.Cookies()) != 0 {
+			t.Errorf("Cookieは保存しない想定です。実際の値：%v", resp.Cookies())
+		}
+	}
+}
@@ -1,326 +1,329 @@
-package gitbucket
-
-import (
-	"errors"
-	"io"
-	"net/http"
-	"net/url"
-	"strings"
-
-	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
-	"github.com/Fintan-contents/review-support-tool/getpr/csv"
-	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
-	"github.com/Fintan-contents/review-support-tool/getpr/text"
-	"golang.org/x/net/html"
-)
-
-type GitBucket struct {
-	Config     *cfg.Config
-	HttpClient *http.Client
-}
-
-func (gitBucket *GitBucket) ParsePullRequest() (*csv.CsvData, error) {
-
-	htmlSource, err := gitBucket.getHtml()
-	if err != nil {
-		return nil, err
-	}
-
-	return gitBucket.parseHtml(htmlSource)
-}
-
-// HTTPクライアントを利用して次の手順でプルリクエストのページのHTMLを取得する
-//
-//  1. ログインページを開く
-//  2. ログインを実施する
-//  3. プルリクエストのページを開く
-func (gitBucket *GitBucket) getHtml() (string, error) {
-
-	// ログインページを開く
-	resp, err := gitBucket.HttpClient.Get(gitBucket.Config.Endpoint + "/signin")
-	if err != nil {
-		// 実装を見る限り、ここで発生するエラーはURLが不正なことが原因
-		return "", errors.New("エラーが発生しました。エンドポイントの設定を見直してください。")
-	}
-	defer resp.Body.Close()
-	if 200 != resp.StatusCode {
-		return "", errors.New("エラーが発生しました。エンドポイントの設定を見直してください。")
-	}
-
-	username, password, found := strings.Cut(gitBucket.Config.AccessToken, ":")
-	if !found {
-		return "", errors.New("GitBucketのアクセストークンはユーザー名とパスワードを:で繋いだものを設定してください。")
-	}
-
-	// ログインを実施する
-	form := url.Values{}
-	form.Add("userName", username)
-	form.Add("password", password)
-	resp, err = gitBucket.HttpClient.PostForm(gitBucket.Config.Endpoint+"/signin", form)
-	if err != nil {
-		return "", errors.New("エラーが発生しました。")
-	}
-	defer resp.Body.Close()
-	if !(200 <= resp.StatusCode && resp.StatusCode <= 399) {
-		return "", errors.New("エラーが発生しました。")
-	}
-	defer func() {
-		resp, err = gitBucket.HttpClient.Get(gitBucket.Config.Endpoint + "/signout")
-		if err != nil {
-			return
-		}
-		defer resp.Body.Close()
-	}()
-
-	// プルリクエストのページを開く
-	resp, err = gitBucket.HttpClient.Get(gitBucket.buildUrl())
-	if err != nil {
-		return "", errors.New("エラーが発生しました。")
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusUnauthorized {
-		return "", errors.New("認証に失敗したか、あるいはリポジトリへアクセスする権限がありません。アクセストークンやオーガニゼーション、リポジトリの設定を見直してください。")
-	} else if resp.StatusCode == http.StatusNotFound {
-		return "", errors.New("プルリクエストが見つかりません。オーガニゼーションやリポジトリ、プルリクエストIDの設定を確認してください。")
-	} else if !(200 <= resp.StatusCode && resp.StatusCode <= 399) {
-		return "", errors.New("エラーが発生しました。")
-	}
-
-	bs, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", errors.New("エラーが発生しました。")
-	}
-
-	s := string(bs)
-	return s, nil
-}
-
-func (gitBucket *GitBucket) buildUrl() string {
-	return gitBucket.Config.Endpoint + "/" + gitBucket.Config.Org + "/" + gitBucket.Config.Repo + "/pull/" + gitBucket.Config.Pull
-}
-
-// PRに付いたコメントの構造
-// div#comment-list
-//
-//	// PRの概要
-//	div.panel.panel-default.issue-comment-box
-//	    // ユーザー名(PRを作ったユーザー)
-//	    div.panel-heading > a.username.strong
-//	    // 本文
-//	    div.panel-body.markdown-body#issueContent
-//	// レビューコメント
-//	div.panel.panel-default
-//	    // 1つのコメント
-//	    div.commit-comment-box.inline-comment#discussion_r5
-//	        // ユーザー名
-//	        a.username.strong
-//	        // 本文
-//	        div.commit-commentContent-5
-//	// 通常のコメント
-//	div.panel.panel-default.issue-comment-box#comment-1
-//	    // ユーザー名
-//	    div.panel-heading > a.username.strong
-//	    // 本文
-//	    div.panel-body.markdown-body#commentContent-1
-
-// HTMLをパースしてCSVデータを構築する。
-func (gitBucket *GitBucket) parseHtml(htmlSource string) (*csv.CsvData, error) {
-	root, err := html.Parse(strings.NewReader(htmlSource))
-	if err != nil {
-		return nil, errors.New("エラーが発生しました。")
-	}
-	commentNodes := getCommentList(root)
-
-	// 先頭のコメントはPRの概要
-	author := extractAuthor(findElementByClass(commentNodes[0], "panel-heading"))
-
-	csvHeader := csv.CsvHeader{}
-	csvReviewComments := make([]csv.CsvReviewComment, 0)
-	for _, commentNode := range commentNodes[1:] {
-		id, ok := getId(commentNode)
-		if ok {
-			// id属性を持つのが通常のコメント（非スレッド）
-			panelHeading := findElementByClass(commentNode, "panel-heading")
-			if isMergedComment(panelHeading) {
-				// マージ時のコミットコメントは無視する
-				continue
-			}
-			reviewer := extractAuthor(panelHeading)
-			textContent := getTextContent(findElementByClass(commentNode, "panel-body markdown-body"))
-			if rvtime.IsReviewTimeComment(textContent) {
-				// レビュー日時情報が書かれたコメント
-				if rvtime.IsCurrentReviewTimeComment(textContent, gitBucket.Config.ReviewTimes) {
-					csvHeader.ReviewTime = rvtime.ParseReviewTime(textContent, gitBucket.Config.ReviewTimes)
-				}
-			} else {
-				// レビュー指摘事項・対応内容が書かれたコメント
-				reviewComment, revieweeComment := text.SplitComment(textContent, gitBucket.Config.Delimiter)
-				csvReviewComment := csv.CsvReviewComment{
-					Url:               gitBucket.buildUrl() + "#" + id,
-					ReviewerComment:   reviewComment,
-					Reviewer:          reviewer,
-					RevieweeComment:   revieweeComment,
-					Reviewee:          author,
-					Resolved:          false,
-					HasResolvedStatus: false,
-				}
-				csvReviewComments = append(csvReviewComments, csvReviewComment)
-			}
-		} else {
-			// id属性を持たないのはレビューコメント（スレッド）
-			csvReviewComment := gitBucket.buildReviewComment(gitBucket.Config.PostScriptPrefix, author, commentNode)
-			csvReviewComments = append(csvReviewComments, csvReviewComment)
-		}
-	}
-	csvData := &csv.CsvData{
-		CsvHeader:         csvHeader,
-		CsvReviewComments: csvReviewComments,
-	}
-	return csvData, nil
-}
-
-// panel-header に "referenced the  pull request" というテキストがあるとマージ時のコミットコメント。
-func isMergedComment(n *html.Node) bool {
-	if n == nil {
-		return false
-	}
-	element := findElementByClass(n, "muted")
-	if element == nil {
-		return false
-	}
-	textContent := getTextContent(element)
-	return strings.Contains(textContent, "referenced the  pull request")
-}
-
-func getCommentList(n *html.Node) []*html.Node {
-	commentListNode := findElementById(n, "comment-list")
-	childDivs := getChildDivs(commentListNode)
-	commentNodes := make([]*html.Node, 0)
-	for _, cd := range childDivs {
-		if hasClass(cd, "panel") {
-			commentNodes = append(commentNodes, cd)
-		}
-	}
-	return commentNodes
-}
-
-func hasClass(n *html.Node, class string) bool {
-	for _, attr := range n.Attr {
-		if attr.Key == "class" && strings.Contains(attr.Val, class) {
-			return true
-		}
-	}
-	return false
-}
-
-func getChildDivs(n *html.Node) []*html.Node {
-	nodes := make([]*html.Node, 0)
-	if n != nil {
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			if c.Type == html.ElementNode && c.Data == "div" {
-				nodes = append(nodes, c)
-			}
-		}
-	}
-	return nodes
-}
-
-func extractAuthor(n *html.Node) string {
-	usernameNode := findElementByClass(n, "username strong")
-	return getTextContent(usernameNode)
-}
-
-func (gitBucket *GitBucket) buildReviewComment(postScriptPrefix, reviewee string, n *html.Node) csv.CsvReviewComment {
-	childDivs := getChildDivs(findElementByClass(n, "panel-body"))
-	reviewerComment := strings.Builder{}
-	revieweeComment := strings.Builder{}
-	id, _ := getId(childDivs[0])
-	var reviewer string
-	for _, childDiv := range childDivs {
-		nestedChildDivs := getChildDivs(findElementByClass(childDiv, "markdown-body"))
-		author := extractAuthor(nestedChildDivs[0])
-		if textContent := getTextContent(nestedChildDivs[1]); len(textContent) > 0 {
-			if author == reviewee {
-				if revieweeComment.Len() > 0 {
-					revieweeComment.WriteString(text.Crlf)
-					revieweeComment.WriteString(postScriptPrefix)
-				}
-				revieweeComment.WriteString(textContent)
-			} else {
-				if reviewerComment.Len() > 0 {
-					reviewerComment.WriteString(text.Crlf)
-					reviewerComment.WriteString(postScriptPrefix)
-				}
-				reviewerComment.WriteString(textContent)
-				if reviewer == "" {
-					reviewer = author
-				}
-			}
-		}
-	}
-	csvReviewComment := csv.CsvReviewComment{
-		Url:               gitBucket.buildUrl() + "#" + id,
-		ReviewerComment:   reviewerComment.String(),
-		Reviewer:          reviewer,
-		RevieweeComment:   revieweeComment.String(),
-		Reviewee:          reviewee,
-		Resolved:          false,
-		HasResolvedStatus: false,
-	}
-	return csvReviewComment
-}
-
-func getId(n *html.Node) (string, bool) {
-	for _, attr := range n.Attr {
-		if attr.Key == "id" {
-			return attr.Val, true
-		}
-	}
-	return "", false
-}
-
-func getTextContent(n *html.Node) string {
-	builder := strings.Builder{}
-	var fn func(n *html.Node)
-	fn = func(n *html.Node) {
-		if n.Type == html.TextNode {
-			textContent := strings.TrimSpace(n.Data)
-			if len(textContent) > 0 {
-				if builder.Len() > 0 {
-					builder.WriteString(text.Crlf)
-				}
-				builder.WriteString(textContent)
-			}
-			return
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			fn(c)
-		}
-	}
-	fn(n)
-	return builder.String()
-}
-
-func findElementById(n *html.Node, id string) *html.Node {
-	return findElementByAttr(n, "id", id)
-}
-
-func findElementByClass(n *html.Node, class string) *html.Node {
-	return findElementByAttr(n, "class", class)
-}
-
-func findElementByAttr(n *html.Node, attrKey, attrVal string) *html.Node {
-	if n.Type == html.ElementNode {
-		for _, attr := range n.Attr {
-			if attr.Key == attrKey && attr.Val == attrVal {
-				return n
-			}
-		}
-	}
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if found := findElementByAttr(c, attrKey, attrVal); found != nil {
-			return found
-		}
-	}
-	return nil
-}
+package gitbucket
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/git/request"
+	"github.com/Fintan-contents/review-support-tool/getpr/markup"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/reviewee"
+	"golang.org/x/net/html"
+)
+
+type GitBucket struct {
+	Config     *cfg.Config
+	HttpClient *http.Client
+}
+
+func (gitBucket *GitBucket) ParsePullRequest(ctx context.Context) (*review.PullRequest, error) {
+
+	htmlSource, err := gitBucket.getHtml(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return gitBucket.parseHtml(htmlSource)
+}
+
+// HTTPクライアントを利用して次の手順でプルリクエストのページのHTMLを取得する
+//
+//  1. ログインページを開く
+//  2. ログインを実施する
+//  3. プルリクエストのページを開く
+func (gitBucket *GitBucket) getHtml(ctx context.Context) (string, error) {
+
+	// ログインページを開く
+	req, err := http.NewRequestWithContext(ctx, "GET", gitBucket.Config.Endpoint+"/signin", nil)
+	if err != nil {
+		return "", errors.New("エラーが発生しました。エンドポイントの設定を見直してください。")
+	}
+	resp, err := gitBucket.HttpClient.Do(req)
+	if ctx.Err() != nil || request.IsKnown(err) {
+		return "", request.Error(ctx, err)
+	}
+	if err != nil {
+		// 実装を見る限り、ここで発生するエラーはURLが不正なことが原因
+		return "", errors.New("エラーが発生しました。エンドポイントの設定を見直してください。")
+	}
+	defer resp.Body.Close()
+	if 200 != resp.StatusCode {
+		return "", errors.New("エラーが発生しました。エンドポイントの設定を見直してください。")
+	}
+
+	username, password, found := strings.Cut(gitBucket.Config.AccessToken, ":")
+	if !found {
+		return "", errors.New("GitBucketのアクセストークンはユーザー名とパスワードを:で繋いだものを設定してください。")
+	}
+
+	// ログインを実施する
+	form := url.Values{}
+	form.Add("userName", username)
+	form.Add("password", password)
+	req, _ = http.NewRequestWithContext(ctx, "POST", gitBucket.Config.Endpoint+"/signin", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err = gitBucket.HttpClient.Do(req)
+	if err != nil {
+		return "", request.Error(ctx, err)
+	}
+	defer resp.Body.Close()
+	if !(200 <= resp.StatusCode && resp.StatusCode <= 399) {
+		return "", errors.New("エラーが発生しました。")
+	}
+	defer func() {
+		// 中断された場合もログアウトするため、呼び出し元のcontextは使用しない
+		resp, err = gitBucket.HttpClient.Get(gitBucket.Config.Endpoint + "/signout")
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+	}()
+
+	// プルリクエストのページを開く
+	req, _ = http.NewRequestWithContext(ctx, "GET", gitBucket.buildUrl(), nil)
+	resp, err = gitBucket.HttpClient.Do(req)
+	if err != nil {
+		return "", request.Error(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", errors.New("認証に失敗したか、あるいはリポジトリへアクセスする権限がありません。アクセストークンやオーガニゼーション、リポジトリの設定を見直してください。")
+	} else if resp.StatusCode == http.StatusNotFound {
+		return "", errors.New("プルリクエストが見つかりません。オーガニゼーションやリポジトリ、プルリクエストIDの設定を確認してください。")
+	} else if !(200 <= resp.StatusCode && resp.StatusCode <= 399) {
+		return "", errors.New("エラーが発生しました。")
+	}
+
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.New("エラーが発生しました。")
+	}
+
+	s := string(bs)
+	return s, nil
+}
+
+func (gitBucket *GitBucket) buildUrl() string {
+	return gitBucket.Config.Endpoint + "/" + gitBucket.Config.Org + "/" + gitBucket.Config.Repo + "/pull/" + gitBucket.Config.Pull
+}
+
+// PRに付いたコメントの構造
+// div#comment-list
+//
+//	// PRの概要
+//	div.panel.panel-default.issue-comment-box
+//	    // ユーザー名(PRを作ったユーザー)
+//	    div.panel-heading > a.username.strong
+//	    // 本文
+//	    div.panel-body.markdown-body#issueContent
+//	// レビューコメント
+//	div.panel.panel-default
+//	    // 1つのコメント
+//	    div.commit-comment-box.inline-comment#discussion_r5
+//	        // ユーザー名
+//	        a.username.strong
+//	        // 本文
+//	        div.commit-commentContent-5
+//	// 通常のコメント
+//	div.panel.panel-default.issue-comment-box#comment-1
+//	    // ユーザー名
+//	    div.panel-heading > a.username.strong
+//	    // 本文
+//	    div.panel-body.markdown-body#commentContent-1
+
+// HTMLをパースしてレビュー情報を構築する。
+func (gitBucket *GitBucket) parseHtml(htmlSource string) (*review.PullRequest, error) {
+	root, err := html.Parse(strings.NewReader(htmlSource))
+	if err != nil {
+		return nil, errors.New("エラーが発生しました。")
+	}
+	commentNodes := getCommentList(root)
+
+	// 先頭のコメントはPRの概要
+	author := extractAuthor(findElementByClass(commentNodes[0], "panel-heading"))
+	// GitBucketはアサイン先やコミット作成者を取得しないため、プルリクエスト作成者と設定されたユーザーをレビュイーとみなす
+	reviewees := reviewee.New(author, gitBucket.Config.Reviewees)
+
+	threads := make([]review.Thread, 0)
+	for _, commentNode := range commentNodes[1:] {
+		id, ok := getId(commentNode)
+		if ok {
+			// id属性を持つのが通常のコメント（非スレッド）
+			panelHeading := findElementByClass(commentNode, "panel-heading")
+			if isMergedComment(panelHeading) {
+				// マージ時のコミットコメントは無視する
+				continue
+			}
+			createdAt := extractTimestamp(panelHeading, gitBucket.Config.Location())
+			bodyNode := findElementByClass(commentNode, "panel-body markdown-body")
+			threads = append(threads, review.Thread{
+				Id:  id,
+				Url: gitBucket.buildUrl() + "#" + id,
+				Comments: []review.Comment{{
+					Id:        id,
+					Url:       gitBucket.buildUrl() + "#" + id,
+					Author:    review.NewParticipant(extractAuthor(panelHeading), "", reviewees),
+					Body:      markup.TextContent(bodyNode),
+					Html:      markup.InnerHtml(bodyNode),
+					CreatedAt: createdAt,
+					UpdatedAt: createdAt,
+				}},
+			})
+		} else {
+			// id属性を持たないのはレビューコメント（スレッド）
+			threads = append(threads, gitBucket.buildThread(reviewees, commentNode))
+		}
+	}
+	pr := &review.PullRequest{
+		Url:     gitBucket.buildUrl(),
+		Author:  review.NewParticipant(author, "", reviewees),
+		Threads: threads,
+	}
+	return pr, nil
+}
+
+// panel-header に "referenced the  pull request" というテキストがあるとマージ時のコミットコメント。
+func isMergedComment(n *html.Node) bool {
+	if n == nil {
+		return false
+	}
+	element := findElementByClass(n, "muted")
+	if element == nil {
+		return false
+	}
+	textContent := markup.TextContent(element)
+	return strings.Contains(textContent, "referenced the  pull request")
+}
+
+func getCommentList(n *html.Node) []*html.Node {
+	commentListNode := findElementById(n, "comment-list")
+	childDivs := getChildDivs(commentListNode)
+	commentNodes := make([]*html.Node, 0)
+	for _, cd := range childDivs {
+		if hasClass(cd, "panel") {
+			commentNodes = append(commentNodes, cd)
+		}
+	}
+	return commentNodes
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == "class" && strings.Contains(attr.Val, class) {
+			return true
+		}
+	}
+	return false
+}
+
+func getChildDivs(n *html.Node) []*html.Node {
+	nodes := make([]*html.Node, 0)
+	if n != nil {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "div" {
+				nodes = append(nodes, c)
+			}
+		}
+	}
+	return nodes
+}
+
+func extractAuthor(n *html.Node) string {
+	usernameNode := findElementByClass(n, "username strong")
+	return markup.TextContent(usernameNode)
+}
+
+// スレッド形式のレビューコメントを構築する。
+func (gitBucket *GitBucket) buildThread(reviewees *reviewee.Reviewees, n *html.Node) review.Thread {
+	childDivs := getChildDivs(findElementByClass(n, "panel-body"))
+	id, _ := getId(childDivs[0])
+	thread := review.Thread{
+		Id:       id,
+		Url:      gitBucket.buildUrl() + "#" + id,
+		Threaded: true,
+	}
+	for _, childDiv := range childDivs {
+		nestedChildDivs := getChildDivs(findElementByClass(childDiv, "markdown-body"))
+		commentId, _ := getId(childDiv)
+		createdAt := extractTimestamp(childDiv, gitBucket.Config.Location())
+		thread.Comments = append(thread.Comments, review.Comment{
+			Id:        commentId,
+			Url:       gitBucket.buildUrl() + "#" + commentId,
+			Author:    review.NewParticipant(extractAuthor(nestedChildDivs[0]), "", reviewees),
+			Body:      markup.TextContent(nestedChildDivs[1]),
+			Html:      markup.InnerHtml(nestedChildDivs[1]),
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		})
+	}
+	return thread
+}
+
+// コメントの日時はツールチップ（span[data-toggle=tooltip]）のtitle属性に "yyyy-MM-dd HH:mm:ss" 形式で書き出されている。
+// GitBucketのサーバーは設定されたタイムゾーンで日時を書き出しているものとみなす。取得できない場合はゼロ値を返す。
+func extractTimestamp(n *html.Node, loc *time.Location) time.Time {
+	if n == nil {
+		return time.Time{}
+	}
+	if n.Type == html.ElementNode {
+		var isTooltip bool
+		var title string
+		for _, attr := range n.Attr {
+			if attr.Key == "data-toggle" && attr.Val == "tooltip" {
+				isTooltip = true
+			} else if attr.Key == "title" {
+				title = attr.Val
+			}
+		}
+		if isTooltip {
+			if t, err := time.ParseInLocation("2006-01-02 15:04:05", strings.TrimSpace(title), loc); err == nil {
+				return t
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if t := extractTimestamp(c, loc); !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func getId(n *html.Node) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == "id" {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func findElementById(n *html.Node, id string) *html.Node {
+	return findElementByAttr(n, "id", id)
+}
+
+func findElementByClass(n *html.Node, class string) *html.Node {
+	return findElementByAttr(n, "class", class)
+}
+
+func findElementByAttr(n *html.Node, attrKey, attrVal string) *html.Node {
+	if n.Type == html.ElementNode {
+		for _, attr := range n.Attr {
+			if attr.Key == attrKey && attr.Val == attrVal {
+				return n
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElementByAttr(c, attrKey, attrVal); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package gitbucket
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/internal/replaytest"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+)
+
+// testdata/replayのやり取りを記録した際の設定。リクエストボディやURLが一致するよう、記録時と同じ値にする。
+func replayConfig() *cfg.Config {
+	return &cfg.Config{
+		Target:               "gitbucket",
+		Endpoint:             "https://gitbucket.example.com",
+		AccessToken:          "user:password",
+		Org:                  "org",
+		Repo:                 "repo",
+		Pull:                 "1",
+		PageSize:             2,
+		Concurrency:          1,
+		Timezone:             "Asia/Tokyo",
+		IncludeAssignees:     true,
+		IncludeCommitAuthors: true,
+	}
+}
+
+// testdata/replayに記録したやり取りを再生して抽出したレビュー情報を、testdata/replay.jsonの期待値と比較する。
+func TestParsePullRequestReplay(t *testing.T) {
+	replaytest.Compare(t, func(transport http.RoundTripper) (*review.PullRequest, error) {
+		// ログインのCookieを送信するため、Cookieを有効化する
+		jar, _ := cookiejar.New(nil)
+		service := &GitBucket{Config: replayConfig(), HttpClient: &http.Client{Transport: transport, Jar: jar}}
+		return service.ParsePullRequest(context.Background())
+	})
+}
@@ -0,0 +1,40 @@
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/git/request"
+)
+
+// プルリクエストにコメントを投稿する。idが空でなければそのコメントの本文を更新する。
+func (gitea *Gitea) PostComment(ctx context.Context, id string, body string) error {
+	// POST /repos/:owner/:repo/issues/:index/comments
+	// PATCH /repos/:owner/:repo/issues/comments/:id
+	method, path := "POST", "/issues/"+gitea.Config.Pull+"/comments"
+	if id != "" {
+		method, path = "PATCH", "/issues/comments/"+id
+	}
+	requestBody, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+	req, err := gitea.newRequest(ctx, method, path, bytes.NewReader(requestBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := gitea.HttpClient.Do(req)
+	if err != nil {
+		return request.Error(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		return errors.New("プルリクエストへコメントを投稿する権限がありません。アクセストークンの権限を見直してください。")
+	}
+	return checkStatus(resp.StatusCode)
+}
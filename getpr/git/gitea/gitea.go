@@ -0,0 +1,385 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/git/request"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/reviewee"
+)
+
+// Giteaの構造体
+type Gitea struct {
+	Config     *cfg.Config
+	HttpClient *http.Client
+	// 同時に送信するリクエストの数を制限するためのセマフォ
+	semaphore chan struct{}
+}
+
+// Giteaからプルリクエストのコメント情報を取得する。
+// プルリクエストの情報、通常のコメント、レビュー（と必要に応じてコミット）を並行して取得し、
+// その後にコメントのあるレビューごとのコメントを並行して取得する。
+func (gitea *Gitea) ParsePullRequest(ctx context.Context) (*review.PullRequest, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	gitea.semaphore = make(chan struct{}, gitea.Config.Concurrency)
+
+	var pullRequest PullRequest
+	var comments []Comment
+	var reviews []Review
+	var commits []Commit
+	fetches := []func() error{
+		func() error {
+			return gitea.getJson(ctx, "/pulls/"+gitea.Config.Pull, &pullRequest)
+		},
+		// 通常のコメントはページングせずに全件を返す
+		func() error {
+			return gitea.getJson(ctx, "/issues/"+gitea.Config.Pull+"/comments", &comments)
+		},
+		func() error {
+			return gitea.getPages(ctx, "/pulls/"+gitea.Config.Pull+"/reviews", func(respBody []byte) (int, error) {
+				var reviewsPerPage []Review
+				err := json.Unmarshal(respBody, &reviewsPerPage)
+				reviews = append(reviews, reviewsPerPage...)
+				return len(reviewsPerPage), err
+			})
+		},
+	}
+	if gitea.Config.IncludeCommitAuthors {
+		fetches = append(fetches, func() error {
+			return gitea.getPages(ctx, "/pulls/"+gitea.Config.Pull+"/commits", func(respBody []byte) (int, error) {
+				var commitsPerPage []Commit
+				err := json.Unmarshal(respBody, &commitsPerPage)
+				commits = append(commits, commitsPerPage...)
+				return len(commitsPerPage), err
+			})
+		})
+	}
+	if err := parallel(cancel, fetches); err != nil {
+		return nil, err
+	}
+
+	submitted := make([]Review, 0, len(reviews))
+	for _, r := range reviews {
+		// 下書きのレビューとレビューの依頼は、レビューとして投稿されていないため除く
+		if r.State != "PENDING" && r.State != "REQUEST_REVIEW" {
+			submitted = append(submitted, r)
+		}
+	}
+	reviewComments := make([][]ReviewComment, len(submitted))
+	fetches = make([]func() error, 0, len(submitted))
+	for i, r := range submitted {
+		if r.CommentsCount == 0 {
+			continue
+		}
+		i, id := i, strconv.Itoa(r.Id)
+		fetches = append(fetches, func() error {
+			return gitea.getJson(ctx, "/pulls/"+gitea.Config.Pull+"/reviews/"+id+"/comments", &reviewComments[i])
+		})
+	}
+	if err := parallel(cancel, fetches); err != nil {
+		return nil, err
+	}
+
+	reviewees := gitea.buildReviewees(&pullRequest, commits)
+	pr := &review.PullRequest{
+		Url:    pullRequest.HtmlUrl,
+		Author: review.NewParticipant(pullRequest.User.Login, pullRequest.User.FullName, reviewees),
+		DiffStats: review.DiffStats{
+			Additions: pullRequest.Additions,
+			Deletions: pullRequest.Deletions,
+		},
+		Threads: buildThreads(comments, submitted, reviewComments, reviewees),
+	}
+	return pr, nil
+}
+
+// 関数を並行して実行し、全て終わるまで待つ。
+// いずれかがエラーを返した場合はcancelで残りを中断し、最初に発生したエラーを返す。
+func parallel(cancel context.CancelFunc, fns []func() error) error {
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for _, fn := range fns {
+		wg.Add(1)
+		go func(fn func() error) {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				// 最初に発生したエラーを返し、それ以降の中断によるエラーは無視する
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(fn)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// プルリクエスト作成者と設定に応じたアサイン先・コミット作成者からレビュイーの集合を構築する。
+func (gitea *Gitea) buildReviewees(pullRequest *PullRequest, commits []Commit) *reviewee.Reviewees {
+	reviewees := reviewee.New(pullRequest.User.Login, gitea.Config.Reviewees)
+	if gitea.Config.IncludeAssignees {
+		for _, assignee := range pullRequest.Assignees {
+			reviewees.Add(assignee.Login)
+		}
+	}
+	// Giteaのユーザーと紐付かないコミットは、コミットに記録された作成者の名前をコメントの作成者の表示名と照合する
+	for _, commit := range commits {
+		if commit.Author != nil && commit.Author.Login != "" {
+			reviewees.Add(commit.Author.Login)
+		} else {
+			reviewees.AddAuthorName(commit.Commit.Author.Name)
+		}
+	}
+	return reviewees
+}
+
+// 通常のコメントとレビューの本文を1件のコメントからなるスレッドに、レビューコメントを会話ごとのスレッドに変換し、
+// 最初のコメントの作成日時の順に並べる。
+func buildThreads(comments []Comment, reviews []Review, reviewComments [][]ReviewComment, reviewees *reviewee.Reviewees) []review.Thread {
+	threads := make([]review.Thread, 0, len(comments)+len(reviews))
+	for _, comment := range comments {
+		threads = append(threads, review.Thread{
+			Id:  strconv.Itoa(comment.Id),
+			Url: comment.HtmlUrl,
+			Comments: []review.Comment{{
+				Id:        strconv.Itoa(comment.Id),
+				Url:       comment.HtmlUrl,
+				Author:    review.NewParticipant(comment.User.Login, comment.User.FullName, reviewees),
+				Body:      comment.Body,
+				CreatedAt: parseTimestamp(comment.CreatedAt),
+				UpdatedAt: parseTimestamp(comment.UpdatedAt),
+			}},
+		})
+	}
+	for _, r := range reviews {
+		// レビューのIDはコメントのIDと重複しうるため、接頭辞を付けて区別する
+		threads = append(threads, review.Thread{
+			Id:  "review-" + strconv.Itoa(r.Id),
+			Url: r.HtmlUrl,
+			Comments: []review.Comment{{
+				Id:        "review-" + strconv.Itoa(r.Id),
+				Url:       r.HtmlUrl,
+				Author:    review.NewParticipant(r.User.Login, r.User.FullName, reviewees),
+				Body:      r.Body,
+				CreatedAt: parseTimestamp(r.SubmittedAt),
+				UpdatedAt: parseTimestamp(r.UpdatedAt),
+			}},
+		})
+	}
+
+	// 返信は別のレビューのコメントとして投稿されるため、全てのレビューのコメントを同じファイルと行ごとにまとめる
+	all := make([]ReviewComment, 0)
+	for _, rc := range reviewComments {
+		all = append(all, rc...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Id < all[j].Id
+	})
+	conversations := make(map[string]int)
+	for _, comment := range all {
+		key := comment.Path + "\x00" + strconv.Itoa(comment.Position) + "\x00" + strconv.Itoa(comment.OriginalPosition)
+		i, ok := conversations[key]
+		if !ok {
+			i = len(threads)
+			conversations[key] = i
+			threads = append(threads, review.Thread{
+				Id:                strconv.Itoa(comment.Id),
+				Url:               comment.HtmlUrl,
+				Threaded:          true,
+				HasResolvedStatus: true,
+			})
+		}
+		thread := &threads[i]
+		// 会話を解決すると、解決したユーザーが会話のコメントに記録される
+		if comment.Resolver != nil {
+			thread.Resolved = true
+		}
+		thread.Comments = append(thread.Comments, review.Comment{
+			Id:        strconv.Itoa(comment.Id),
+			Url:       comment.HtmlUrl,
+			Author:    review.NewParticipant(comment.User.Login, comment.User.FullName, reviewees),
+			Body:      comment.Body,
+			CreatedAt: parseTimestamp(comment.CreatedAt),
+			UpdatedAt: parseTimestamp(comment.UpdatedAt),
+		})
+	}
+
+	sort.SliceStable(threads, func(i, j int) bool {
+		return threads[i].CreatedAt().Before(threads[j].CreatedAt())
+	})
+	return threads
+}
+
+// Giteaのタイムスタンプをパースする。パースできない場合はゼロ値を返す。
+func parseTimestamp(timestamp string) time.Time {
+	t, _ := time.Parse(time.RFC3339, timestamp)
+	return t
+}
+
+// 一覧を全ページ取得し、ページ順にparseへ渡す。parseはページの件数を返す。
+// X-Total-Countの件数を取得し終えるか、空のページを受け取るまで順番に取得する。
+// Giteaは1ページの件数をサーバーの設定で制限するため、受け取った件数だけでは最後のページを判断しない。
+func (gitea *Gitea) getPages(ctx context.Context, path string, parse func(respBody []byte) (int, error)) error {
+	fetched := 0
+	for page := 1; ; page++ {
+		respBody, header, err := gitea.get(ctx, path+"?limit="+strconv.Itoa(gitea.Config.PageSize)+"&page="+strconv.Itoa(page))
+		if err != nil {
+			return err
+		}
+		count, err := parse(respBody)
+		if err != nil {
+			return errors.New("エラーが発生しました。")
+		}
+		fetched += count
+		total, err := strconv.Atoi(header.Get("X-Total-Count"))
+		if count == 0 || err == nil && fetched >= total {
+			return nil
+		}
+	}
+}
+
+// リポジトリのURLにpathを付けたURLへGETリクエストを送信し、レスポンスをvへデコードする。
+func (gitea *Gitea) getJson(ctx context.Context, path string, v interface{}) error {
+	respBody, _, err := gitea.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(respBody, v); err != nil {
+		return errors.New("エラーが発生しました。")
+	}
+	return nil
+}
+
+// リポジトリのURLにpathを付けたURLへGETリクエストを送信し、レスポンスボディとヘッダーを返す。
+// 同時に送信するリクエストの数はconcurrencyまでに制限する。
+func (gitea *Gitea) get(ctx context.Context, path string) ([]byte, http.Header, error) {
+	select {
+	case gitea.semaphore <- struct{}{}:
+		defer func() { <-gitea.semaphore }()
+	case <-ctx.Done():
+		return nil, nil, request.Error(ctx, ctx.Err())
+	}
+
+	req, err := gitea.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := gitea.HttpClient.Do(req)
+	if err != nil {
+		return nil, nil, request.Error(ctx, err)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp.StatusCode); err != nil {
+		return nil, nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, request.Error(ctx, err)
+	}
+	return respBody, resp.Header, nil
+}
+
+// リポジトリのURLにpathを付けたURLへのリクエストを、アクセストークンを付けて構築する。
+func (gitea *Gitea) newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, method,
+		gitea.Config.Endpoint+"/repos/"+gitea.Config.Org+"/"+gitea.Config.Repo+path,
+		body,
+	)
+	if err != nil {
+		return nil, errors.New("エラーが発生しました。")
+	}
+	req.Header.Add("Authorization", "token "+gitea.Config.AccessToken)
+	return req, nil
+}
+
+// ステータスコードを利用者向けのエラーメッセージに変換する。成功の場合はnilを返す。
+func checkStatus(statusCode int) error {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return errors.New("認証に失敗しました。アクセストークンの設定を見直してください。")
+	case statusCode == http.StatusNotFound:
+		return errors.New("プルリクエストが見つかりません。オーガニゼーションやリポジトリ、プルリクエストIDの設定を確認してください。")
+	case !(200 <= statusCode && statusCode <= 299):
+		return errors.New("エラーが発生しました。")
+	}
+	return nil
+}
+
+// APIで取得するユーザーの構造体
+type User struct {
+	Login    string `json:"login"`
+	FullName string `json:"full_name"`
+}
+
+// APIで取得するプルリクエストの構造体
+type PullRequest struct {
+	HtmlUrl   string `json:"html_url"`
+	User      User   `json:"user"`
+	Assignees []User `json:"assignees"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// APIで取得する通常のコメントの構造体
+type Comment struct {
+	Id        int    `json:"id"`
+	HtmlUrl   string `json:"html_url"`
+	User      User   `json:"user"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// APIで取得するレビューの構造体
+type Review struct {
+	Id            int    `json:"id"`
+	HtmlUrl       string `json:"html_url"`
+	User          User   `json:"user"`
+	Body          string `json:"body"`
+	State         string `json:"state"`
+	CommentsCount int    `json:"comments_count"`
+	SubmittedAt   string `json:"submitted_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// APIで取得するレビューコメントの構造体
+type ReviewComment struct {
+	Id      int    `json:"id"`
+	HtmlUrl string `json:"html_url"`
+	User    User   `json:"user"`
+	Body    string `json:"body"`
+	Path    string `json:"path"`
+	// 変更後の行番号。変更前の行へのコメントでは0。
+	Position int `json:"position"`
+	// 変更前の行番号。変更後の行へのコメントでは0。
+	OriginalPosition int `json:"original_position"`
+	// 会話を解決したユーザー。未解決の場合はnil。
+	Resolver  *User  `json:"resolver"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// APIで取得するコミットの構造体
+type Commit struct {
+	// コミットの作成者と紐付くGiteaのユーザー。紐付かない場合はnil。
+	Author *User `json:"author"`
+	Commit struct {
+		Author struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"commit"`
+}
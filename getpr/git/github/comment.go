@@ -0,0 +1,61 @@
+package github
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// プルリクエストのIDを取得するためのGraphQLクエリー
+//
+//go:embed "pull_request_id.gql"
+var pullRequestIdQuery string
+
+// プルリクエストにコメントを投稿するためのGraphQLミューテーション
+//
+//go:embed "add_comment.gql"
+var addCommentMutation string
+
+// コメントの本文を更新するためのGraphQLミューテーション
+//
+//go:embed "update_comment.gql"
+var updateCommentMutation string
+
+// プルリクエストにコメントを投稿する。idが空でなければそのコメントの本文を更新する。
+func (gitHub *GitHub) PostComment(ctx context.Context, id string, body string) error {
+	if id != "" {
+		return gitHub.mutate(ctx, updateCommentMutation, map[string]interface{}{"id": id, "body": body})
+	}
+	pull, err := strconv.Atoi(gitHub.Config.Pull)
+	if err != nil {
+		return errors.New("プルリクエストのIDには数値を設定してください。")
+	}
+	var root PullRequestIdRoot
+	if err := gitHub.postJson(ctx, pullRequestIdQuery, map[string]interface{}{"org": gitHub.Config.Org, "repo": gitHub.Config.Repo, "pull": pull}, &root); err != nil {
+		return err
+	}
+	if err := checkErrors(root.Errors); err != nil {
+		return err
+	}
+	return gitHub.mutate(ctx, addCommentMutation, map[string]interface{}{"subjectId": root.Data.Repository.PullRequest.Id, "body": body})
+}
+
+func (gitHub *GitHub) mutate(ctx context.Context, mutation string, variables map[string]interface{}) error {
+	var root MutationRoot
+	if err := gitHub.postJson(ctx, mutation, variables, &root); err != nil {
+		return err
+	}
+	return checkErrors(root.Errors)
+}
+
+// クエリーと変数をJSONにしてGraphQL APIへ送信する。
+// コメント本文は任意の文字列を含むため、テンプレートを使わずにエンコードする。
+func (gitHub *GitHub) postJson(ctx context.Context, query string, variables map[string]interface{}, root interface{}) error {
+	requestBody, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	return gitHub.post(ctx, string(requestBody), root)
+}
@@ -1,348 +1,427 @@
-package github
-
-import (
-	"encoding/json"
-	"errors"
-	"io"
-	"net/http"
-	"sort"
-	"strings"
-
-	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
-	"github.com/Fintan-contents/review-support-tool/getpr/csv"
-	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
-	"github.com/Fintan-contents/review-support-tool/getpr/text"
-)
-
-type GitHub struct {
-	Config     *cfg.Config
-	HttpClient *http.Client
-}
-
-func (gitHub *GitHub) ParsePullRequest() (*csv.CsvData, error) {
-	comments, additions, deletions, err := gitHub.getComments()
-	if err != nil {
-		return nil, err
-	}
-
-	csvHeader := csv.CsvHeader{
-		Additions:  additions,
-		Deletions:  deletions,
-		ReviewTime: gitHub.extractReviewTime(comments),
-	}
-
-	extractedReviewComments := gitHub.extractReviewComments(comments)
-	builtReviewComments, err := gitHub.buildReviewComments()
-	if err != nil {
-		return nil, err
-	}
-
-	csvReviewCommentWithTimestamps := make([]CsvReviewCommentWithTimestamp, 0, len(extractedReviewComments)+len(builtReviewComments))
-	csvReviewCommentWithTimestamps = append(csvReviewCommentWithTimestamps, extractedReviewComments...)
-	csvReviewCommentWithTimestamps = append(csvReviewCommentWithTimestamps, builtReviewComments...)
-
-	sort.Slice(csvReviewCommentWithTimestamps, func(i, j int) bool {
-		return csvReviewCommentWithTimestamps[i].timestamp < csvReviewCommentWithTimestamps[j].timestamp
-	})
-
-	csvReviewComments := make([]csv.CsvReviewComment, 0, len(csvReviewCommentWithTimestamps))
-	for _, c := range csvReviewCommentWithTimestamps {
-		csvReviewComments = append(csvReviewComments, c.CsvReviewComment)
-	}
-
-	csvData := &csv.CsvData{
-		CsvHeader:         csvHeader,
-		CsvReviewComments: csvReviewComments,
-	}
-
-	return csvData, nil
-}
-
-// レビュー時刻を抽出する。
-func (gitHub *GitHub) extractReviewTime(comments []Comment) rvtime.ReviewTime {
-	reviewTimeComment, extracted := gitHub.extractReviewTimeComment(comments)
-	if extracted {
-		return rvtime.ParseReviewTime(reviewTimeComment, gitHub.Config.ReviewTimes)
-	}
-	return rvtime.ReviewTime{}
-}
-
-const githubGraphQLEndpoint = "https://api.github.com/graphql"
-
-// コメントと追加行数・削除行数を取得する。
-func (gitHub *GitHub) getComments() ([]Comment, int, int, error) {
-	comments := make([]Comment, 0)
-	var commentsCursor, reviewsCursor string
-	var additions, deletions int
-	for {
-		requestBody := &strings.Builder{}
-		data := make(map[string]interface{})
-		data["Query"] = commentsQuery
-		data["Org"] = gitHub.Config.Org
-		data["Repo"] = gitHub.Config.Repo
-		data["Pull"] = gitHub.Config.Pull
-		data["Limit"] = gitHub.Config.PageSize
-		data["CommentsCursor"] = commentsCursor
-		data["CommentsCursorIsNil"] = (commentsCursor == "")
-		data["ReviewsCursor"] = reviewsCursor
-		data["ReviewsCursorIsNil"] = (reviewsCursor == "")
-		commentsTemplate.Execute(requestBody, data)
-
-		// エラーが発生するのは HTTPメソッドが不正な場合 と URLが不正な場合 なため、ここではエラーは発生しない
-		req, _ := http.NewRequest("POST", githubGraphQLEndpoint, strings.NewReader(requestBody.String()))
-
-		req.Header.Add("Authorization", "Bearer "+gitHub.Config.AccessToken)
-
-		resp, err := gitHub.HttpClient.Do(req)
-		if err != nil {
-			return nil, 0, 0, errors.New("エラーが発生しました。")
-
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode == http.StatusUnauthorized {
-			return nil, 0, 0, errors.New("認証に失敗しました。アクセストークンの設定を見直してください。")
-		}
-		if !(200 <= resp.StatusCode && resp.StatusCode <= 299) {
-			bs, _ := io.ReadAll(resp.Body)
-			s := string(bs)
-			if strings.Contains(s, "API rate limit exceeded") {
-				return nil, 0, 0, errors.New("APIのレート制限によりプルリクエストの情報を取得できませんでした。しばらく待ってから再実行してください。")
-			}
-			return nil, 0, 0, errors.New("エラーが発生しました。")
-		}
-
-		var root ReviewTimeRoot
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&root)
-		if err != nil {
-			return nil, 0, 0, errors.New("エラーが発生しました。")
-		}
-		if len(root.Errors) > 0 {
-			e := root.Errors[0]
-			if e.Type == "NOT_FOUND" {
-				return nil, 0, 0, errors.New("プルリクエストが見つかりません。オーガニゼーションやリポジトリ、プルリクエストIDの設定を確認してください。")
-			}
-			return nil, 0, 0, errors.New("エラーが発生しました。")
-		}
-
-		pr := root.Data.Repository.PullRequest
-
-		additions = pr.Additions
-		deletions = pr.Deletions
-
-		for _, comment := range pr.Comments.Edges {
-			comments = append(comments, comment.Node)
-		}
-		for _, review := range pr.Reviews.Edges {
-			comments = append(comments, review.Node)
-		}
-
-		// 次のページがあればカーソルを更新してループ
-		// 次のページがなければループを脱出
-		nextPage := false
-		if pr.Comments.PageInfo.HasNextPage {
-			commentsCursor = pr.Comments.PageInfo.EndCursor
-			nextPage = true
-		} else if pr.Comments.PageInfo.EndCursor != "" {
-			commentsCursor = pr.Comments.PageInfo.EndCursor
-		}
-		if pr.Reviews.PageInfo.HasNextPage {
-			reviewsCursor = pr.Reviews.PageInfo.EndCursor
-			nextPage = true
-		} else if pr.Reviews.PageInfo.EndCursor != "" {
-			reviewsCursor = pr.Reviews.PageInfo.EndCursor
-		}
-		if !nextPage {
-			break
-		}
-	}
-	return comments, additions, deletions, nil
-}
-
-// レビュー時刻が書かれたコメントを抽出する。
-func (gitHub *GitHub) extractReviewTimeComment(comments []Comment) (string, bool) {
-	reviewTimes := gitHub.Config.ReviewTimes
-	for _, comment := range comments {
-		input := comment.Body
-		if rvtime.IsCurrentReviewTimeComment(input, reviewTimes) {
-			return input, true
-		}
-	}
-	return "", false
-}
-
-type CsvReviewCommentWithTimestamp struct {
-	csv.CsvReviewComment
-	timestamp string
-}
-
-// 通常のコメントからレビュー指摘コメントを抽出する。
-func (gitHub *GitHub) extractReviewComments(comments []Comment) []CsvReviewCommentWithTimestamp {
-	csvReviewComments := make([]CsvReviewCommentWithTimestamp, 0)
-	for _, comment := range comments {
-		if len(comment.Body) > 0 && !rvtime.IsReviewTimeComment(comment.Body) {
-			reviewerComment, revieweeComment := text.SplitComment(comment.Body, gitHub.Config.Delimiter)
-			if len(reviewerComment) > 0 || len(revieweeComment) > 0 {
-				csvReviewComment := CsvReviewCommentWithTimestamp{
-					CsvReviewComment: csv.CsvReviewComment{
-						Url:             comment.Url,
-						ReviewerComment: reviewerComment,
-						Reviewer:        comment.Author.Login,
-						RevieweeComment: revieweeComment,
-						// 通常のコメントではレビュイーや解決状態は取得できない
-						HasResolvedStatus: false,
-					},
-					timestamp: comment.CreatedAt,
-				}
-				csvReviewComments = append(csvReviewComments, csvReviewComment)
-			}
-		}
-	}
-	return csvReviewComments
-}
-
-// レビュー指摘コメントを構築する。
-func (gitHub *GitHub) buildReviewComments() ([]CsvReviewCommentWithTimestamp, error) {
-	csvReviewComments := make([]CsvReviewCommentWithTimestamp, 0)
-	var reviewThreadsCursor string
-	postscriptPrefix := text.Crlf + gitHub.Config.PostScriptPrefix
-
-	for {
-		root, err := gitHub.getReviewComments(gitHub.Config.PageSize, reviewThreadsCursor, gitHub.Config.PageSize, "")
-		if err != nil {
-			return nil, err
-		}
-
-		author := root.Data.Repository.PullRequest.Author.Login
-
-		pr := root.Data.Repository.PullRequest
-
-		for i, reviewThread := range pr.ReviewThreads.Edges {
-
-			csvReviewComment := CsvReviewCommentWithTimestamp{
-				CsvReviewComment: csv.CsvReviewComment{
-					Reviewee:          author,
-					Resolved:          reviewThread.Node.IsResolved,
-					HasResolvedStatus: true,
-				},
-			}
-
-			if len(reviewThread.Node.Comments.Edges) > 0 {
-				node := reviewThread.Node.Comments.Edges[0].Node
-				csvReviewComment.Url = node.Url
-				csvReviewComment.timestamp = node.CreatedAt
-			}
-
-			revieweeComment := make([]string, 0)
-			reviewerComment := make([]string, 0)
-
-			for _, comment := range reviewThread.Node.Comments.Edges {
-				body := comment.Node.Body
-				if comment.Node.Author.Login == author {
-					revieweeComment = append(revieweeComment, body)
-				} else {
-					reviewerComment = append(reviewerComment, body)
-					if csvReviewComment.Reviewer == "" {
-						csvReviewComment.Reviewer = comment.Node.Author.Login
-					}
-				}
-			}
-
-			// 更にコメントがあれば読み込む。
-			// スレッド単位で処理するため、1つ前のスレッドのカーソル以降の1件（つまり対象のスレッド）を指定している。
-			var cursor string
-			if i == 0 {
-				cursor = reviewThreadsCursor
-			} else {
-				cursor = pr.ReviewThreads.Edges[i-1].Cursor
-			}
-			for reviewThread.Node.Comments.PageInfo.HasNextPage {
-				reviewCommentsRoot, err := gitHub.getReviewComments(1, cursor, gitHub.Config.PageSize, reviewThread.Node.Comments.PageInfo.EndCursor)
-				if err != nil {
-					return nil, err
-				}
-
-				for _, nextReviewThread := range reviewCommentsRoot.Data.Repository.PullRequest.ReviewThreads.Edges {
-					if nextReviewThread.Node.Id == reviewThread.Node.Id {
-						reviewThread = nextReviewThread
-						for _, comment := range reviewThread.Node.Comments.Edges {
-							body := comment.Node.Body
-							if comment.Node.Author.Login == author {
-								revieweeComment = append(revieweeComment, body)
-							} else {
-								reviewerComment = append(reviewerComment, body)
-							}
-						}
-						break
-					}
-				}
-			}
-
-			csvReviewComment.ReviewerComment = strings.Join(reviewerComment, postscriptPrefix)
-			csvReviewComment.RevieweeComment = strings.Join(revieweeComment, postscriptPrefix)
-			csvReviewComments = append(csvReviewComments, csvReviewComment)
-		}
-
-		if pr.ReviewThreads.PageInfo.HasNextPage {
-			reviewThreadsCursor = pr.ReviewThreads.PageInfo.EndCursor
-		} else {
-			break
-		}
-	}
-
-	return csvReviewComments, nil
-}
-
-// レビューコメントを取得する。
-func (gitHub *GitHub) getReviewComments(reviewThreadsLimit int, reviewThreadsCursor string, commentsLimit int, commentsCursor string) (ReviewCommentsRoot, error) {
-	requestBody := &strings.Builder{}
-	data := make(map[string]interface{})
-	data["Query"] = threadsQuery
-	data["Org"] = gitHub.Config.Org
-	data["Repo"] = gitHub.Config.Repo
-	data["Pull"] = gitHub.Config.Pull
-	data["ReviewThreadsLimit"] = reviewThreadsLimit
-	data["ReviewThreadsCursor"] = reviewThreadsCursor
-	data["ReviewThreadsCursorIsNil"] = (reviewThreadsCursor == "")
-	data["CommentsLimit"] = commentsLimit
-	data["CommentsCursor"] = commentsCursor
-	data["CommentsCursorIsNil"] = (commentsCursor == "")
-	threadsTemplate.Execute(requestBody, data)
-
-	// エラーが発生するのはHTTPメソッドが不正な場合とURLが不正な場合なため、ここではエラーは発生しない
-	req, _ := http.NewRequest("POST", githubGraphQLEndpoint, strings.NewReader(requestBody.String()))
-
-	req.Header.Add("Authorization", "Bearer "+gitHub.Config.AccessToken)
-
-	resp, err := gitHub.HttpClient.Do(req)
-	if err != nil {
-		return ReviewCommentsRoot{}, errors.New("エラーが発生しました。")
-	}
-	defer resp.Body.Close()
-	if !(200 <= resp.StatusCode && resp.StatusCode <= 299) {
-		bs, _ := io.ReadAll(resp.Body)
-		s := string(bs)
-		if strings.Contains(s, "API rate limit exceeded") {
-			return ReviewCommentsRoot{}, errors.New("APIのレート制限によりプルリクエストの情報を取得できませんでした。しばらく待ってから再実行してください。")
-		}
-		return ReviewCommentsRoot{}, errors.New("エラーが発生しました。")
-	}
-
-	var root ReviewCommentsRoot
-
-	decoder := json.NewDecoder(resp.Body)
-	err = decoder.Decode(&root)
-	if err != nil {
-		return ReviewCommentsRoot{}, errors.New("エラーが発生しました。")
-	}
-	if len(root.Errors) > 0 {
-		e := root.Errors[0]
-		if e.Type == "NOT_FOUND" {
-			return ReviewCommentsRoot{}, errors.New("プルリクエストが見つかりません。オーガニゼーションやリポジトリ、プルリクエストIDの設定を確認してください。")
-		}
-		return ReviewCommentsRoot{}, errors.New("エラーが発生しました。")
-	}
-
-	return root, nil
-}
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/git/request"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/reviewee"
+)
+
+type GitHub struct {
+	Config     *cfg.Config
+	HttpClient *http.Client
+}
+
+func (gitHub *GitHub) ParsePullRequest(ctx context.Context) (*review.PullRequest, error) {
+	comments, pr, author, err := gitHub.getComments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reviewees, err := gitHub.getReviewees(ctx, author.Login)
+	if err != nil {
+		return nil, err
+	}
+	pr.Author = review.NewParticipant(author.Login, author.Name, reviewees)
+
+	threads := make([]review.Thread, 0, len(comments))
+	for _, comment := range comments {
+		// 通常のコメントは1件のコメントからなるスレッドとして扱う
+		threads = append(threads, review.Thread{
+			Id:       comment.Id,
+			Url:      comment.Url,
+			Comments: []review.Comment{newComment(comment, reviewees)},
+		})
+	}
+	reviewThreads, err := gitHub.getThreads(ctx, reviewees)
+	if err != nil {
+		return nil, err
+	}
+	threads = append(threads, reviewThreads...)
+	sort.SliceStable(threads, func(i, j int) bool {
+		return threads[i].CreatedAt().Before(threads[j].CreatedAt())
+	})
+	pr.Threads = threads
+	return pr, nil
+}
+
+// APIで取得したコメントをレビュー情報のコメントに変換する。
+func newComment(comment Comment, reviewees *reviewee.Reviewees) review.Comment {
+	return review.Comment{
+		Id:        comment.Id,
+		Url:       comment.Url,
+		Author:    review.NewParticipant(comment.Author.Login, comment.Author.Name, reviewees),
+		Body:      comment.Body,
+		CreatedAt: parseTimestamp(comment.CreatedAt),
+		UpdatedAt: parseTimestamp(comment.UpdatedAt),
+	}
+}
+
+// GitHubのタイムスタンプをパースする。パースできない場合はゼロ値を返す。
+func parseTimestamp(timestamp string) time.Time {
+	t, _ := time.Parse(time.RFC3339, timestamp)
+	return t
+}
+
+// 通常のコメントとレビュー、スレッドと作成者を除いたプルリクエストの情報、プルリクエストの作成者を取得する。
+func (gitHub *GitHub) getComments(ctx context.Context) ([]Comment, *review.PullRequest, Author, error) {
+	comments := make([]Comment, 0)
+	var commentsCursor, reviewsCursor string
+	var pullRequest *review.PullRequest
+	var author Author
+	for {
+		requestBody := &strings.Builder{}
+		data := make(map[string]interface{})
+		data["Query"] = commentsQuery
+		data["Org"] = gitHub.Config.Org
+		data["Repo"] = gitHub.Config.Repo
+		data["Pull"] = gitHub.Config.Pull
+		data["Limit"] = gitHub.Config.PageSize
+		data["CommentsCursor"] = commentsCursor
+		data["CommentsCursorIsNil"] = (commentsCursor == "")
+		data["ReviewsCursor"] = reviewsCursor
+		data["ReviewsCursorIsNil"] = (reviewsCursor == "")
+		commentsTemplate.Execute(requestBody, data)
+
+		var root ReviewTimeRoot
+		if err := gitHub.post(ctx, requestBody.String(), &root); err != nil {
+			return nil, nil, Author{}, err
+		}
+		if err := checkErrors(root.Errors); err != nil {
+			return nil, nil, Author{}, err
+		}
+
+		pr := root.Data.Repository.PullRequest
+
+		if pullRequest == nil {
+			author = pr.Author
+			pullRequest = &review.PullRequest{
+				Url: pr.Url,
+				DiffStats: review.DiffStats{
+					Additions: pr.Additions,
+					Deletions: pr.Deletions,
+				},
+			}
+		}
+
+		for _, comment := range pr.Comments.Edges {
+			comments = append(comments, comment.Node)
+		}
+		for _, review := range pr.Reviews.Edges {
+			comments = append(comments, review.Node)
+		}
+
+		// 次のページがあればカーソルを更新してループ
+		// 次のページがなければループを脱出
+		nextPage := false
+		if pr.Comments.PageInfo.HasNextPage {
+			commentsCursor = pr.Comments.PageInfo.EndCursor
+			nextPage = true
+		} else if pr.Comments.PageInfo.EndCursor != "" {
+			commentsCursor = pr.Comments.PageInfo.EndCursor
+		}
+		if pr.Reviews.PageInfo.HasNextPage {
+			reviewsCursor = pr.Reviews.PageInfo.EndCursor
+			nextPage = true
+		} else if pr.Reviews.PageInfo.EndCursor != "" {
+			reviewsCursor = pr.Reviews.PageInfo.EndCursor
+		}
+		if !nextPage {
+			break
+		}
+	}
+	return comments, pullRequest, author, nil
+}
+
+// プルリクエスト作成者と設定に応じたアサイン先・コミット作成者からレビュイーの集合を構築する。
+// アサイン先とコミットは、設定で指定された場合だけ全ページを取得する。
+func (gitHub *GitHub) getReviewees(ctx context.Context, author string) (*reviewee.Reviewees, error) {
+	reviewees := reviewee.New(author, gitHub.Config.Reviewees)
+	if !gitHub.Config.IncludeAssignees && !gitHub.Config.IncludeCommitAuthors {
+		return reviewees, nil
+	}
+	var assigneesCursor, commitsCursor string
+	for {
+		requestBody := &strings.Builder{}
+		data := make(map[string]interface{})
+		data["Query"] = revieweesQuery
+		data["Org"] = gitHub.Config.Org
+		data["Repo"] = gitHub.Config.Repo
+		data["Pull"] = gitHub.Config.Pull
+		data["Limit"] = gitHub.Config.PageSize
+		data["AssigneesCursor"] = assigneesCursor
+		data["AssigneesCursorIsNil"] = (assigneesCursor == "")
+		data["CommitsCursor"] = commitsCursor
+		data["CommitsCursorIsNil"] = (commitsCursor == "")
+		revieweesTemplate.Execute(requestBody, data)
+
+		var root RevieweesRoot
+		if err := gitHub.post(ctx, requestBody.String(), &root); err != nil {
+			return nil, err
+		}
+		if err := checkErrors(root.Errors); err != nil {
+			return nil, err
+		}
+
+		pr := root.Data.Repository.PullRequest
+		if gitHub.Config.IncludeAssignees {
+			for _, assignee := range pr.Assignees.Nodes {
+				reviewees.Add(assignee.Login)
+			}
+		}
+		if gitHub.Config.IncludeCommitAuthors {
+			for _, commit := range pr.Commits.Nodes {
+				for _, commitAuthor := range commit.Commit.Authors.Nodes {
+					reviewees.Add(commitAuthor.User.Login)
+				}
+			}
+		}
+
+		// 取得し終えた一覧は最後のカーソルを指定し続け、空のページを受け取る
+		nextPage := false
+		if pr.Assignees.PageInfo.EndCursor != "" {
+			assigneesCursor = pr.Assignees.PageInfo.EndCursor
+		}
+		if pr.Assignees.PageInfo.HasNextPage {
+			nextPage = true
+		}
+		if pr.Commits.PageInfo.EndCursor != "" {
+			commitsCursor = pr.Commits.PageInfo.EndCursor
+		}
+		if pr.Commits.PageInfo.HasNextPage {
+			nextPage = true
+		}
+		if !nextPage {
+			break
+		}
+	}
+	return reviewees, nil
+}
+
+// スレッドを取得する。
+// 1ページに収まらないコメントは、全てのスレッドを取得した後にスレッドごとに並行して取得する。
+func (gitHub *GitHub) getThreads(ctx context.Context, reviewees *reviewee.Reviewees) ([]review.Thread, error) {
+	threads := make([]review.Thread, 0)
+	pendings := make([]pendingThread, 0)
+	var reviewThreadsCursor string
+
+	for {
+		root, err := gitHub.getReviewComments(ctx, gitHub.Config.PageSize, reviewThreadsCursor, gitHub.Config.PageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		pr := root.Data.Repository.PullRequest
+
+		for _, reviewThread := range pr.ReviewThreads.Edges {
+
+			thread := review.Thread{
+				Id:                reviewThread.Node.Id,
+				Threaded:          true,
+				Resolved:          reviewThread.Node.IsResolved,
+				HasResolvedStatus: true,
+			}
+
+			if len(reviewThread.Node.Comments.Edges) > 0 {
+				thread.Url = reviewThread.Node.Comments.Edges[0].Node.Url
+			}
+
+			for _, comment := range reviewThread.Node.Comments.Edges {
+				thread.Comments = append(thread.Comments, newComment(comment.Node, reviewees))
+			}
+
+			// 更にコメントがあれば後でまとめて読み込む
+			if reviewThread.Node.Comments.PageInfo.HasNextPage {
+				pendings = append(pendings, pendingThread{
+					index:  len(threads),
+					id:     reviewThread.Node.Id,
+					cursor: reviewThread.Node.Comments.PageInfo.EndCursor,
+				})
+			}
+
+			threads = append(threads, thread)
+		}
+
+		if pr.ReviewThreads.PageInfo.HasNextPage {
+			reviewThreadsCursor = pr.ReviewThreads.PageInfo.EndCursor
+		} else {
+			break
+		}
+	}
+
+	if err := gitHub.getRemainingComments(ctx, threads, pendings, reviewees); err != nil {
+		return nil, err
+	}
+	return threads, nil
+}
+
+// 1ページ目に収まらなかったコメントがあるスレッド
+type pendingThread struct {
+	// スレッド一覧でのインデックス
+	index int
+	// スレッドのID
+	id string
+	// 取得済みのコメントのカーソル
+	cursor string
+}
+
+// 残りのコメントをスレッドごとにconcurrencyの数まで並行して取得し、スレッドに追加する。
+// 取得する順番によらず、コメントはスレッドごとに投稿順で追加する。いずれかの取得に失敗した場合は残りの取得を中断する。
+func (gitHub *GitHub) getRemainingComments(ctx context.Context, threads []review.Thread, pendings []pendingThread, reviewees *reviewee.Reviewees) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]review.Comment, len(pendings))
+	jobs := make(chan int)
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for w := 0; w < gitHub.Config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				comments, err := gitHub.getThreadComments(ctx, pendings[i].id, pendings[i].cursor, reviewees)
+				if err != nil {
+					// 最初に発生したエラーを返し、それ以降の中断によるエラーは無視する
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				results[i] = comments
+			}
+		}()
+	}
+	for i := range pendings {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return request.Error(ctx, err)
+	}
+	for i, pending := range pendings {
+		threads[pending.index].Comments = append(threads[pending.index].Comments, results[i]...)
+	}
+	return nil
+}
+
+// スレッドのカーソル以降のコメントを全て取得する。
+func (gitHub *GitHub) getThreadComments(ctx context.Context, id string, cursor string, reviewees *reviewee.Reviewees) ([]review.Comment, error) {
+	comments := make([]review.Comment, 0)
+	for {
+		requestBody := &strings.Builder{}
+		data := make(map[string]interface{})
+		data["Query"] = threadCommentsQuery
+		data["Id"] = id
+		data["Limit"] = gitHub.Config.PageSize
+		data["Cursor"] = cursor
+		data["CursorIsNil"] = (cursor == "")
+		threadCommentsTemplate.Execute(requestBody, data)
+
+		var root ThreadCommentsRoot
+		if err := gitHub.post(ctx, requestBody.String(), &root); err != nil {
+			return nil, err
+		}
+		if err := checkErrors(root.Errors); err != nil {
+			return nil, err
+		}
+
+		threadComments := root.Data.Node.Comments
+		for _, comment := range threadComments.Edges {
+			comments = append(comments, newComment(comment.Node, reviewees))
+		}
+		if !threadComments.PageInfo.HasNextPage {
+			return comments, nil
+		}
+		cursor = threadComments.PageInfo.EndCursor
+	}
+}
+
+// スレッドを取得する。
+func (gitHub *GitHub) getReviewComments(ctx context.Context, reviewThreadsLimit int, reviewThreadsCursor string, commentsLimit int) (ReviewCommentsRoot, error) {
+	requestBody := &strings.Builder{}
+	data := make(map[string]interface{})
+	data["Query"] = threadsQuery
+	data["Org"] = gitHub.Config.Org
+	data["Repo"] = gitHub.Config.Repo
+	data["Pull"] = gitHub.Config.Pull
+	data["ReviewThreadsLimit"] = reviewThreadsLimit
+	data["ReviewThreadsCursor"] = reviewThreadsCursor
+	data["ReviewThreadsCursorIsNil"] = (reviewThreadsCursor == "")
+	data["CommentsLimit"] = commentsLimit
+	threadsTemplate.Execute(requestBody, data)
+
+	var root ReviewCommentsRoot
+	if err := gitHub.post(ctx, requestBody.String(), &root); err != nil {
+		return ReviewCommentsRoot{}, err
+	}
+	if err := checkErrors(root.Errors); err != nil {
+		return ReviewCommentsRoot{}, err
+	}
+	return root, nil
+}
+
+// GraphQL APIへリクエストボディを送信し、レスポンスをrootへデコードする。
+func (gitHub *GitHub) post(ctx context.Context, requestBody string, root interface{}) error {
+	// エラーが発生するのはHTTPメソッドが不正な場合とURLが不正な場合なため、ここではエラーは発生しない
+	req, _ := http.NewRequestWithContext(ctx, "POST", gitHub.Config.Endpoint, strings.NewReader(requestBody))
+
+	req.Header.Add("Authorization", "Bearer "+gitHub.Config.AccessToken)
+
+	resp, err := gitHub.HttpClient.Do(req)
+	if err != nil {
+		return request.Error(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New("認証に失敗しました。アクセストークンの設定を見直してください。")
+	}
+	if !(200 <= resp.StatusCode && resp.StatusCode <= 299) {
+		bs, _ := io.ReadAll(resp.Body)
+		s := string(bs)
+		if strings.Contains(s, "API rate limit exceeded") {
+			return errors.New("APIのレート制限によりプルリクエストの情報を取得できませんでした。しばらく待ってから再実行してください。")
+		}
+		return errors.New("エラーが発生しました。")
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	if err := decoder.Decode(root); err != nil {
+		return errors.New("エラーが発生しました。")
+	}
+	return nil
+}
+
+// GraphQL APIが返したエラーを利用者向けのエラーメッセージに変換する。エラーがなければnilを返す。
+func checkErrors(errs Errors) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	e := errs[0]
+	if e.Type == "NOT_FOUND" {
+		return errors.New("プルリクエストが見つかりません。オーガニゼーションやリポジトリ、プルリクエストIDの設定を確認してください。")
+	}
+	if e.Type == "RATE_LIMITED" {
+		return errors.New("APIのレート制限によりプルリクエストの情報を取得できませんでした。しばらく待ってから再実行してください。")
+	}
+	return errors.New("エラーが発生しました。")
+}
@@ -0,0 +1,205 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+)
+
+// GraphQL APIのうち、getprが使用するクエリーだけを実装した偽のサーバー。
+type fakeGraphQL struct {
+	pageSize int
+	// スレッドIDごとのコメント本文
+	threads map[string][]string
+	// スレッドIDの並び順
+	threadIds []string
+	// node(id:)で取得できないスレッドID
+	brokenThreadIds map[string]bool
+
+	mu          sync.Mutex
+	running     int
+	maxRunning  int
+	nodeQueries int
+}
+
+func (f *fakeGraphQL) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var response interface{}
+	switch {
+	case strings.HasPrefix(body.Query, "query Comments"):
+		response = f.comments()
+	case strings.HasPrefix(body.Query, "query Threads"):
+		cursor, _ := body.Variables["reviewThreadsCursor"].(string)
+		response = f.reviewThreads(cursor)
+	case strings.HasPrefix(body.Query, "query ThreadComments"):
+		f.mu.Lock()
+		f.running++
+		f.nodeQueries++
+		if f.running > f.maxRunning {
+			f.maxRunning = f.running
+		}
+		f.mu.Unlock()
+		// 並行して処理されるように少し待つ
+		time.Sleep(20 * time.Millisecond)
+		id, _ := body.Variables["id"].(string)
+		cursor, _ := body.Variables["cursor"].(string)
+		if f.brokenThreadIds[id] {
+			response = map[string]interface{}{"errors": []map[string]string{{"type": "NOT_FOUND"}}}
+		} else {
+			response = map[string]interface{}{"data": map[string]interface{}{"node": map[string]interface{}{"comments": f.page(id, cursor)}}}
+		}
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+	default:
+		http.Error(w, "unknown query", http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeGraphQL) comments() interface{} {
+	empty := map[string]interface{}{"edges": []interface{}{}, "pageInfo": map[string]interface{}{"hasNextPage": false}}
+	return map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"pullRequest": map[string]interface{}{
+		"url":       "https://github.com/org/repo/pull/1",
+		"additions": 3,
+		"deletions": 1,
+		"author":    map[string]string{"login": "author"},
+		"comments":  empty,
+		"reviews":   empty,
+	}}}}
+}
+
+// 1回目の取得ではpageSize件ずつスレッドを返す。カーソルはスレッドの位置。
+func (f *fakeGraphQL) reviewThreads(cursor string) interface{} {
+	start, _ := strconv.Atoi(cursor)
+	end := start + f.pageSize
+	if end > len(f.threadIds) {
+		end = len(f.threadIds)
+	}
+	edges := make([]interface{}, 0)
+	for _, id := range f.threadIds[start:end] {
+		edges = append(edges, map[string]interface{}{
+			"node": map[string]interface{}{"id": id, "isResolved": false, "comments": f.page(id, "")},
+		})
+	}
+	return map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"pullRequest": map[string]interface{}{
+		"author": map[string]string{"login": "author"},
+		"reviewThreads": map[string]interface{}{
+			"edges":    edges,
+			"pageInfo": map[string]interface{}{"hasNextPage": end < len(f.threadIds), "endCursor": strconv.Itoa(end)},
+		},
+	}}}}
+}
+
+// スレッドのコメントをカーソル以降pageSize件返す。カーソルはコメントの位置。
+func (f *fakeGraphQL) page(id string, cursor string) interface{} {
+	bodies := f.threads[id]
+	start, _ := strconv.Atoi(cursor)
+	end := start + f.pageSize
+	if end > len(bodies) {
+		end = len(bodies)
+	}
+	edges := make([]interface{}, 0)
+	for i, body := range bodies[start:end] {
+		edges = append(edges, map[string]interface{}{"node": map[string]interface{}{
+			"id":        fmt.Sprintf("%s-%d", id, start+i),
+			"url":       fmt.Sprintf("https://github.com/org/repo/pull/1#%s-%d", id, start+i),
+			"body":      body,
+			"author":    map[string]string{"login": "reviewer"},
+			"createdAt": time.Date(2024, 5, 1, 10, 0, start+i, 0, time.UTC).Format(time.RFC3339),
+		}})
+	}
+	return map[string]interface{}{
+		"edges":    edges,
+		"pageInfo": map[string]interface{}{"hasNextPage": end < len(bodies), "endCursor": strconv.Itoa(end)},
+	}
+}
+
+func newFakeGraphQL() *fakeGraphQL {
+	f := &fakeGraphQL{pageSize: 2, threads: map[string][]string{}, brokenThreadIds: map[string]bool{}}
+	for i, size := range []int{5, 1, 3, 7, 2} {
+		id := fmt.Sprintf("T%d", i)
+		f.threadIds = append(f.threadIds, id)
+		for j := 0; j < size; j++ {
+			f.threads[id] = append(f.threads[id], fmt.Sprintf("%s-%d", id, j))
+		}
+	}
+	return f
+}
+
+func newTestGitHub(serverUrl string, client *http.Client, concurrency int) *GitHub {
+	return &GitHub{
+		Config: &cfg.Config{
+			Endpoint:    serverUrl,
+			Org:         "org",
+			Repo:        "repo",
+			Pull:        "1",
+			PageSize:    2,
+			Concurrency: concurrency,
+		},
+		HttpClient: client,
+	}
+}
+
+func TestParsePullRequestRemainingComments(t *testing.T) {
+	fake := newFakeGraphQL()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	gitHub := newTestGitHub(server.URL, server.Client(), 2)
+	pr, err := gitHub.ParsePullRequest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pr.Threads) != len(fake.threadIds) {
+		t.Fatalf("スレッドの件数が期待通りではありません。期待値：%v 実際の値：%v", len(fake.threadIds), len(pr.Threads))
+	}
+	for i, thread := range pr.Threads {
+		expected := fake.threads[fake.threadIds[i]]
+		actual := make([]string, 0)
+		for _, comment := range thread.Comments {
+			actual = append(actual, comment.Body)
+		}
+		if strings.Join(actual, ",") != strings.Join(expected, ",") {
+			t.Errorf("スレッド%vのコメントが期待通りではありません。期待値：%v 実際の値：%v", thread.Id, expected, actual)
+		}
+	}
+	// 2件を超えるスレッドは T0（残り3件）、T2（残り1件）、T3（残り5件）で、それぞれ2件ずつ取得する
+	if fake.nodeQueries != 2+1+3 {
+		t.Errorf("node(id:)の呼び出し回数が期待通りではありません。実際の値：%v", fake.nodeQueries)
+	}
+	if fake.maxRunning > 2 {
+		t.Errorf("並行して送信したリクエストの数が上限を超えています。実際の値：%v", fake.maxRunning)
+	}
+}
+
+func TestParsePullRequestRemainingCommentsError(t *testing.T) {
+	fake := newFakeGraphQL()
+	fake.brokenThreadIds["T3"] = true
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	gitHub := newTestGitHub(server.URL, server.Client(), 3)
+	_, err := gitHub.ParsePullRequest(context.Background())
+	if err == nil || err.Error() != "プルリクエストが見つかりません。オーガニゼーションやリポジトリ、プルリクエストIDの設定を確認してください。" {
+		t.Errorf("取得に失敗したスレッドのエラーを返す想定です。実際の値：%v", err)
+	}
+}
@@ -50,8 +50,7 @@ var threadsRequestBodyTemplate = `{
 		"pull": {{.Pull}},
 		"reviewThreadsLimit": {{.ReviewThreadsLimit}},
 		"commentsLimit": {{.CommentsLimit}},
-		"reviewThreadsCursor": {{if .ReviewThreadsCursorIsNil}}null{{else}}"{{.ReviewThreadsCursor}}"{{end}},
-		"commentsCursor": {{if .CommentsCursorIsNil}}null{{else}}"{{.CommentsCursor}}"{{end}}
+		"reviewThreadsCursor": {{if .ReviewThreadsCursorIsNil}}null{{else}}"{{.ReviewThreadsCursor}}"{{end}}
 	}
 }`
 
@@ -60,3 +59,54 @@ func init() {
 	threadsQuery = strings.ReplaceAll(threadsQuery, "\t", " ")
 	threadsTemplate = template.Must(template.New("example").Parse(threadsRequestBodyTemplate))
 }
+
+// スレッドの残りのコメントを取得するためのHTTPリクエストボディのテンプレート。
+var threadCommentsTemplate *template.Template
+
+// スレッドの残りのコメントを取得するためのGraphQLクエリー
+//
+//go:embed "thread_comments.gql"
+var threadCommentsQuery string
+
+// スレッドの残りのコメントを取得するためのHTTPリクエストボディのテンプレート文字列。
+var threadCommentsRequestBodyTemplate = `{
+	"query": "{{.Query}}",
+	"variables": {
+		"id": "{{.Id}}",
+		"limit": {{.Limit}},
+		"cursor": {{if .CursorIsNil}}null{{else}}"{{.Cursor}}"{{end}}
+	}
+}`
+
+func init() {
+	threadCommentsQuery = strings.ReplaceAll(threadCommentsQuery, "\n", "\\n")
+	threadCommentsQuery = strings.ReplaceAll(threadCommentsQuery, "\t", " ")
+	threadCommentsTemplate = template.Must(template.New("example").Parse(threadCommentsRequestBodyTemplate))
+}
+
+// レビュイーの取得元候補を取得するためのHTTPリクエストボディのテンプレート。
+var revieweesTemplate *template.Template
+
+// レビュイーの取得元候補を取得するためのGraphQLクエリー
+//
+//go:embed "reviewees.gql"
+var revieweesQuery string
+
+// レビュイーの取得元候補を取得するためのHTTPリクエストボディのテンプレート文字列。
+var revieweesRequestBodyTemplate = `{
+	"query": "{{.Query}}",
+	"variables": {
+		"org": "{{.Org}}",
+		"repo": "{{.Repo}}",
+		"pull": {{.Pull}},
+		"limit": {{.Limit}},
+		"assigneesCursor": {{if .AssigneesCursorIsNil}}null{{else}}"{{.AssigneesCursor}}"{{end}},
+		"commitsCursor": {{if .CommitsCursorIsNil}}null{{else}}"{{.CommitsCursor}}"{{end}}
+	}
+}`
+
+func init() {
+	revieweesQuery = strings.ReplaceAll(revieweesQuery, "\n", "\\n")
+	revieweesQuery = strings.ReplaceAll(revieweesQuery, "\t", " ")
+	revieweesTemplate = template.Must(template.New("example").Parse(revieweesRequestBodyTemplate))
+}
@@ -4,6 +4,7 @@ type ReviewTimeRoot struct {
 	Data struct {
 		Repository struct {
 			PullRequest struct {
+				Url       string `json:"url"`
 				Additions int    `json:"additions"`
 				Deletions int    `json:"deletions"`
 				Title     string `json:"title"`
@@ -19,6 +20,25 @@ type ReviewTimeRoot struct {
 	Errors Errors `json:"errors"`
 }
 
+// レビュイーの取得元候補を取得するレスポンス
+type RevieweesRoot struct {
+	Data struct {
+		Repository struct {
+			PullRequest struct {
+				Assignees struct {
+					Nodes    []Author `json:"nodes"`
+					PageInfo PageInfo `json:"pageInfo"`
+				} `json:"assignees"`
+				Commits struct {
+					Nodes    []CommitNode `json:"nodes"`
+					PageInfo PageInfo     `json:"pageInfo"`
+				} `json:"commits"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	} `json:"data"`
+	Errors Errors `json:"errors"`
+}
+
 type ReviewCommentsRoot struct {
 	Data struct {
 		Repository struct {
@@ -41,6 +61,32 @@ type ReviewCommentsRoot struct {
 	Errors Errors `json:"errors"`
 }
 
+// プルリクエストのIDを取得するレスポンス
+type PullRequestIdRoot struct {
+	Data struct {
+		Repository struct {
+			PullRequest struct {
+				Id string `json:"id"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	} `json:"data"`
+	Errors Errors `json:"errors"`
+}
+
+// ミューテーションのレスポンス。エラーのみ使用する。
+type MutationRoot struct {
+	Errors Errors `json:"errors"`
+}
+
+type ThreadCommentsRoot struct {
+	Data struct {
+		Node struct {
+			Comments Comments `json:"comments"`
+		} `json:"node"`
+	} `json:"data"`
+	Errors Errors `json:"errors"`
+}
+
 type Comments struct {
 	Edges []struct {
 		Node   Comment `json:"node"`
@@ -50,15 +96,30 @@ type Comments struct {
 }
 
 type Comment struct {
+	Id  string `json:"id"`
 	Url string `json:"url"`
 	// Bodyが空文字列のものは無視する
 	Body      string `json:"body"`
 	Author    Author `json:"author"`
 	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type CommitNode struct {
+	Commit struct {
+		// Co-authored-byで指定された共同作成者を含むコミットの作成者
+		Authors struct {
+			Nodes []struct {
+				User Author `json:"user"`
+			} `json:"nodes"`
+		} `json:"authors"`
+	} `json:"commit"`
 }
 
 type Author struct {
 	Login string `json:"login"`
+	// ユーザーの表示名。Botなどユーザー以外の場合や、表示名を設定していない場合は空文字列。
+	Name string `json:"name"`
 }
 
 type PageInfo struct {
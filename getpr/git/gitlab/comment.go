@@ -0,0 +1,52 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/git/request"
+)
+
+// マージリクエストにnoteを投稿する。idが空でなければそのnoteの本文を更新する。
+func (gitLab *GitLab) PostComment(ctx context.Context, id string, body string) error {
+	// POST /projects/:id/merge_requests/:merge_request_iid/notes
+	// PUT /projects/:id/merge_requests/:merge_request_iid/notes/:note_id
+	method, path := "POST", "/notes"
+	if id != "" {
+		method, path = "PUT", "/notes/"+id
+	}
+	requestBody, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, method,
+		gitLab.Config.Endpoint+"/projects/"+gitLab.Config.Repo+"/merge_requests/"+gitLab.Config.Pull+path,
+		bytes.NewReader(requestBody),
+	)
+	if err != nil {
+		return errors.New("エラーが発生しました。")
+	}
+	req.Header.Add("PRIVATE-TOKEN", gitLab.Config.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := gitLab.Client.Do(req)
+	if err != nil {
+		return request.Error(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New("認証に失敗しました。アクセストークンの設定を見直してください。")
+	} else if resp.StatusCode == http.StatusForbidden {
+		return errors.New("マージリクエストへコメントを投稿する権限がありません。アクセストークンの権限を見直してください。")
+	} else if resp.StatusCode == http.StatusNotFound {
+		return errors.New("マージリクエストが見つかりません。リポジトリやマージリクエストIDの設定を確認してください。")
+	}
+	if !(200 <= resp.StatusCode && resp.StatusCode <= 299) {
+		return errors.New("エラーが発生しました。")
+	}
+	return nil
+}
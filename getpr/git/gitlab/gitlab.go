@@ -1,316 +1,389 @@
-package gitlab
-
-import (
-	"encoding/json"
-	"errors"
-	"io"
-	"net/http"
-	"strconv"
-	"strings"
-
-	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
-	"github.com/Fintan-contents/review-support-tool/getpr/csv"
-	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
-)
-
-// GitLabからマージリクエストのコメント情報を取得する
-func (gitLab *GitLab) ParsePullRequest() (*csv.CsvData, error) {
-
-	// マージリクエストの情報を取得する
-	mergeRequestInfo, err := gitLab.getMergeRequestInfo()
-	if err != nil {
-		return nil, err
-	}
-
-	// 指摘コメントの情報取得
-	gitlabDiscussions, err := gitLab.getGitlabDiscussions()
-	if err != nil {
-		return nil, err
-	}
-
-	additions, deletions, err := gitLab.getChanges()
-	if err != nil {
-		return nil, err
-	}
-
-	// レビュー指摘、レビュー時間のパース
-	csvReviewComments, csvHeader := gitLab.parseReviewCommentAndTime(&mergeRequestInfo, &gitlabDiscussions, additions, deletions)
-
-	csvData := &csv.CsvData{}
-	csvData.CsvHeader = csvHeader
-	csvData.CsvReviewComments = csvReviewComments
-	return csvData, nil
-}
-
-// GitLabからマージリクエストのコメント以外の情報を取得する
-func (gitLab *GitLab) getMergeRequestInfo() (MergeRequestInfo, error) {
-	//マージリクエストの情報を取得するurlは　GET /projects/:id/merge_requests/:merge_request_iid
-	var body io.Reader = nil
-	req, err := http.NewRequest(
-		"GET",
-		gitLab.Config.Endpoint+"/projects/"+gitLab.Config.Repo+"/merge_requests/"+gitLab.Config.Pull,
-		body,
-	)
-	if err != nil {
-		return MergeRequestInfo{}, errors.New("エラーが発生しました。")
-	}
-
-	req.Header.Add("PRIVATE-TOKEN", gitLab.Config.AccessToken)
-
-	resp, err := gitLab.Client.Do(req)
-	if err != nil {
-		return MergeRequestInfo{}, errors.New("エラーが発生しました。")
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusUnauthorized {
-		return MergeRequestInfo{}, errors.New("認証に失敗しました。アクセストークンの設定を見直してください。")
-	} else if resp.StatusCode == http.StatusNotFound {
-		return MergeRequestInfo{}, errors.New("マージリクエストが見つかりません。リポジトリやマージリクエストIDの設定を確認してください。")
-	}
-	if !(200 <= resp.StatusCode && resp.StatusCode <= 299) {
-		return MergeRequestInfo{}, errors.New("エラーが発生しました。")
-	}
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return MergeRequestInfo{}, errors.New("エラーが発生しました。")
-	}
-
-	var mergeRequestInfo MergeRequestInfo
-	err = json.Unmarshal(respBody, &mergeRequestInfo)
-	if err != nil {
-		return MergeRequestInfo{}, errors.New("エラーが発生しました。")
-	}
-
-	return mergeRequestInfo, nil
-}
-
-// 差分から変更行数を算出する。
-func (gitLab *GitLab) getChanges() (int, int, error) {
-	var additions, deletions int
-	for page := 1; ; page++ {
-		var body io.Reader = nil
-		req, err := http.NewRequest(
-			"GET", gitLab.Config.Endpoint+"/projects/"+gitLab.Config.Repo+"/merge_requests/"+gitLab.Config.Pull+"/changes?per_page="+strconv.Itoa(gitLab.Config.PageSize)+"&page="+strconv.Itoa(page),
-			body,
-		)
-		if err != nil {
-			return 0, 0, errors.New("エラーが発生しました。")
-		}
-		req.Header.Add("PRIVATE-TOKEN", gitLab.Config.AccessToken)
-
-		resp, err := gitLab.Client.Do(req)
-		if err != nil {
-			return 0, 0, errors.New("エラーが発生しました。")
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode == http.StatusNotFound {
-			return 0, 0, errors.New("マージリクエストが見つかりません。リポジトリやマージリクエストIDの設定を確認してください。")
-		}
-		if !(200 <= resp.StatusCode && resp.StatusCode <= 299) {
-			return 0, 0, errors.New("エラーが発生しました。")
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-
-		var root Changes
-		err = decoder.Decode(&root)
-		if err != nil {
-			return 0, 0, errors.New("エラーが発生しました。")
-		}
-		for _, change := range root.Changes {
-			additions += strings.Count(change.Diff, "\n+")
-			deletions += strings.Count(change.Diff, "\n-")
-		}
-
-		xNextPage := resp.Header["X-Next-Page"]
-		if len(xNextPage) == 0 || xNextPage[0] == "" {
-			break
-		}
-	}
-
-	return additions, deletions, nil
-}
-
-// GiaLabからdiscussionsを取得する
-func (gitLab *GitLab) getGitlabDiscussions() ([]GitlabDiscussion, error) {
-	discussions := make([]GitlabDiscussion, 0)
-	//discussionsを取得するエンドポイントは GET /projects/:id/merge_requests/:merge_request_iid/discussions
-	for page := 1; ; page++ {
-		var body io.Reader = nil
-		req, err := http.NewRequest(
-			"GET", gitLab.Config.Endpoint+"/projects/"+gitLab.Config.Repo+"/merge_requests/"+gitLab.Config.Pull+"/discussions?per_page="+strconv.Itoa(gitLab.Config.PageSize)+"&page="+strconv.Itoa(page),
-			body,
-		)
-		if err != nil {
-			return nil, errors.New("エラーが発生しました。")
-		}
-		req.Header.Add("PRIVATE-TOKEN", gitLab.Config.AccessToken)
-
-		resp, err := gitLab.Client.Do(req)
-		if err != nil {
-			return nil, errors.New("エラーが発生しました。")
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode == http.StatusNotFound {
-			return nil, errors.New("マージリクエストが見つかりません。リポジトリやマージリクエストIDの設定を確認してください。")
-		}
-		if !(200 <= resp.StatusCode && resp.StatusCode <= 299) {
-			return nil, errors.New("エラーが発生しました。")
-		}
-
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, errors.New("エラーが発生しました。")
-		}
-
-		var discussionsPerPage []GitlabDiscussion
-		err = json.Unmarshal(respBody, &discussionsPerPage)
-		if err != nil {
-			return nil, errors.New("エラーが発生しました。")
-		}
-		discussions = append(discussions, discussionsPerPage...)
-
-		xNextPage := resp.Header["X-Next-Page"]
-		if len(xNextPage) == 0 || xNextPage[0] == "" {
-			break
-		}
-	}
-
-	return discussions, nil
-}
-
-// レビューコメントをパースして、レビュー指摘と、レビュー日時を取得する
-func (gitLab *GitLab) parseReviewCommentAndTime(mergeRequestInfo *MergeRequestInfo, gitLabDisucussions *[]GitlabDiscussion, additions int, deletions int) ([]csv.CsvReviewComment, csv.CsvHeader) {
-
-	// 各情報の格納先
-	var csvReviewComments []csv.CsvReviewComment
-	var csvHeader csv.CsvHeader
-
-	csvHeader.Additions = additions
-	csvHeader.Deletions = deletions
-
-	for _, discussion := range *gitLabDisucussions {
-		notes := gitLab.filterNotes(discussion.Notes)
-		if len(notes) == 0 {
-			continue
-		}
-		if isReviewComment(notes) {
-			// 指摘一覧に記載する情報を取得
-			commentId := strconv.Itoa(notes[0].ID)
-			reviewer := notes[0].Author.Username
-			resolved := notes[len(notes)-1].Resolved
-			reveiwerComment, revieweeComment := gitLab.parseReviewComments(notes, mergeRequestInfo.Author.ID)
-			csvReviewComments = append(csvReviewComments, csv.CsvReviewComment{
-				Url:               mergeRequestInfo.WebUrl + "#note_" + commentId,
-				ReviewerComment:   reveiwerComment,
-				Reviewer:          reviewer,
-				RevieweeComment:   revieweeComment,
-				Reviewee:          mergeRequestInfo.Author.Username,
-				Resolved:          resolved,
-				HasResolvedStatus: true,
-			})
-		} else if isTarget, index := gitLab.isTargetReviewTimeComment(notes); isTarget {
-			// レビュー日時を取得する
-			csvHeader.ReviewTime = gitLab.parseReviewTime(notes, index)
-		}
-	}
-
-	return csvReviewComments, csvHeader
-}
-
-func (gitLab *GitLab) filterNotes(notes []Note) []Note {
-	filteredNotes := make([]Note, 0)
-	for _, note := range notes {
-		if !note.System {
-			filteredNotes = append(filteredNotes, note)
-		}
-	}
-	return filteredNotes
-}
-
-// レビュー指摘コメントをパースし取得する
-func (gitLab *GitLab) parseReviewComments(notes []Note, authorId int) (reviewerComment string, revieweeComment string) {
-	//指摘を加工する前に、配列に格納する。
-	reviewerComments := make([]string, 0)
-	revieweeComments := make([]string, 0)
-	for _, note := range notes {
-		if note.Author.ID == authorId {
-			revieweeComments = append(revieweeComments, note.Body)
-		} else {
-			reviewerComments = append(reviewerComments, note.Body)
-		}
-	}
-
-	// 指摘を加工する。（postscript-prefixをつけ、文字をつなげる。）
-	return strings.Join(reviewerComments, "\n"+gitLab.Config.PostScriptPrefix),
-		strings.Join(revieweeComments, "\n"+gitLab.Config.PostScriptPrefix)
-}
-
-// 取り出したいレビュー時間のコメントかどうかを判断する（bool）
-// 取り出したいものであればそのindexを返し、そうでない場合はデフォルトの0を返す（int）
-func (gitLab *GitLab) isTargetReviewTimeComment(notes []Note) (bool, int) {
-	// １つのスレッドをレビュー時間のスレッドとして、返信する形でレビュー時間を書いていく場合も想定し、このようにした。
-	for i, note := range notes {
-		if rvtime.IsCurrentReviewTimeComment(note.Body, gitLab.Config.ReviewTimes) {
-			return true, i
-		}
-	}
-	return false, 0
-}
-
-// レビュー指摘コメントかどうかを判断する
-func isReviewComment(notes []Note) bool {
-	// スレッドの中の１つ目のコメントがレビュー時間の情報どうかで判断
-	return !rvtime.IsReviewTimeComment(notes[0].Body) && !notes[0].System
-}
-
-// レビュー時間をパースし取得する
-func (gitLab *GitLab) parseReviewTime(notes []Note, targetIndex int) rvtime.ReviewTime {
-	return rvtime.ParseReviewTime(notes[targetIndex].Body, gitLab.Config.ReviewTimes)
-}
-
-// GitLabの構造体
-type GitLab struct {
-	Config *cfg.Config
-	Client *http.Client
-}
-
-// APIで取得するDiscussionの構造体
-type GitlabDiscussion struct {
-	Notes []Note
-}
-
-// APIで取得するDiscussionのnote項目の構造体
-type Note struct {
-	ID     int    `json:"id"`
-	Body   string `json:"body"`
-	Author struct {
-		ID       int    `json:"id"`
-		Username string `json:"username"`
-	} `json:"author"`
-	System     bool `json:"system"`
-	Resolved   bool `json:"resolved"`
-	ResolvedBy struct {
-		ID       int    `json:"id"`
-		Username string `json:"username"`
-	} `json:"resolved_by"`
-	ResolvedAt string `json:"resolved_at"`
-}
-
-// APIで取得するマージリクエスト（コメント以外の情報）の構造体
-type MergeRequestInfo struct {
-	Author struct {
-		ID       int    `json:"id"`
-		Username string `json:"username"`
-	} `json:"author"`
-	WebUrl string `json:"web_url"`
-}
-
-type Changes struct {
-	Changes []Change `json:"changes"`
-}
-
-type Change struct {
-	Diff string `json:"diff"`
-}
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/git/request"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/reviewee"
+)
+
+// GitLabからマージリクエストのコメント情報を取得する
+// マージリクエストの情報、discussions、差分（と必要に応じてコミット）は並行して取得する。
+func (gitLab *GitLab) ParsePullRequest(ctx context.Context) (*review.PullRequest, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	gitLab.semaphore = make(chan struct{}, gitLab.Config.Concurrency)
+
+	var mergeRequestInfo MergeRequestInfo
+	var gitlabDiscussions []GitlabDiscussion
+	var additions, deletions int
+	var commits []Commit
+	fetches := []func() error{
+		// マージリクエストの情報を取得する
+		func() (err error) {
+			mergeRequestInfo, err = gitLab.getMergeRequestInfo(ctx)
+			return err
+		},
+		// 指摘コメントの情報取得
+		func() (err error) {
+			gitlabDiscussions, err = gitLab.getGitlabDiscussions(ctx)
+			return err
+		},
+		func() (err error) {
+			additions, deletions, err = gitLab.getChanges(ctx)
+			return err
+		},
+	}
+	if gitLab.Config.IncludeCommitAuthors {
+		fetches = append(fetches, func() (err error) {
+			commits, err = gitLab.getCommits(ctx)
+			return err
+		})
+	}
+	if err := parallel(cancel, fetches); err != nil {
+		return nil, err
+	}
+
+	reviewees := gitLab.buildReviewees(&mergeRequestInfo, commits)
+
+	pr := &review.PullRequest{
+		Url:    mergeRequestInfo.WebUrl,
+		Author: review.NewParticipant(mergeRequestInfo.Author.Username, mergeRequestInfo.Author.Name, reviewees),
+		DiffStats: review.DiffStats{
+			Additions: additions,
+			Deletions: deletions,
+		},
+		Threads: gitLab.buildThreads(&mergeRequestInfo, gitlabDiscussions, reviewees),
+	}
+	return pr, nil
+}
+
+// 関数を並行して実行し、全て終わるまで待つ。
+// いずれかがエラーを返した場合はcancelで残りを中断し、最初に発生したエラーを返す。
+func parallel(cancel context.CancelFunc, fns []func() error) error {
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for _, fn := range fns {
+		wg.Add(1)
+		go func(fn func() error) {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				// 最初に発生したエラーを返し、それ以降の中断によるエラーは無視する
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(fn)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// GitLabからマージリクエストのコメント以外の情報を取得する
+func (gitLab *GitLab) getMergeRequestInfo(ctx context.Context) (MergeRequestInfo, error) {
+	//マージリクエストの情報を取得するurlは　GET /projects/:id/merge_requests/:merge_request_iid
+	respBody, _, err := gitLab.get(ctx, "")
+	if err != nil {
+		return MergeRequestInfo{}, err
+	}
+
+	var mergeRequestInfo MergeRequestInfo
+	err = json.Unmarshal(respBody, &mergeRequestInfo)
+	if err != nil {
+		return MergeRequestInfo{}, errors.New("エラーが発生しました。")
+	}
+
+	return mergeRequestInfo, nil
+}
+
+// 差分から変更行数を算出する。
+func (gitLab *GitLab) getChanges(ctx context.Context) (int, int, error) {
+	var additions, deletions int
+	err := gitLab.getPages(ctx, "/changes", func(respBody []byte) error {
+		var root Changes
+		if err := json.Unmarshal(respBody, &root); err != nil {
+			return err
+		}
+		for _, change := range root.Changes {
+			additions += strings.Count(change.Diff, "\n+")
+			deletions += strings.Count(change.Diff, "\n-")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return additions, deletions, nil
+}
+
+// マージリクエスト作成者と設定に応じたアサイン先・コミット作成者からレビュイーの集合を構築する。
+func (gitLab *GitLab) buildReviewees(mergeRequestInfo *MergeRequestInfo, commits []Commit) *reviewee.Reviewees {
+	reviewees := reviewee.New(mergeRequestInfo.Author.Username, gitLab.Config.Reviewees)
+	if gitLab.Config.IncludeAssignees {
+		for _, assignee := range mergeRequestInfo.Assignees {
+			reviewees.Add(assignee.Username)
+		}
+	}
+	// コミットにはユーザー名が含まれないため、コミット作成者の名前をnoteの作成者の表示名だけと照合する
+	for _, commit := range commits {
+		reviewees.AddAuthorName(commit.AuthorName)
+	}
+	return reviewees
+}
+
+// GitLabからマージリクエストに含まれるコミットを取得する
+func (gitLab *GitLab) getCommits(ctx context.Context) ([]Commit, error) {
+	commits := make([]Commit, 0)
+	//コミットを取得するエンドポイントは GET /projects/:id/merge_requests/:merge_request_iid/commits
+	err := gitLab.getPages(ctx, "/commits", func(respBody []byte) error {
+		var commitsPerPage []Commit
+		if err := json.Unmarshal(respBody, &commitsPerPage); err != nil {
+			return err
+		}
+		commits = append(commits, commitsPerPage...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// GiaLabからdiscussionsを取得する
+func (gitLab *GitLab) getGitlabDiscussions(ctx context.Context) ([]GitlabDiscussion, error) {
+	discussions := make([]GitlabDiscussion, 0)
+	//discussionsを取得するエンドポイントは GET /projects/:id/merge_requests/:merge_request_iid/discussions
+	err := gitLab.getPages(ctx, "/discussions", func(respBody []byte) error {
+		var discussionsPerPage []GitlabDiscussion
+		if err := json.Unmarshal(respBody, &discussionsPerPage); err != nil {
+			return err
+		}
+		discussions = append(discussions, discussionsPerPage...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return discussions, nil
+}
+
+// マージリクエスト配下のリソースを全ページ取得し、ページ順にparseへ渡す。
+//
+// 1ページ目のレスポンスにX-Total-Pagesがあれば、2ページ目以降は並行して取得する。
+// X-Total-Pagesがない場合（件数が多すぎてGitLabが返さない場合）は、X-Next-Pageに従って順番に取得する。
+func (gitLab *GitLab) getPages(ctx context.Context, resource string, parse func(respBody []byte) error) error {
+	pageUrl := func(page int) string {
+		return resource + "?per_page=" + strconv.Itoa(gitLab.Config.PageSize) + "&page=" + strconv.Itoa(page)
+	}
+	respBody, header, err := gitLab.get(ctx, pageUrl(1))
+	if err != nil {
+		return err
+	}
+	if err := parse(respBody); err != nil {
+		return errors.New("エラーが発生しました。")
+	}
+
+	if totalPages, err := strconv.Atoi(header.Get("X-Total-Pages")); err == nil {
+		if totalPages <= 1 {
+			return nil
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		pages := make([][]byte, totalPages+1)
+		fetches := make([]func() error, 0, totalPages-1)
+		for page := 2; page <= totalPages; page++ {
+			page := page
+			fetches = append(fetches, func() (err error) {
+				pages[page], _, err = gitLab.get(ctx, pageUrl(page))
+				return err
+			})
+		}
+		if err := parallel(cancel, fetches); err != nil {
+			return err
+		}
+		for _, respBody := range pages[2:] {
+			if err := parse(respBody); err != nil {
+				return errors.New("エラーが発生しました。")
+			}
+		}
+		return nil
+	}
+
+	for {
+		nextPage, err := strconv.Atoi(header.Get("X-Next-Page"))
+		if err != nil {
+			return nil
+		}
+		respBody, header, err = gitLab.get(ctx, pageUrl(nextPage))
+		if err != nil {
+			return err
+		}
+		if err := parse(respBody); err != nil {
+			return errors.New("エラーが発生しました。")
+		}
+	}
+}
+
+// マージリクエストのURLにpathを付けたURLへGETリクエストを送信し、レスポンスボディとヘッダーを返す。
+// 同時に送信するリクエストの数はconcurrencyまでに制限する。
+func (gitLab *GitLab) get(ctx context.Context, path string) ([]byte, http.Header, error) {
+	select {
+	case gitLab.semaphore <- struct{}{}:
+		defer func() { <-gitLab.semaphore }()
+	case <-ctx.Done():
+		return nil, nil, request.Error(ctx, ctx.Err())
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, "GET",
+		gitLab.Config.Endpoint+"/projects/"+gitLab.Config.Repo+"/merge_requests/"+gitLab.Config.Pull+path,
+		nil,
+	)
+	if err != nil {
+		return nil, nil, errors.New("エラーが発生しました。")
+	}
+
+	req.Header.Add("PRIVATE-TOKEN", gitLab.Config.AccessToken)
+
+	resp, err := gitLab.Client.Do(req)
+	if err != nil {
+		return nil, nil, request.Error(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, nil, errors.New("認証に失敗しました。アクセストークンの設定を見直してください。")
+	} else if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, errors.New("マージリクエストが見つかりません。リポジトリやマージリクエストIDの設定を確認してください。")
+	}
+	if !(200 <= resp.StatusCode && resp.StatusCode <= 299) {
+		return nil, nil, errors.New("エラーが発生しました。")
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, request.Error(ctx, err)
+	}
+	return respBody, resp.Header, nil
+}
+
+// discussionをスレッドに変換する。システムが投稿したnoteは除く。
+func (gitLab *GitLab) buildThreads(mergeRequestInfo *MergeRequestInfo, gitLabDiscussions []GitlabDiscussion, reviewees *reviewee.Reviewees) []review.Thread {
+	threads := make([]review.Thread, 0, len(gitLabDiscussions))
+	for _, discussion := range gitLabDiscussions {
+		notes := gitLab.filterNotes(discussion.Notes)
+		if len(notes) == 0 {
+			continue
+		}
+		thread := review.Thread{
+			Id:                discussion.ID,
+			Url:               mergeRequestInfo.WebUrl + "#note_" + strconv.Itoa(notes[0].ID),
+			Threaded:          true,
+			Resolved:          notes[len(notes)-1].Resolved,
+			HasResolvedStatus: true,
+		}
+		for _, note := range notes {
+			if resolvedAt := parseTimestamp(note.ResolvedAt); !resolvedAt.IsZero() && (thread.ResolvedAt == nil || resolvedAt.After(*thread.ResolvedAt)) {
+				thread.ResolvedAt = &resolvedAt
+			}
+			thread.Comments = append(thread.Comments, review.Comment{
+				Id:        strconv.Itoa(note.ID),
+				Url:       mergeRequestInfo.WebUrl + "#note_" + strconv.Itoa(note.ID),
+				Author:    review.NewParticipant(note.Author.Username, note.Author.Name, reviewees),
+				Body:      note.Body,
+				CreatedAt: parseTimestamp(note.CreatedAt),
+				UpdatedAt: parseTimestamp(note.UpdatedAt),
+			})
+		}
+		threads = append(threads, thread)
+	}
+	return threads
+}
+
+// GitLabのタイムスタンプをパースする。パースできない場合はゼロ値を返す。
+func parseTimestamp(timestamp string) time.Time {
+	t, _ := time.Parse(time.RFC3339, timestamp)
+	return t
+}
+
+func (gitLab *GitLab) filterNotes(notes []Note) []Note {
+	filteredNotes := make([]Note, 0)
+	for _, note := range notes {
+		if !note.System {
+			filteredNotes = append(filteredNotes, note)
+		}
+	}
+	return filteredNotes
+}
+
+// GitLabの構造体
+type GitLab struct {
+	Config *cfg.Config
+	Client *http.Client
+	// 同時に送信するリクエストの数を制限するためのセマフォ
+	semaphore chan struct{}
+}
+
+// APIで取得するDiscussionの構造体
+type GitlabDiscussion struct {
+	ID    string `json:"id"`
+	Notes []Note `json:"notes"`
+}
+
+// APIで取得するDiscussionのnote項目の構造体
+type Note struct {
+	ID     int    `json:"id"`
+	Body   string `json:"body"`
+	Author struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	} `json:"author"`
+	System     bool `json:"system"`
+	Resolved   bool `json:"resolved"`
+	ResolvedBy struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	} `json:"resolved_by"`
+	ResolvedAt string `json:"resolved_at"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// APIで取得するマージリクエスト（コメント以外の情報）の構造体
+type MergeRequestInfo struct {
+	Author struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	} `json:"author"`
+	Assignees []struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	} `json:"assignees"`
+	WebUrl string `json:"web_url"`
+}
+
+// APIで取得するコミットの構造体
+type Commit struct {
+	AuthorName string `json:"author_name"`
+}
+
+type Changes struct {
+	Changes []Change `json:"changes"`
+}
+
+type Change struct {
+	Diff string `json:"diff"`
+}
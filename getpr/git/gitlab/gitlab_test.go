@@ -0,0 +1,144 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+)
+
+// マージリクエストのREST APIのうち、getprが使用するものだけを実装した偽のサーバー。
+type fakeGitLab struct {
+	pageSize    int
+	discussions int
+	// X-Total-Pagesを返さない場合はtrue
+	omitTotalPages bool
+	// エラーを返すパス
+	brokenPath string
+
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.running++
+	if f.running > f.maxRunning {
+		f.maxRunning = f.running
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+	}()
+	// 並行して処理されるように少し待つ
+	time.Sleep(20 * time.Millisecond)
+
+	path := strings.TrimPrefix(r.URL.Path, "/projects/1/merge_requests/1")
+	if f.brokenPath != "" && path == f.brokenPath {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch path {
+	case "":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"web_url": "https://gitlab.com/org/repo/-/merge_requests/1",
+			"author":  map[string]string{"username": "author", "name": "Author"},
+		})
+	case "/changes":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"changes": []map[string]string{{"diff": "@@\n+a\n+b\n-c"}},
+		})
+	case "/discussions":
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		totalPages := (f.discussions + f.pageSize - 1) / f.pageSize
+		if !f.omitTotalPages {
+			w.Header().Set("X-Total-Pages", strconv.Itoa(totalPages))
+		}
+		if page < totalPages {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+		discussions := make([]interface{}, 0)
+		for i := (page - 1) * f.pageSize; i < page*f.pageSize && i < f.discussions; i++ {
+			discussions = append(discussions, map[string]interface{}{
+				"id": fmt.Sprintf("D%d", i),
+				"notes": []map[string]interface{}{{
+					"id":         i,
+					"body":       fmt.Sprintf("指摘%d", i),
+					"author":     map[string]string{"username": "reviewer"},
+					"created_at": time.Date(2024, 5, 1, 10, i, 0, 0, time.UTC).Format(time.RFC3339),
+				}},
+			})
+		}
+		json.NewEncoder(w).Encode(discussions)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestGitLab(serverUrl string, client *http.Client, concurrency int) *GitLab {
+	return &GitLab{
+		Config: &cfg.Config{
+			Endpoint:    serverUrl,
+			Repo:        "1",
+			Pull:        "1",
+			PageSize:    2,
+			Concurrency: concurrency,
+		},
+		Client: client,
+	}
+}
+
+func TestParsePullRequestPages(t *testing.T) {
+	for _, omitTotalPages := range []bool{false, true} {
+		t.Run(fmt.Sprintf("X-Total-Pagesなし=%v", omitTotalPages), func(t *testing.T) {
+			fake := &fakeGitLab{pageSize: 2, discussions: 9, omitTotalPages: omitTotalPages}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			gitLab := newTestGitLab(server.URL, server.Client(), 3)
+			pr, err := gitLab.ParsePullRequest(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(pr.Threads) != fake.discussions {
+				t.Fatalf("スレッドの件数が期待通りではありません。期待値：%v 実際の値：%v", fake.discussions, len(pr.Threads))
+			}
+			// ページを並行して取得してもページ順に並ぶ
+			for i, thread := range pr.Threads {
+				if thread.Id != fmt.Sprintf("D%d", i) {
+					t.Errorf("スレッドの並び順が期待通りではありません。位置：%v 実際の値：%v", i, thread.Id)
+				}
+			}
+			if pr.Url != "https://gitlab.com/org/repo/-/merge_requests/1" || pr.DiffStats.Additions != 2 || pr.DiffStats.Deletions != 1 {
+				t.Errorf("マージリクエストの情報が期待通りではありません。実際の値：%v", pr)
+			}
+			if fake.maxRunning > 3 {
+				t.Errorf("並行して送信したリクエストの数が上限を超えています。実際の値：%v", fake.maxRunning)
+			}
+		})
+	}
+}
+
+func TestParsePullRequestError(t *testing.T) {
+	fake := &fakeGitLab{pageSize: 2, discussions: 5, brokenPath: "/changes"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	gitLab := newTestGitLab(server.URL, server.Client(), 4)
+	_, err := gitLab.ParsePullRequest(context.Background())
+	if err == nil || err.Error() != "マージリクエストが見つかりません。リポジトリやマージリクエストIDの設定を確認してください。" {
+		t.Errorf("取得に失敗したリソースのエラーを返す想定です。実際の値：%v", err)
+	}
+}
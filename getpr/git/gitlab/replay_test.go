@@ -0,0 +1,36 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/cfg"
+	"github.com/Fintan-contents/review-support-tool/getpr/internal/replaytest"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+)
+
+// testdata/replayのやり取りを記録した際の設定。リクエストボディやURLが一致するよう、記録時と同じ値にする。
+func replayConfig() *cfg.Config {
+	return &cfg.Config{
+		Target:               "gitlab",
+		Endpoint:             "https://gitlab.example.com/api/v4",
+		AccessToken:          "token",
+		Org:                  "org",
+		Repo:                 "repo",
+		Pull:                 "1",
+		PageSize:             2,
+		Concurrency:          1,
+		Timezone:             "Asia/Tokyo",
+		IncludeAssignees:     true,
+		IncludeCommitAuthors: true,
+	}
+}
+
+// testdata/replayに記録したやり取りを再生して抽出したレビュー情報を、testdata/replay.jsonの期待値と比較する。
+func TestParsePullRequestReplay(t *testing.T) {
+	replaytest.Compare(t, func(transport http.RoundTripper) (*review.PullRequest, error) {
+		service := &GitLab{Config: replayConfig(), Client: &http.Client{Transport: transport}}
+		return service.ParsePullRequest(context.Background())
+	})
+}
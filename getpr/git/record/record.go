@@ -0,0 +1,227 @@
+// APIとのやり取りをディレクトリへ記録し、記録したやり取りを再生するためのパッケージ。
+// 記録したディレクトリは、不具合の報告やテストのフィクスチャとして利用できる。
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 再生時に、リクエストに対応するやり取りが記録されていない場合のエラー。
+var ErrNotRecorded = errors.New("リクエストに対応するやり取りが記録されていません。replayのディレクトリと設定を見直してください。")
+
+// 記録したやり取りを読み込めない場合のエラー。
+var ErrLoad = errors.New("記録したやり取りを読み込めません。replayのディレクトリを見直してください。")
+
+// 記録に失敗した場合のエラー。
+var ErrRecord = errors.New("やり取りの記録に失敗しました。recordの設定を見直してください。")
+
+// 伏せ字にした値
+const redacted = "REDACTED"
+
+// 記録から取り除くレスポンスヘッダー
+var secretResponseHeaders = []string{"Set-Cookie"}
+
+// 伏せ字にするクエリーパラメーターとフォームの項目
+var secretParams = []string{"private_token", "access_token", "password"}
+
+// 1回のHTTPリクエストとレスポンスのやり取り。
+// リクエストヘッダーはアクセストークンやCookieを含むため記録しない。
+type Exchange struct {
+	Method      string      `json:"method"`
+	Url         string      `json:"url"`
+	RequestBody string      `json:"requestBody,omitempty"`
+	StatusCode  int         `json:"statusCode"`
+	Header      http.Header `json:"header"`
+	Body        string      `json:"body"`
+}
+
+func (exchange *Exchange) key() string {
+	return exchange.Method + " " + exchange.Url + "\n" + exchange.RequestBody
+}
+
+// やり取りをディレクトリへ記録するRoundTripper。
+// やり取りは送信した順に連番を付けたJSONファイルへ、アクセストークンやパスワード、Cookieを伏せて書き出す。
+type Recorder struct {
+	// 記録先のディレクトリ
+	Dir string
+	// リクエストの送信に使用するRoundTripper。nilの場合はhttp.DefaultTransportを使用する。
+	Base http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+}
+
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	exchange, err := newExchange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	base := recorder.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	exchange.StatusCode = resp.StatusCode
+	exchange.Header = resp.Header.Clone()
+	for _, name := range secretResponseHeaders {
+		exchange.Header.Del(name)
+	}
+	exchange.Body = string(body)
+	if err := recorder.save(exchange); err != nil {
+		return nil, ErrRecord
+	}
+	return resp, nil
+}
+
+func (recorder *Recorder) save(exchange *Exchange) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if err := os.MkdirAll(recorder.Dir, 0700); err != nil {
+		return err
+	}
+	recorder.seq++
+	bs, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(recorder.Dir, fmt.Sprintf("%04d.json", recorder.seq)), bs, 0600)
+}
+
+// 記録したやり取りを再生するRoundTripper。リクエストは送信しない。
+//
+// メソッドとURL、リクエストボディが一致するやり取りのレスポンスを返す。
+// 一致するやり取りが複数ある場合は記録した順に返し、使い切った後は最後のやり取りを繰り返し返す。
+type Replayer struct {
+	// 記録したディレクトリ
+	Dir string
+
+	mu        sync.Mutex
+	exchanges map[string][]*Exchange
+	err       error
+}
+
+func (replayer *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	exchange, err := newExchange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+	if replayer.exchanges == nil && replayer.err == nil {
+		replayer.exchanges, replayer.err = load(replayer.Dir)
+	}
+	if replayer.err != nil {
+		return nil, replayer.err
+	}
+	candidates := replayer.exchanges[exchange.key()]
+	if len(candidates) == 0 {
+		return nil, ErrNotRecorded
+	}
+	recorded := candidates[0]
+	if len(candidates) > 1 {
+		replayer.exchanges[exchange.key()] = candidates[1:]
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// ディレクトリから記録したやり取りを記録した順に読み込む。
+func load(dir string) (map[string][]*Exchange, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(paths) == 0 {
+		return nil, ErrLoad
+	}
+	sort.Strings(paths)
+	exchanges := map[string][]*Exchange{}
+	for _, path := range paths {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return nil, ErrLoad
+		}
+		var exchange Exchange
+		if err := json.Unmarshal(bs, &exchange); err != nil {
+			return nil, ErrLoad
+		}
+		exchanges[exchange.key()] = append(exchanges[exchange.key()], &exchange)
+	}
+	return exchanges, nil
+}
+
+// リクエストからアクセストークンやパスワードを伏せたやり取りを作成する。
+func newExchange(req *http.Request) (*Exchange, error) {
+	u := *req.URL
+	u.User = nil
+	u.RawQuery = redactValues(u.RawQuery)
+	exchange := &Exchange{Method: req.Method, Url: u.String()}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		bs, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		exchange.RequestBody = string(bs)
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			exchange.RequestBody = redactValues(exchange.RequestBody)
+		}
+	}
+	return exchange, nil
+}
+
+// クエリー文字列やフォームのうち、秘密情報の値を伏せ字にする。
+func redactValues(query string) string {
+	if query == "" {
+		return query
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return query
+	}
+	changed := false
+	for _, name := range secretParams {
+		if _, ok := values[name]; ok {
+			values.Set(name, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return query
+	}
+	return values.Encode()
+}
@@ -0,0 +1,83 @@
+package record
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-secret"})
+		w.Header().Set("X-Next-Page", "2")
+		r.ParseForm()
+		io.WriteString(w, r.Method+" "+r.URL.Path+" "+r.Form.Get("userName")+" "+strings.Repeat("!", count))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	send := func(client *http.Client, secret string) []string {
+		bodies := make([]string, 0)
+		requests := []func() (*http.Response, error){
+			func() (*http.Response, error) {
+				req, _ := http.NewRequest("GET", server.URL+"/discussions?private_token="+secret, nil)
+				req.Header.Set("PRIVATE-TOKEN", secret)
+				return client.Do(req)
+			},
+			func() (*http.Response, error) {
+				return client.PostForm(server.URL+"/signin", url.Values{"userName": {"user"}, "password": {secret}})
+			},
+			// 同じリクエストは記録した順に再生する
+			func() (*http.Response, error) { return client.Get(server.URL + "/pull/1") },
+			func() (*http.Response, error) { return client.Get(server.URL + "/pull/1") },
+		}
+		for _, request := range requests {
+			resp, err := request()
+			if err != nil {
+				t.Fatal(err)
+			}
+			bs, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.Header.Get("X-Next-Page") != "2" {
+				t.Errorf("レスポンスヘッダーが期待通りではありません。実際の値：%v", resp.Header)
+			}
+			bodies = append(bodies, string(bs))
+		}
+		return bodies
+	}
+
+	recorded := send(&http.Client{Transport: &Recorder{Dir: dir}}, "token-secret")
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(paths) != 4 {
+		t.Fatalf("やり取りの件数が期待通りではありません。実際の値：%v", len(paths))
+	}
+	for _, path := range paths {
+		bs, _ := os.ReadFile(path)
+		if strings.Contains(string(bs), "token-secret") || strings.Contains(string(bs), "cookie-secret") {
+			t.Errorf("秘密情報が記録されています。%v", string(bs))
+		}
+	}
+
+	// 再生時のアクセストークンやパスワードは記録時と異なってもよい
+	replayed := send(&http.Client{Transport: &Replayer{Dir: dir}}, "other")
+	if strings.Join(replayed, ",") != strings.Join(recorded, ",") {
+		t.Errorf("再生したレスポンスが期待通りではありません。期待値：%v 実際の値：%v", recorded, replayed)
+	}
+	if count != 4 {
+		t.Errorf("再生時はリクエストを送信しない想定です。リクエスト数：%v", count)
+	}
+
+	_, err := (&http.Client{Transport: &Replayer{Dir: dir}}).Get(server.URL + "/changes")
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("記録されていないリクエストはエラーになる想定です。実際の値：%v", err)
+	}
+}
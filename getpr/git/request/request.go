@@ -0,0 +1,44 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/git/cache"
+	"github.com/Fintan-contents/review-support-tool/getpr/git/record"
+)
+
+// 利用者向けのメッセージを持つため、そのまま返すエラー
+var knownErrors = []error{cache.ErrNotCached, cache.ErrSave, record.ErrNotRecorded, record.ErrLoad, record.ErrRecord}
+
+// HTTPリクエストで発生したエラーを利用者向けのエラーメッセージに変換する。
+// 中断やタイムアウト、キャッシュや記録・再生に関するエラーはその旨を、それ以外は汎用的なメッセージを返す。
+func Error(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return errors.New("処理を中断しました。")
+	case context.DeadlineExceeded:
+		return errors.New("制限時間内に処理が完了しなかったため中断しました。timeoutの設定を見直してください。")
+	}
+	for _, known := range knownErrors {
+		if errors.Is(err, known) {
+			return known
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errors.New("APIの応答が制限時間内に返りませんでした。request-timeoutやproxyの設定を見直してください。")
+	}
+	return errors.New("エラーが発生しました。")
+}
+
+// HTTPリクエストで発生したエラーが、利用者向けのメッセージを持つ場合はtrueを返す。
+func IsKnown(err error) bool {
+	for _, known := range knownErrors {
+		if errors.Is(err, known) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/git/cache"
+)
+
+func TestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+
+	fixtures := []struct {
+		name      string
+		ctx       context.Context
+		timeout   time.Duration
+		transport http.RoundTripper
+		expected  string
+	}{
+		{"中断", canceled, 0, nil, "処理を中断しました。"},
+		{"全体のタイムアウト", expired, 0, nil, "制限時間内に処理が完了しなかったため中断しました。timeoutの設定を見直してください。"},
+		{"リクエストのタイムアウト", context.Background(), 10 * time.Millisecond, nil, "APIの応答が制限時間内に返りませんでした。request-timeoutやproxyの設定を見直してください。"},
+		{"キャッシュなし", context.Background(), 0, &cache.Transport{Dir: t.TempDir(), Offline: true}, "キャッシュにレスポンスが保存されていません。from-cacheを指定せずに一度実行してください。"},
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			client := &http.Client{Timeout: fixture.timeout, Transport: fixture.transport}
+			req, _ := http.NewRequestWithContext(fixture.ctx, "GET", server.URL, nil)
+			_, err := client.Do(req)
+			if err == nil {
+				t.Fatal("エラーが発生する想定です。")
+			}
+			if actual := Error(fixture.ctx, err).Error(); actual != fixture.expected {
+				t.Errorf("期待値は %v ですが実際には %v でした", fixture.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// getprのテストで使用する、GitHub、GitLab、GitBucketを模した偽のサーバーを提供するパッケージ。
+//
+// サーバーはScenarioに書いたプルリクエストを各サービスの形式で返す。
+// ページングはgetprが送信したページサイズに従い、ScenarioのFailAfterやStatus、RateLimitedで失敗を再現できる。
+package fakeserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// 偽のサーバーが返すプルリクエストとその返し方。
+type Scenario struct {
+	// プルリクエストのURL
+	Url string
+	// プルリクエスト作成者のユーザー名
+	Author string
+	// 追加行数
+	Additions int
+	// 削除行数
+	Deletions int
+	// 通常のコメント。GitLabでは1件のnoteからなるdiscussionとして返す。
+	Comments []Comment
+	// スレッド形式のコメント
+	Threads []Thread
+	// アサイン先のユーザー名
+	Assignees []string
+	// コミットごとの作成者のユーザー名。GitLabではコミットに記録された作成者の名前として、ユーザーの表示名を返す。
+	Commits []string
+	// ユーザー名ごとの表示名。GitHubとGitLabで返す。登録されていないユーザーの表示名は、GitHubでは空、GitLabではユーザー名とする。
+	Names map[string]string
+
+	// GitLabでX-Total-Pagesを返さない場合はtrue
+	OmitTotalPages bool
+	// 指定した件数のリクエストに正常に応答した後、StatusやRateLimitedに従って失敗する。0の場合は最初から失敗する。
+	FailAfter int
+	// 失敗する際に返すステータスコード。0の場合は失敗しない。
+	Status int
+	// 失敗する際にレート制限を超えた旨を返す場合はtrue
+	RateLimited bool
+}
+
+// スレッド形式のコメント
+type Thread struct {
+	Resolved bool
+	Comments []Comment
+}
+
+// コメント。GitBucketではCreatedAtをそのタイムゾーンの日時として書き出す。
+type Comment struct {
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// 偽のサーバー
+type Server struct {
+	*httptest.Server
+	scenario *Scenario
+
+	mu       sync.Mutex
+	requests int
+}
+
+func newServer(scenario *Scenario, handler func(server *Server, w http.ResponseWriter, r *http.Request)) *Server {
+	server := &Server{scenario: scenario}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(server, w, r)
+	}))
+	return server
+}
+
+// 受け付けたリクエストの数を返す。
+func (server *Server) Requests() int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.requests
+}
+
+// リクエストを数え、シナリオに従って失敗させる場合はtrueを返す。
+func (server *Server) failing() bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.requests++
+	if server.scenario.Status == 0 && !server.scenario.RateLimited {
+		return false
+	}
+	return server.requests > server.scenario.FailAfter
+}
+
+// 失敗する際のステータスコードを返す。
+func (server *Server) failureStatus() int {
+	if server.scenario.Status != 0 {
+		return server.scenario.Status
+	}
+	return http.StatusTooManyRequests
+}
+
+// スライスのstart以降から最大limit件を取り出す範囲と、次のページがあるかを返す。
+func page(length, start, limit int) (int, int, bool) {
+	if start > length {
+		start = length
+	}
+	end := start + limit
+	if limit <= 0 || end > length {
+		end = length
+	}
+	return start, end, end < length
+}
+
+// 投稿されたコメントの作成日時。既存のコメントより後に並ぶよう、シナリオの日時より後の固定の日時にする。
+var PostedAt = time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+
+// 投稿されたコメントを通常のコメントとしてシナリオへ追加し、その位置を返す。
+func (server *Server) post(body string) int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.scenario.Comments = append(server.scenario.Comments, Comment{Author: "getpr", Body: body, CreatedAt: PostedAt})
+	return len(server.scenario.Comments) - 1
+}
+
+// 指定された位置の通常のコメントの本文を更新する。コメントがなければfalseを返す。
+func (server *Server) update(i int, body string) bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if i < 0 || i >= len(server.scenario.Comments) {
+		return false
+	}
+	server.scenario.Comments[i].Body = body
+	return true
+}
@@ -0,0 +1,74 @@
+package fakeserver
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/markup"
+)
+
+// GitBucketのうち、getprが使用するログイン、ログアウト、プルリクエストの画面を実装した偽のサーバーを起動する。
+// エンドポイントにはサーバーのURLをそのまま指定し、プルリクエストは任意のorg、repo、pullで取得できる。
+func NewGitBucket(scenario *Scenario) *Server {
+	return newServer(scenario, serveGitBucket)
+}
+
+func serveGitBucket(server *Server, w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/signin" && r.Method == http.MethodGet:
+		fmt.Fprint(w, `<html><body><form method="POST" action="/signin"></form></body></html>`)
+	case r.URL.Path == "/signin" && r.Method == http.MethodPost:
+		http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session", Path: "/"})
+		http.Redirect(w, r, "/", http.StatusFound)
+	case r.URL.Path == "/signout":
+		http.Redirect(w, r, "/", http.StatusFound)
+	case r.URL.Path == "/":
+		fmt.Fprint(w, `<html><body></body></html>`)
+	case strings.Contains(r.URL.Path, "/pull/"):
+		if server.failing() {
+			w.WriteHeader(server.failureStatus())
+			return
+		}
+		if _, err := r.Cookie("JSESSIONID"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, gitBucketHtml(server.scenario))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// プルリクエストの画面のうち、getprが読み取るコメント一覧だけを書き出す。
+func gitBucketHtml(scenario *Scenario) string {
+	builder := &strings.Builder{}
+	builder.WriteString(`<html><body><div id="comment-list">`)
+	// 先頭はプルリクエストの概要
+	fmt.Fprintf(builder, `<div class="panel panel-default issue-comment-box"><div class="panel-heading"><a class="username strong">%s</a></div><div class="panel-body markdown-body" id="issueContent"></div></div>`,
+		html.EscapeString(scenario.Author))
+	for i, comment := range scenario.Comments {
+		fmt.Fprintf(builder, `<div class="panel panel-default issue-comment-box" id="comment-%d"><div class="panel-heading"><a class="username strong">%s</a> <span class="muted">commented %s</span></div><div class="panel-body markdown-body">%s</div></div>`,
+			i, html.EscapeString(comment.Author), gitBucketTimestamp(comment), gitBucketBody(comment.Body))
+	}
+	for i, thread := range scenario.Threads {
+		builder.WriteString(`<div class="panel panel-default"><div class="panel-body">`)
+		for j, comment := range thread.Comments {
+			fmt.Fprintf(builder, `<div class="commit-comment-box inline-comment" id="discussion_r%d%02d"><div class="markdown-body"><div><a class="username strong">%s</a> %s</div><div class="commit-commentContent-%d%02d">%s</div></div></div>`,
+				i, j, html.EscapeString(comment.Author), gitBucketTimestamp(comment), i, j, gitBucketBody(comment.Body))
+		}
+		builder.WriteString(`</div></div>`)
+	}
+	builder.WriteString(`</div></body></html>`)
+	return builder.String()
+}
+
+func gitBucketTimestamp(comment Comment) string {
+	return fmt.Sprintf(`<span data-toggle="tooltip" title="%s">just now</span>`, comment.CreatedAt.Format("2006-01-02 15:04:05"))
+}
+
+// GitBucketと同じく、本文のマークダウンをHTMLにして書き出す。
+func gitBucketBody(body string) string {
+	return markup.MarkdownToHtml(body)
+}
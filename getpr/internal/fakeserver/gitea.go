@@ -0,0 +1,188 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// リポジトリ配下のリソースのパス
+	giteaPath = regexp.MustCompile(`^/repos/[^/]+/[^/]+(/.+)$`)
+	// レビューのコメントのパス
+	giteaReviewCommentsPath = regexp.MustCompile(`^/pulls/[^/]+/reviews/([0-9]+)/comments$`)
+	// 通常のコメントのパス
+	giteaCommentPath = regexp.MustCompile(`^/issues/comments/([0-9]+)$`)
+)
+
+// GiteaのREST API（v1）のうち、getprが使用するプルリクエスト、通常のコメント、レビュー、レビューのコメント、コミットを実装した偽のサーバーを起動する。
+// エンドポイントにはサーバーのURLをそのまま指定し、プルリクエストは任意のorg、repo、pullで取得できる。
+//
+// スレッドはそれぞれ1件のレビューとして返し、返信も同じレビューのコメントとする。
+func NewGitea(scenario *Scenario) *Server {
+	return newServer(scenario, serveGitea)
+}
+
+func serveGitea(server *Server, w http.ResponseWriter, r *http.Request) {
+	if server.failing() {
+		w.WriteHeader(server.failureStatus())
+		return
+	}
+	match := giteaPath.FindStringSubmatch(r.URL.Path)
+	if match == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	scenario := server.scenario
+	path := match[1]
+	switch {
+	case regexp.MustCompile(`^/pulls/[^/]+$`).MatchString(path):
+		writeJson(w, map[string]interface{}{
+			"html_url":  scenario.Url,
+			"user":      giteaUser(scenario, scenario.Author),
+			"assignees": giteaAssignees(scenario),
+			"additions": scenario.Additions,
+			"deletions": scenario.Deletions,
+		})
+	case regexp.MustCompile(`^/issues/[^/]+/comments$`).MatchString(path):
+		if r.Method == http.MethodPost {
+			body, ok := decodeGiteaBody(w, r)
+			if !ok {
+				return
+			}
+			i := server.post(body)
+			writeJson(w, map[string]interface{}{"id": i + 1, "body": body})
+			return
+		}
+		comments := make([]interface{}, len(scenario.Comments))
+		for i, comment := range scenario.Comments {
+			comments[i] = giteaComment(scenario, comment, i+1)
+		}
+		writeJson(w, comments)
+	case regexp.MustCompile(`^/pulls/[^/]+/reviews$`).MatchString(path):
+		reviews := make([]interface{}, len(scenario.Threads))
+		for i, thread := range scenario.Threads {
+			first := thread.Comments[0]
+			reviews[i] = map[string]interface{}{
+				"id":             i + 1,
+				"html_url":       scenario.Url + "#pullrequestreview-" + strconv.Itoa(i+1),
+				"user":           giteaUser(scenario, first.Author),
+				"body":           "",
+				"state":          "COMMENT",
+				"comments_count": len(thread.Comments),
+				"submitted_at":   first.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+				"updated_at":     first.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			}
+		}
+		writeGiteaPage(w, r, reviews)
+	case giteaReviewCommentsPath.MatchString(path):
+		i, _ := strconv.Atoi(giteaReviewCommentsPath.FindStringSubmatch(path)[1])
+		if i < 1 || i > len(scenario.Threads) {
+			http.NotFound(w, r)
+			return
+		}
+		writeJson(w, giteaReviewComments(scenario, i-1))
+	case regexp.MustCompile(`^/pulls/[^/]+/commits$`).MatchString(path):
+		commits := make([]interface{}, len(scenario.Commits))
+		for i, login := range scenario.Commits {
+			commits[i] = map[string]interface{}{
+				"author": giteaUser(scenario, login),
+				"commit": map[string]interface{}{"author": map[string]string{"name": gitLabName(scenario, login)}},
+			}
+		}
+		writeGiteaPage(w, r, commits)
+	case giteaCommentPath.MatchString(path) && r.Method == http.MethodPatch:
+		id, _ := strconv.Atoi(giteaCommentPath.FindStringSubmatch(path)[1])
+		body, ok := decodeGiteaBody(w, r)
+		if !ok {
+			return
+		}
+		if !server.update(id-1, body) {
+			http.NotFound(w, r)
+			return
+		}
+		writeJson(w, map[string]interface{}{"id": id, "body": body})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// リクエストボディからコメントの本文を読み取る。読み取れない場合は400を返してfalseを返す。
+func decodeGiteaBody(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var body struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return "", false
+	}
+	return body.Body, true
+}
+
+func giteaAssignees(scenario *Scenario) []interface{} {
+	assignees := make([]interface{}, len(scenario.Assignees))
+	for i, login := range scenario.Assignees {
+		assignees[i] = giteaUser(scenario, login)
+	}
+	return assignees
+}
+
+func giteaUser(scenario *Scenario, login string) interface{} {
+	return map[string]string{"login": login, "full_name": scenario.Names[login]}
+}
+
+func giteaComment(scenario *Scenario, comment Comment, id int) interface{} {
+	return map[string]interface{}{
+		"id":         id,
+		"html_url":   scenario.Url + "#issuecomment-" + strconv.Itoa(id),
+		"user":       giteaUser(scenario, comment.Author),
+		"body":       comment.Body,
+		"created_at": comment.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		"updated_at": comment.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// スレッドのコメントを、スレッドごとに異なるファイルの同じ行へのコメントとして返す。
+// 通常のコメントとIDが重複しないよう、IDはスレッドとスレッド内の位置から決める。解決したスレッドは最初のコメントに解決したユーザーを設定する。
+func giteaReviewComments(scenario *Scenario, i int) []interface{} {
+	thread := scenario.Threads[i]
+	comments := make([]interface{}, len(thread.Comments))
+	for j, comment := range thread.Comments {
+		id := 1000 + i*100 + j
+		var resolver interface{}
+		if thread.Resolved && j == 0 {
+			resolver = giteaUser(scenario, thread.Comments[len(thread.Comments)-1].Author)
+		}
+		comments[j] = map[string]interface{}{
+			"id":                     id,
+			"html_url":               scenario.Url + "/files#issuecomment-" + strconv.Itoa(id),
+			"user":                   giteaUser(scenario, comment.Author),
+			"body":                   comment.Body,
+			"path":                   "file" + strconv.Itoa(i) + ".go",
+			"position":               1,
+			"original_position":      0,
+			"resolver":               resolver,
+			"pull_request_review_id": i + 1,
+			"created_at":             comment.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			"updated_at":             comment.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		}
+	}
+	return comments
+}
+
+// limitとpageに従って一覧の1ページを返し、X-Total-Countを設定する。
+func writeGiteaPage(w http.ResponseWriter, r *http.Request, items []interface{}) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 30
+	}
+	pageNumber, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || pageNumber <= 0 {
+		pageNumber = 1
+	}
+	start, end, _ := page(len(items), (pageNumber-1)*limit, limit)
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
+	writeJson(w, items[start:end])
+}
@@ -0,0 +1,187 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GitHubのGraphQL APIのうち、getprが使用するComments、Reviewees、Threads、ThreadComments、PullRequestIdクエリーと、
+// AddComment、UpdateIssueCommentミューテーションを実装した偽のサーバーを起動する。
+// エンドポイントにはサーバーのURLをそのまま指定する。
+func NewGitHub(scenario *Scenario) *Server {
+	return newServer(scenario, serveGitHub)
+}
+
+func serveGitHub(server *Server, w http.ResponseWriter, r *http.Request) {
+	if server.failing() {
+		if server.scenario.RateLimited {
+			// GraphQL APIはレート制限を超えてもステータスコード200でエラーを返す
+			writeJson(w, map[string]interface{}{"errors": []map[string]string{{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}}})
+			return
+		}
+		w.WriteHeader(server.failureStatus())
+		return
+	}
+
+	var body struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	variable := func(name string) string {
+		value, _ := body.Variables[name].(string)
+		return value
+	}
+	limit := func(name string) int {
+		value, _ := body.Variables[name].(float64)
+		return int(value)
+	}
+
+	scenario := server.scenario
+	switch {
+	case strings.HasPrefix(body.Query, "query Comments"):
+		comments := make([]interface{}, len(scenario.Comments))
+		for i, comment := range scenario.Comments {
+			comments[i] = gitHubComment(scenario, comment, fmt.Sprintf("IC_%d", i), scenario.Url+"#issuecomment-"+strconv.Itoa(i))
+		}
+		writeJson(w, gitHubPullRequest(map[string]interface{}{
+			"url":       scenario.Url,
+			"additions": scenario.Additions,
+			"deletions": scenario.Deletions,
+			"title":     "",
+			"body":      "",
+			"author":    gitHubAuthor(scenario, scenario.Author),
+			"comments":  gitHubConnection(comments, variable("commentsCursor"), limit("limit")),
+			"reviews":   gitHubConnection(nil, variable("reviewsCursor"), limit("limit")),
+		}))
+	case strings.HasPrefix(body.Query, "query Reviewees"):
+		assignees := make([]interface{}, len(scenario.Assignees))
+		for i, login := range scenario.Assignees {
+			assignees[i] = map[string]string{"login": login}
+		}
+		commits := make([]interface{}, len(scenario.Commits))
+		for i, login := range scenario.Commits {
+			commits[i] = map[string]interface{}{"commit": map[string]interface{}{"authors": map[string]interface{}{
+				"nodes": []interface{}{map[string]interface{}{"user": map[string]string{"login": login}}},
+			}}}
+		}
+		writeJson(w, gitHubPullRequest(map[string]interface{}{
+			"assignees": gitHubNodes(assignees, variable("assigneesCursor"), limit("limit")),
+			"commits":   gitHubNodes(commits, variable("commitsCursor"), limit("limit")),
+		}))
+	case strings.HasPrefix(body.Query, "query Threads"):
+		threads := make([]interface{}, len(scenario.Threads))
+		for i, thread := range scenario.Threads {
+			threads[i] = map[string]interface{}{
+				"id":         fmt.Sprintf("PRRT_%d", i),
+				"isResolved": thread.Resolved,
+				"comments":   gitHubConnection(gitHubThreadComments(scenario, i), "", limit("commentsLimit")),
+			}
+		}
+		writeJson(w, gitHubPullRequest(map[string]interface{}{
+			"author":        gitHubAuthor(scenario, scenario.Author),
+			"reviewThreads": gitHubConnection(threads, variable("reviewThreadsCursor"), limit("reviewThreadsLimit")),
+		}))
+	case strings.HasPrefix(body.Query, "query ThreadComments"):
+		i, err := strconv.Atoi(strings.TrimPrefix(variable("id"), "PRRT_"))
+		if err != nil || i >= len(scenario.Threads) {
+			writeJson(w, map[string]interface{}{"errors": []map[string]string{{"type": "NOT_FOUND"}}})
+			return
+		}
+		writeJson(w, map[string]interface{}{"data": map[string]interface{}{"node": map[string]interface{}{
+			"comments": gitHubConnection(gitHubThreadComments(scenario, i), variable("cursor"), limit("limit")),
+		}}})
+	case strings.HasPrefix(body.Query, "query PullRequestId"):
+		writeJson(w, gitHubPullRequest(map[string]interface{}{"id": "PR_1"}))
+	case strings.HasPrefix(body.Query, "mutation AddComment"):
+		if variable("subjectId") != "PR_1" {
+			writeJson(w, map[string]interface{}{"errors": []map[string]string{{"type": "NOT_FOUND"}}})
+			return
+		}
+		id := server.post(variable("body"))
+		writeJson(w, map[string]interface{}{"data": map[string]interface{}{"addComment": map[string]interface{}{
+			"commentEdge": map[string]interface{}{"node": map[string]string{"id": fmt.Sprintf("IC_%d", id)}},
+		}}})
+	case strings.HasPrefix(body.Query, "mutation UpdateIssueComment"):
+		i, err := strconv.Atoi(strings.TrimPrefix(variable("id"), "IC_"))
+		if err != nil || !server.update(i, variable("body")) {
+			writeJson(w, map[string]interface{}{"errors": []map[string]string{{"type": "NOT_FOUND"}}})
+			return
+		}
+		writeJson(w, map[string]interface{}{"data": map[string]interface{}{"updateIssueComment": map[string]interface{}{
+			"issueComment": map[string]string{"id": variable("id")},
+		}}})
+	default:
+		http.Error(w, "unknown query", http.StatusBadRequest)
+	}
+}
+
+func gitHubPullRequest(pullRequest map[string]interface{}) interface{} {
+	return map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"pullRequest": pullRequest}}}
+}
+
+func gitHubThreadComments(scenario *Scenario, i int) []interface{} {
+	comments := make([]interface{}, len(scenario.Threads[i].Comments))
+	for j, comment := range scenario.Threads[i].Comments {
+		comments[j] = gitHubComment(scenario, comment, fmt.Sprintf("PRRC_%d_%d", i, j), fmt.Sprintf("%s#discussion_r%d%02d", scenario.Url, i, j))
+	}
+	return comments
+}
+
+func gitHubAuthor(scenario *Scenario, login string) interface{} {
+	return map[string]string{"login": login, "name": scenario.Names[login]}
+}
+
+func gitHubComment(scenario *Scenario, comment Comment, id, url string) interface{} {
+	return map[string]interface{}{
+		"id":        id,
+		"url":       url,
+		"body":      comment.Body,
+		"author":    gitHubAuthor(scenario, comment.Author),
+		"createdAt": comment.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		"updatedAt": comment.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// ノードの一覧をページングしたコネクションを返す。カーソルは一覧の位置。
+func gitHubConnection(nodes []interface{}, cursor string, limit int) interface{} {
+	start, _ := strconv.Atoi(cursor)
+	start, end, hasNextPage := page(len(nodes), start, limit)
+	edges := make([]interface{}, 0)
+	for i := start; i < end; i++ {
+		edges = append(edges, map[string]interface{}{"node": nodes[i], "cursor": strconv.Itoa(i + 1)})
+	}
+	endCursor := ""
+	if end > 0 {
+		endCursor = strconv.Itoa(end)
+	}
+	return map[string]interface{}{
+		"edges":    edges,
+		"pageInfo": map[string]interface{}{"hasNextPage": hasNextPage, "endCursor": endCursor},
+	}
+}
+
+// ノードの一覧をページングし、エッジを介さずにノードを返すコネクションを返す。カーソルは一覧の位置。
+func gitHubNodes(nodes []interface{}, cursor string, limit int) interface{} {
+	start, _ := strconv.Atoi(cursor)
+	start, end, hasNextPage := page(len(nodes), start, limit)
+	var endCursor interface{}
+	if end > start {
+		endCursor = strconv.Itoa(end)
+	}
+	return map[string]interface{}{
+		"nodes":    nodes[start:end],
+		"pageInfo": map[string]interface{}{"hasNextPage": hasNextPage, "endCursor": endCursor},
+	}
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
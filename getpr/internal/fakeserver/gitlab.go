@@ -0,0 +1,180 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// マージリクエスト配下のリソースのパス
+var gitLabPath = regexp.MustCompile(`^/projects/[^/]+/merge_requests/[^/]+(/[a-z]+(?:/[0-9]+)?)?$`)
+
+// GitLabのREST API（v4）のうち、getprが使用するマージリクエスト、discussions、changes、commits、notesを実装した偽のサーバーを起動する。
+// エンドポイントにはサーバーのURLをそのまま指定する。
+func NewGitLab(scenario *Scenario) *Server {
+	return newServer(scenario, serveGitLab)
+}
+
+func serveGitLab(server *Server, w http.ResponseWriter, r *http.Request) {
+	if server.failing() {
+		w.WriteHeader(server.failureStatus())
+		return
+	}
+	match := gitLabPath.FindStringSubmatch(r.URL.Path)
+	if match == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	scenario := server.scenario
+	switch match[1] {
+	case "":
+		writeJson(w, map[string]interface{}{
+			"web_url":   scenario.Url,
+			"author":    gitLabAuthor(scenario, scenario.Author),
+			"assignees": gitLabAssignees(scenario),
+		})
+	case "/discussions":
+		writeGitLabPage(w, r, gitLabDiscussions(scenario), scenario.OmitTotalPages)
+	case "/commits":
+		writeGitLabPage(w, r, gitLabCommits(scenario), scenario.OmitTotalPages)
+	case "/notes":
+		var body struct {
+			Body string `json:"body"`
+		}
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&body) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		server.post(body.Body)
+		writeJson(w, map[string]interface{}{"body": body.Body})
+	case "/changes":
+		diff := "@@ -1 +1 @@\n" + strings.Repeat("+added\n", scenario.Additions) + strings.Repeat("-deleted\n", scenario.Deletions)
+		writeJson(w, map[string]interface{}{"changes": []map[string]string{{"diff": diff}}})
+	default:
+		// 通常のコメントのnoteの本文を更新する
+		if id, err := strconv.Atoi(strings.TrimPrefix(match[1], "/notes/")); err == nil && r.Method == http.MethodPut {
+			var body struct {
+				Body string `json:"body"`
+			}
+			if json.NewDecoder(r.Body).Decode(&body) != nil {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			for i, discussion := range sortGitLabDiscussions(scenario) {
+				if i*100+1 == id && discussion.comment >= 0 && server.update(discussion.comment, body.Body) {
+					writeJson(w, map[string]interface{}{"id": id, "body": body.Body})
+					return
+				}
+			}
+		}
+		http.NotFound(w, r)
+	}
+}
+
+func gitLabAssignees(scenario *Scenario) []interface{} {
+	assignees := make([]interface{}, len(scenario.Assignees))
+	for i, login := range scenario.Assignees {
+		assignees[i] = gitLabAuthor(scenario, login)
+	}
+	return assignees
+}
+
+// コミットの一覧を返す。コミットにはユーザー名を含めず、作成者の名前にユーザーの表示名を設定する。
+func gitLabCommits(scenario *Scenario) []interface{} {
+	commits := make([]interface{}, len(scenario.Commits))
+	for i, login := range scenario.Commits {
+		commits[i] = map[string]interface{}{"author_name": gitLabName(scenario, login)}
+	}
+	return commits
+}
+
+// discussion
+type gitLabDiscussion struct {
+	thread   Thread
+	threaded bool
+	// 通常のコメントの場合はシナリオのCommentsでの位置。スレッドの場合は-1。
+	comment int
+}
+
+// 通常のコメントとスレッドを、最初のコメントの作成日時の順に並べる。
+func sortGitLabDiscussions(scenario *Scenario) []gitLabDiscussion {
+	discussions := make([]gitLabDiscussion, 0)
+	for i, comment := range scenario.Comments {
+		discussions = append(discussions, gitLabDiscussion{thread: Thread{Comments: []Comment{comment}}, comment: i})
+	}
+	for _, thread := range scenario.Threads {
+		discussions = append(discussions, gitLabDiscussion{thread: thread, threaded: true, comment: -1})
+	}
+	sort.SliceStable(discussions, func(i, j int) bool {
+		return discussions[i].thread.Comments[0].CreatedAt.Before(discussions[j].thread.Comments[0].CreatedAt)
+	})
+	return discussions
+}
+
+// 通常のコメントとスレッドを、最初のコメントの作成日時の順に並べたdiscussionの一覧を返す。
+func gitLabDiscussions(scenario *Scenario) []interface{} {
+	discussions := sortGitLabDiscussions(scenario)
+	result := make([]interface{}, 0, len(discussions))
+	for i, discussion := range discussions {
+		notes := make([]interface{}, 0)
+		for j, comment := range discussion.thread.Comments {
+			notes = append(notes, map[string]interface{}{
+				// 返信を追加しても他のノートのIDが変わらないよう、スレッドとスレッド内の位置から決める
+				"id":         i*100 + j + 1,
+				"body":       comment.Body,
+				"author":     gitLabAuthor(scenario, comment.Author),
+				"resolved":   discussion.thread.Resolved,
+				"created_at": comment.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+				"updated_at": comment.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+			})
+		}
+		result = append(result, map[string]interface{}{
+			"id":              "discussion" + strconv.Itoa(i),
+			"individual_note": !discussion.threaded,
+			"notes":           notes,
+		})
+	}
+	return result
+}
+
+// per_pageとpageに従って一覧の1ページを返し、X-Next-PageとX-Total-Pagesを設定する。
+func writeGitLabPage(w http.ResponseWriter, r *http.Request, items []interface{}, omitTotalPages bool) {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = 20
+	}
+	pageNumber, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || pageNumber <= 0 {
+		pageNumber = 1
+	}
+	start, end, hasNextPage := page(len(items), (pageNumber-1)*perPage, perPage)
+	totalPages := (len(items) + perPage - 1) / perPage
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if !omitTotalPages {
+		w.Header().Set("X-Total-Pages", strconv.Itoa(totalPages))
+	}
+	nextPage := ""
+	if hasNextPage {
+		nextPage = strconv.Itoa(pageNumber + 1)
+	}
+	w.Header().Set("X-Next-Page", nextPage)
+	writeJson(w, items[start:end])
+}
+
+func gitLabAuthor(scenario *Scenario, username string) interface{} {
+	return map[string]string{"username": username, "name": gitLabName(scenario, username)}
+}
+
+// ユーザーの表示名を返す。表示名が登録されていない場合はユーザー名を返す。
+func gitLabName(scenario *Scenario, username string) string {
+	if name, ok := scenario.Names[username]; ok {
+		return name
+	}
+	return username
+}
@@ -0,0 +1,47 @@
+// git配下の各サービスのテストで使用する、記録したやり取りを再生して抽出結果を期待値と比較する処理を提供するパッケージ。
+package replaytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/git/record"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+)
+
+// trueの場合は期待値のJSONファイルを実際の抽出結果で更新する。
+var update = flag.Bool("update", false, "testdata配下の期待値のJSONファイルを更新する")
+
+// testdata/replayに記録したやり取りを再生するTransportをparseに渡して抽出したレビュー情報を、testdata/replay.jsonの期待値と比較する。
+// 期待値は go test -update で更新できる。
+func Compare(t *testing.T, parse func(transport http.RoundTripper) (*review.PullRequest, error)) {
+	t.Helper()
+	replayer := &record.Replayer{Dir: filepath.Join("testdata", "replay")}
+	pr, err := parse(replayer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := json.MarshalIndent(pr, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "replay.json")
+	if *update {
+		if err := os.WriteFile(golden, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("抽出したレビュー情報が期待通りではありません。\n期待値：\n%s\n実際の値：\n%s", expected, actual)
+	}
+}
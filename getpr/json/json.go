@@ -0,0 +1,36 @@
+package json
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+)
+
+// 指定されたWriterにレビュー情報をJSON形式で書き出す。
+// CSVと異なり、スレッド内のコメントを加工せずにそのまま書き出す。文字コードは常にUTF-8。
+func Write(w io.Writer, pr *review.PullRequest) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(pr)
+}
+
+// 指定されたパスからJSON形式のレビュー情報を読み込む。
+func ReadJson(jsonFile string) (*review.PullRequest, error) {
+	file, err := os.Open(jsonFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// 指定されたReaderからJSON形式のレビュー情報を読み込む。
+func Read(r io.Reader) (*review.PullRequest, error) {
+	var pr review.PullRequest
+	if err := json.NewDecoder(r).Decode(&pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
@@ -0,0 +1,490 @@
+package markup
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/text"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var spacePattern = regexp.MustCompile(`[ \t\r\n]+`)
+
+// 前後で改行するHTMLの要素
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Details: true,
+	atom.Div: true, atom.Dl: true, atom.Dd: true, atom.Dt: true, atom.Figure: true, atom.Footer: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.Section: true, atom.Summary: true, atom.Table: true, atom.Tr: true, atom.Ul: true,
+}
+
+// HTMLの断片をパースする。
+func parseFragment(source string) ([]*html.Node, error) {
+	return html.ParseFragment(strings.NewReader(source), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+}
+
+// HTMLを装飾のないテキストに変換する。MarkdownToPlainと同じく、リンクと画像は「テキスト (URL)」の形式にし、
+// 整形済みテキストは内容をそのまま残す。改行はCRLFにする。
+func HtmlToPlain(source string) string {
+	nodes, err := parseFragment(source)
+	if err != nil {
+		return source
+	}
+	lines := &plainLines{}
+	for _, n := range nodes {
+		lines.node(n)
+	}
+	return lines.String()
+}
+
+// HTMLから変換したテキストを行単位で組み立てる。
+type plainLines struct {
+	lines []string
+	// 組み立て中の行
+	current strings.Builder
+	// 行頭に付ける引用の記号
+	quote string
+}
+
+func (lines *plainLines) write(s string) {
+	if lines.current.Len() == 0 {
+		lines.current.WriteString(lines.quote)
+		s = strings.TrimLeft(s, " ")
+	}
+	lines.current.WriteString(s)
+}
+
+func (lines *plainLines) newLine() {
+	if lines.current.Len() > 0 {
+		lines.lines = append(lines.lines, strings.TrimRight(lines.current.String(), " "))
+		lines.current.Reset()
+	}
+}
+
+func (lines *plainLines) blankLine() {
+	lines.newLine()
+	if len(lines.lines) > 0 && lines.lines[len(lines.lines)-1] != "" {
+		lines.lines = append(lines.lines, "")
+	}
+}
+
+func (lines *plainLines) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		lines.node(c)
+	}
+}
+
+func (lines *plainLines) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if s := spacePattern.ReplaceAllString(n.Data, " "); strings.TrimSpace(s) != "" || lines.current.Len() > 0 {
+			lines.write(s)
+		}
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		lines.newLine()
+	case atom.Script, atom.Style:
+	case atom.Pre:
+		lines.blankLine()
+		for _, line := range strings.Split(strings.TrimRight(textContent(n), "\n"), "\n") {
+			lines.lines = append(lines.lines, lines.quote+line)
+		}
+		lines.blankLine()
+	case atom.A:
+		label := strings.TrimSpace(spacePattern.ReplaceAllString(textContent(n), " "))
+		if href := attr(n, "href"); href != "" && !strings.HasPrefix(href, "#") {
+			lines.write(linkText(label, href))
+		} else {
+			lines.write(label)
+		}
+	case atom.Img:
+		if src := attr(n, "src"); src != "" {
+			lines.write(linkText(attr(n, "alt"), src))
+		}
+	case atom.Li:
+		lines.newLine()
+		marker := "- "
+		if n.Parent != nil && n.Parent.DataAtom == atom.Ol {
+			index := 1
+			for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+				if s.DataAtom == atom.Li {
+					index++
+				}
+			}
+			marker = strconv.Itoa(index) + ". "
+		}
+		lines.write(marker)
+		lines.children(n)
+		lines.newLine()
+	case atom.Blockquote:
+		lines.blankLine()
+		quote := lines.quote
+		lines.quote += "> "
+		lines.children(n)
+		lines.newLine()
+		lines.quote = quote
+		lines.blankLine()
+	case atom.Td, atom.Th:
+		if n.PrevSibling != nil {
+			lines.write(" | ")
+		}
+		lines.children(n)
+	default:
+		if blockElements[n.DataAtom] {
+			if n.DataAtom == atom.P || n.DataAtom == atom.Hr || n.DataAtom == atom.Details {
+				lines.blankLine()
+			} else {
+				lines.newLine()
+			}
+			lines.children(n)
+			if n.DataAtom == atom.P || n.DataAtom == atom.Details {
+				lines.blankLine()
+			} else {
+				lines.newLine()
+			}
+			return
+		}
+		lines.children(n)
+	}
+}
+
+func (lines *plainLines) String() string {
+	lines.newLine()
+	return strings.Trim(strings.Join(collapseBlankLines(lines.lines), text.Crlf), text.Crlf)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	builder := strings.Builder{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		builder.WriteString(textContent(c))
+	}
+	return builder.String()
+}
+
+// テキストノードの前後の空白を取り除き、改行で繋いだテキストを返す。GitBucketの画面からコメントの本文を取り出す方法と同じ。
+func TextContent(n *html.Node) string {
+	builder := strings.Builder{}
+	var fn func(n *html.Node)
+	fn = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			textContent := strings.TrimSpace(n.Data)
+			if len(textContent) > 0 {
+				if builder.Len() > 0 {
+					builder.WriteString(text.Crlf)
+				}
+				builder.WriteString(textContent)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fn(c)
+		}
+	}
+	fn(n)
+	return builder.String()
+}
+
+// HTMLの断片からTextContentと同じ方法でテキストを取り出す。
+func ScrapeText(source string) string {
+	nodes, err := parseFragment(source)
+	if err != nil {
+		return source
+	}
+	texts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if textContent := TextContent(n); len(textContent) > 0 {
+			texts = append(texts, textContent)
+		}
+	}
+	return strings.Join(texts, text.Crlf)
+}
+
+// 要素の子ノードをHTMLの文字列にする。
+func InnerHtml(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	builder := &strings.Builder{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(builder, c)
+	}
+	// 対応する開始タグのない</p>は空の段落になるため取り除く
+	return strings.TrimSpace(strings.ReplaceAll(builder.String(), "<p></p>", ""))
+}
+
+// 段落か改行タグで区切られた行
+var htmlLinePattern = regexp.MustCompile(`(?i)(?:<p>|<br\s*/?>)\s*([^<]*?)\s*(?:</p>|<br\s*/?>)`)
+
+// 整形済みテキスト
+var prePattern = regexp.MustCompile(`(?is)<pre\b.*?</pre\s*>`)
+
+// HTMLを、isDelimiterがtrueを返す行で分割する。行は段落か改行タグで区切られたものとし、整形済みテキストの中は分割しない。
+func SplitHtml(source string, isDelimiter func(string) bool) []string {
+	parts := make([]string, 0, 2)
+	pres := prePattern.FindAllStringIndex(source, -1)
+	start := 0
+	for offset := 0; offset < len(source); {
+		loc := htmlLinePattern.FindStringSubmatchIndex(source[offset:])
+		if loc == nil {
+			break
+		}
+		if end, ok := enclosingEnd(pres, offset+loc[0]); ok {
+			offset = end
+			continue
+		}
+		if isDelimiter(html.UnescapeString(source[offset+loc[2] : offset+loc[3]])) {
+			parts = append(parts, balance(source[start:offset+loc[0]]))
+			start = offset + loc[1]
+			offset = start
+			continue
+		}
+		// 行末の改行タグは次の行の先頭にもなるため、行の内容の直後から探し直す
+		offset += loc[3]
+	}
+	return append(parts, balance(source[start:]))
+}
+
+// 位置を含む範囲があれば、その範囲の終わりを返す。
+func enclosingEnd(ranges [][]int, position int) (int, bool) {
+	for _, r := range ranges {
+		if r[0] <= position && position < r[1] {
+			return r[1], true
+		}
+	}
+	return 0, false
+}
+
+// 分割で閉じられなくなったタグを補い、余った終了タグを取り除く。テキストのない断片は空文字列にする。
+func balance(source string) string {
+	nodes, err := parseFragment(source)
+	if err != nil {
+		return strings.TrimSpace(source)
+	}
+	if strings.TrimSpace(HtmlToPlain(source)) == "" {
+		return ""
+	}
+	builder := &strings.Builder{}
+	for _, n := range nodes {
+		html.Render(builder, n)
+	}
+	// 対応する開始タグのない</p>は空の段落になるため取り除く
+	return strings.TrimSpace(strings.ReplaceAll(builder.String(), "<p></p>", ""))
+}
+
+// 引用（blockquote要素）を取り除く。
+func StripQuotes(source string) string {
+	nodes, err := parseFragment(source)
+	if err != nil {
+		return source
+	}
+	builder := &strings.Builder{}
+	for _, n := range nodes {
+		if n.DataAtom == atom.Blockquote {
+			continue
+		}
+		removeQuotes(n)
+		html.Render(builder, n)
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func removeQuotes(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && c.DataAtom == atom.Blockquote {
+			n.RemoveChild(c)
+		} else {
+			removeQuotes(c)
+		}
+		c = next
+	}
+}
+
+// 変更の提案（言語にsuggestionを指定した整形済みテキスト）を、text.RenderSuggestionsと同じく「提案: コード」の形式にする。
+// GitBucketのようにHTMLしか取得できない場合に使用する。
+func RenderSuggestions(source string) string {
+	root, err := parseContainer(source)
+	if err != nil {
+		return source
+	}
+	renderSuggestions(root)
+	return renderChildren(root)
+}
+
+func renderSuggestions(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if isSuggestion(c) {
+			if nodes, err := html.ParseFragment(strings.NewReader(suggestionHtml(textContent(c))), n); err == nil {
+				for _, node := range nodes {
+					n.InsertBefore(node, c)
+				}
+				n.RemoveChild(c)
+			}
+		} else {
+			renderSuggestions(c)
+		}
+		c = next
+	}
+}
+
+// 整形済みテキストかその中のコードに、言語としてsuggestionを指定するクラスがあればtrueを返す。
+func isSuggestion(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.DataAtom != atom.Pre {
+		return false
+	}
+	classes := strings.Fields(attr(n, "class"))
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Code {
+			classes = append(classes, strings.Fields(attr(c, "class"))...)
+		}
+	}
+	for _, class := range classes {
+		if class == "lang-suggestion" || class == "language-suggestion" {
+			return true
+		}
+	}
+	return false
+}
+
+func suggestionHtml(code string) string {
+	code = strings.TrimRight(code, "\n")
+	switch {
+	case code == "":
+		return "<p>提案: (削除)</p>"
+	case !strings.Contains(code, "\n"):
+		return "<p>提案: <code>" + html.EscapeString(code) + "</code></p>"
+	default:
+		return "<p>提案:</p><pre><code>" + html.EscapeString(code) + "</code></pre>"
+	}
+}
+
+// 署名の区切り（-- の行）で始まる段落から末尾までを、text.StripSignatureと同じく取り除く。区切りの後が5行を超える場合は署名とみなさない。
+func StripSignature(source string) string {
+	root, err := parseContainer(source)
+	if err != nil {
+		return source
+	}
+	following := 0
+	for n := root.LastChild; n != nil && following <= 5; n = n.PrevSibling {
+		lines := make([]string, 0)
+		for _, line := range strings.Split(HtmlToPlain(renderNode(n)), text.Crlf) {
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		if n.DataAtom == atom.P && len(lines) > 0 && strings.TrimRight(lines[0], " \t") == "--" && following+len(lines)-1 <= 5 {
+			for n.NextSibling != nil {
+				root.RemoveChild(n.NextSibling)
+			}
+			root.RemoveChild(n)
+			return renderChildren(root)
+		}
+		following += len(lines)
+	}
+	return source
+}
+
+// HTMLの断片をパースし、div要素の子ノードにして返す。
+func parseContainer(source string) (*html.Node, error) {
+	nodes, err := parseFragment(source)
+	if err != nil {
+		return nil, err
+	}
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	return root, nil
+}
+
+func renderNode(n *html.Node) string {
+	builder := &strings.Builder{}
+	html.Render(builder, n)
+	return builder.String()
+}
+
+func renderChildren(n *html.Node) string {
+	builder := &strings.Builder{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(builder, c)
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// 空白以外を含むテキストノードの内容を、TextContentと同じく前後の空白を取り除いてreplaceに渡し、replaceが返した内容に置き換える。
+// 空文字列に置き換えたテキストノードは取り除き、それによって空になった要素も取り除く。内容を変えなかった場合は元のHTMLを返す。
+// GitBucketのようにHTMLしか取得できない場合に、本文の行に対する処理をHTMLに適用するために使用する。
+func ReplaceTexts(source string, replace func(texts []string) []string) string {
+	root, err := parseContainer(source)
+	if err != nil {
+		return source
+	}
+	nodes := make([]*html.Node, 0)
+	texts := make([]string, 0)
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				if textContent := strings.TrimSpace(c.Data); len(textContent) > 0 {
+					nodes = append(nodes, c)
+					texts = append(texts, textContent)
+				}
+			} else {
+				collect(c)
+			}
+		}
+	}
+	collect(root)
+
+	replaced := replace(append([]string(nil), texts...))
+	changed := false
+	for i, n := range nodes {
+		if i >= len(replaced) || replaced[i] == texts[i] {
+			continue
+		}
+		changed = true
+		if replaced[i] != "" {
+			n.Data = replaced[i]
+			continue
+		}
+		for parent := n.Parent; parent != root && parent.FirstChild == n && n.NextSibling == nil; parent = n.Parent {
+			n = parent
+		}
+		// 行を区切っていた改行タグも取り除く
+		if isBreak(n.PrevSibling) {
+			n.Parent.RemoveChild(n.PrevSibling)
+		} else if isBreak(n.NextSibling) {
+			n.Parent.RemoveChild(n.NextSibling)
+		}
+		n.Parent.RemoveChild(n)
+	}
+	if !changed {
+		return source
+	}
+	return renderChildren(root)
+}
+
+func isBreak(n *html.Node) bool {
+	return n != nil && n.Type == html.ElementNode && n.DataAtom == atom.Br
+}
@@ -0,0 +1,354 @@
+// コメント本文のマークダウンやHTMLを、CSVへ書き出す形式に変換するパッケージ。
+package markup
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/text"
+)
+
+var (
+	fencePattern      = regexp.MustCompile("^ {0,3}(```+|~~~+)[ \t]*([^`\\s]*)")
+	headingPattern    = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)[ \t]*#*[ \t]*$`)
+	quotePattern      = regexp.MustCompile(`^ {0,3}>[ \t]?(.*)$`)
+	listPattern       = regexp.MustCompile(`^([ \t]*)([-*+]|\d+[.)])[ \t]+(.*)$`)
+	rulePattern       = regexp.MustCompile(`^ {0,3}((\*[ \t]*){3,}|(-[ \t]*){3,}|(_[ \t]*){3,})$`)
+	imagePattern      = regexp.MustCompile(`!\[([^\]]*)\]\(` + destination + `(?:[ \t]+"[^"]*")?\)`)
+	linkPattern       = regexp.MustCompile(`\[([^\]]+)\]\(` + destination + `(?:[ \t]+"[^"]*")?\)`)
+	autolinkPattern   = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	tagPattern        = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>`)
+	breakPattern      = regexp.MustCompile(`(?i)^<br\s*/?>$`)
+	strongPattern     = regexp.MustCompile(`\*\*([^*\s](?:[^*]*[^*\s])?)\*\*|__([^_\s](?:[^_]*[^_\s])?)__`)
+	strikePattern     = regexp.MustCompile(`~~([^~\s](?:[^~]*[^~\s])?)~~`)
+	emphasisPattern   = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	underscorePattern = regexp.MustCompile(`(^|[^\p{L}\p{N}_])_([^_\s](?:[^_]*[^_\s])?)_($|[^\p{L}\p{N}_])`)
+	escapePattern     = regexp.MustCompile("\\\\([!-/:-@\\[-`{-~])")
+)
+
+// リンクと画像のURL。URLの中の括弧は、対応が取れていれば2段まで入れ子にできる。
+const destination = `((?:[^()\s]|\((?:[^()\s]|\([^()\s]*\))*\))+)`
+
+// HTMLのリンクと画像に許可するスキーム
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// エスケープされた記号を置き換える私用領域の先頭
+const escapeBase = 0xE000
+
+// マークダウンの行の種類
+type block struct {
+	// 段落、見出し、引用、リスト、水平線、コードブロックのいずれか
+	kind string
+	// 見出しのレベル、またはリストのインデント
+	level int
+	// リストの記号
+	marker string
+	// 行の内容。コードブロックの場合は複数行。
+	lines []string
+	// コードブロックの言語
+	language string
+}
+
+// マークダウンを行単位の要素に分ける。入れ子のリストや引用の中のコードブロックといった複雑な構造は扱わない。
+func parseBlocks(markdown string) []block {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	blocks := make([]block, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if match := fencePattern.FindStringSubmatch(line); match != nil {
+			fence := match[1]
+			code := block{kind: "code", language: match[2], lines: []string{}}
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence[:3]) && strings.Trim(strings.TrimSpace(lines[i]), fence[:1]) == "" {
+					break
+				}
+				code.lines = append(code.lines, lines[i])
+			}
+			blocks = append(blocks, code)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			blocks = append(blocks, block{kind: "blank"})
+		} else if rulePattern.MatchString(line) {
+			blocks = append(blocks, block{kind: "rule", lines: []string{strings.TrimSpace(line)}})
+		} else if match := headingPattern.FindStringSubmatch(line); match != nil {
+			blocks = append(blocks, block{kind: "heading", level: len(match[1]), lines: []string{match[2]}})
+		} else if match := quotePattern.FindStringSubmatch(line); match != nil {
+			blocks = append(blocks, block{kind: "quote", lines: []string{match[1]}})
+		} else if match := listPattern.FindStringSubmatch(line); match != nil {
+			blocks = append(blocks, block{kind: "list", level: len(match[1]), marker: match[2], lines: []string{match[3]}})
+		} else {
+			blocks = append(blocks, block{kind: "paragraph", lines: []string{strings.TrimRight(line, " \t")}})
+		}
+	}
+	return blocks
+}
+
+// マークダウンを装飾のないテキストに変換する。
+//
+// 強調やインラインコードの記号、見出しの記号、HTMLのタグを取り除き、リンクと画像は「テキスト (URL)」の形式にする。
+// コードブロックは囲みの行だけを取り除いて内容をそのまま残す。改行はCRLFにする。
+func MarkdownToPlain(markdown string) string {
+	lines := make([]string, 0)
+	for _, b := range parseBlocks(markdown) {
+		switch b.kind {
+		case "code":
+			lines = append(lines, b.lines...)
+		case "blank":
+			lines = append(lines, "")
+		case "rule":
+			lines = append(lines, b.lines[0])
+		case "quote":
+			lines = append(lines, "> "+inlinePlain(b.lines[0]))
+		case "list":
+			marker := b.marker
+			if !isOrdered(marker) {
+				marker = "-"
+			}
+			lines = append(lines, strings.Repeat(" ", b.level)+marker+" "+inlinePlain(b.lines[0]))
+		default:
+			// タグだけの行（<details>など）は空行にせず取り除く
+			if plain := inlinePlain(b.lines[0]); strings.TrimSpace(plain) != "" || strings.TrimSpace(tagPattern.ReplaceAllString(b.lines[0], "")) != "" {
+				lines = append(lines, plain)
+			}
+		}
+	}
+	return strings.Trim(strings.Join(collapseBlankLines(lines), text.Crlf), text.Crlf)
+}
+
+// マークダウンをHTMLに変換する。マークダウンに書かれたHTMLのタグは改行を除いてエスケープする。改行はCRLFにする。
+func MarkdownToHtml(markdown string) string {
+	output := make([]string, 0)
+	var paragraph []string
+	var list []block
+	var quote []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			output = append(output, "<p>"+strings.Join(paragraph, "<br>"+text.Crlf)+"</p>")
+			paragraph = nil
+		}
+		if len(list) > 0 {
+			tag := "ul"
+			if isOrdered(list[0].marker) {
+				tag = "ol"
+			}
+			items := make([]string, 0, len(list))
+			for _, item := range list {
+				items = append(items, "<li>"+inlineHtml(item.lines[0])+"</li>")
+			}
+			output = append(output, "<"+tag+">"+strings.Join(items, "")+"</"+tag+">")
+			list = nil
+		}
+		if len(quote) > 0 {
+			output = append(output, "<blockquote>"+MarkdownToHtml(strings.Join(quote, "\n"))+"</blockquote>")
+			quote = nil
+		}
+	}
+	for _, b := range parseBlocks(markdown) {
+		// リストの記号の種類が変わった場合は別のリストにする
+		if b.kind != "paragraph" && len(paragraph) > 0 || len(list) > 0 && (b.kind != "list" || listType(b.marker) != listType(list[0].marker)) || b.kind != "quote" && len(quote) > 0 {
+			flush()
+		}
+		switch b.kind {
+		case "code":
+			class := ""
+			if b.language != "" {
+				class = ` class="language-` + html.EscapeString(b.language) + `"`
+			}
+			output = append(output, "<pre><code"+class+">"+html.EscapeString(strings.Join(b.lines, "\n"))+"</code></pre>")
+		case "rule":
+			output = append(output, "<hr>")
+		case "heading":
+			tag := "h" + strconv.Itoa(b.level)
+			output = append(output, "<"+tag+">"+inlineHtml(b.lines[0])+"</"+tag+">")
+		case "quote":
+			quote = append(quote, b.lines[0])
+		case "list":
+			list = append(list, b)
+		case "paragraph":
+			paragraph = append(paragraph, inlineHtml(b.lines[0]))
+		}
+	}
+	flush()
+	return strings.Join(output, text.Crlf)
+}
+
+func isOrdered(marker string) bool {
+	return marker != "-" && marker != "*" && marker != "+"
+}
+
+// リストの記号の種類として、番号なしリストは記号を、番号付きリストは番号の後の記号を返す。
+func listType(marker string) string {
+	return marker[len(marker)-1:]
+}
+
+// 連続する空行を1行にまとめる。
+func collapseBlankLines(lines []string) []string {
+	result := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if line == "" && i > 0 && lines[i-1] == "" {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// 1行の中のインラインコード、HTMLのタグ、それ以外のテキストを順に処理する。
+func inline(line string, code func(string) string, tag func(string) string, other func(string) string) string {
+	builder := &strings.Builder{}
+	rest := line
+	for rest != "" {
+		codeStart := strings.Index(rest, "`")
+		tagLoc := tagPattern.FindStringIndex(rest)
+		autolinkLoc := autolinkPattern.FindStringIndex(rest)
+		if autolinkLoc != nil && tagLoc != nil && autolinkLoc[0] == tagLoc[0] {
+			// <https://...> はタグではなくリンクとして扱う
+			tagLoc = nil
+		}
+		if codeStart >= 0 && (tagLoc == nil || codeStart < tagLoc[0]) {
+			ticks := len(rest[codeStart:]) - len(strings.TrimLeft(rest[codeStart:], "`"))
+			end := strings.Index(rest[codeStart+ticks:], strings.Repeat("`", ticks))
+			if end >= 0 {
+				builder.WriteString(other(rest[:codeStart]))
+				builder.WriteString(code(strings.TrimSpace(rest[codeStart+ticks : codeStart+ticks+end])))
+				rest = rest[codeStart+ticks+end+ticks:]
+				continue
+			}
+		}
+		if tagLoc != nil {
+			builder.WriteString(other(rest[:tagLoc[0]]))
+			builder.WriteString(tag(rest[tagLoc[0]:tagLoc[1]]))
+			rest = rest[tagLoc[1]:]
+			continue
+		}
+		builder.WriteString(other(rest))
+		break
+	}
+	return builder.String()
+}
+
+func inlinePlain(line string) string {
+	return inline(line, func(code string) string {
+		return code
+	}, func(tag string) string {
+		if breakPattern.MatchString(tag) {
+			return text.Crlf
+		}
+		return ""
+	}, func(s string) string {
+		s = protectEscapes(s)
+		s = imagePattern.ReplaceAllStringFunc(s, func(m string) string {
+			match := imagePattern.FindStringSubmatch(m)
+			return linkText(match[1], match[2])
+		})
+		s = linkPattern.ReplaceAllStringFunc(s, func(m string) string {
+			match := linkPattern.FindStringSubmatch(m)
+			return linkText(match[1], match[2])
+		})
+		s = autolinkPattern.ReplaceAllString(s, "$1")
+		s = strongPattern.ReplaceAllString(s, "$1$2")
+		s = strikePattern.ReplaceAllString(s, "$1")
+		s = emphasisPattern.ReplaceAllString(s, "$1")
+		s = underscorePattern.ReplaceAllString(s, "$1$2$3")
+		return restoreEscapes(html.UnescapeString(s), false)
+	})
+}
+
+// 1行をHTMLに変換する。書かれたHTMLのタグは改行を除いてエスケープする。
+//
+// 自動リンクはエスケープすると一致しなくなるため、エスケープ前のテキストで探し、その間のテキストだけをエスケープして装飾する。
+func inlineHtml(line string) string {
+	return inline(line, func(code string) string {
+		return "<code>" + html.EscapeString(code) + "</code>"
+	}, func(tag string) string {
+		if breakPattern.MatchString(tag) {
+			return "<br>"
+		}
+		return html.EscapeString(tag)
+	}, func(s string) string {
+		s = protectEscapes(s)
+		builder := strings.Builder{}
+		for {
+			loc := autolinkPattern.FindStringSubmatchIndex(s)
+			if loc == nil {
+				builder.WriteString(decorateHtml(s))
+				break
+			}
+			builder.WriteString(decorateHtml(s[:loc[0]]))
+			url := html.EscapeString(s[loc[2]:loc[3]])
+			builder.WriteString(`<a href="` + url + `">` + url + `</a>`)
+			s = s[loc[1]:]
+		}
+		return restoreEscapes(builder.String(), true)
+	})
+}
+
+// 自動リンクを除くテキストをエスケープし、リンクや強調などの装飾をHTMLにする。
+// 許可しないスキームのリンクと画像は、テキストだけを残す。
+func decorateHtml(s string) string {
+	s = html.EscapeString(html.UnescapeString(s))
+	s = imagePattern.ReplaceAllStringFunc(s, func(m string) string {
+		match := imagePattern.FindStringSubmatch(m)
+		if !isAllowedUrl(match[2]) {
+			return match[1]
+		}
+		return `<img src="` + match[2] + `" alt="` + match[1] + `">`
+	})
+	s = linkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		match := linkPattern.FindStringSubmatch(m)
+		if !isAllowedUrl(match[2]) {
+			return match[1]
+		}
+		return `<a href="` + match[2] + `">` + match[1] + `</a>`
+	})
+	s = strongPattern.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = strikePattern.ReplaceAllString(s, "<del>$1</del>")
+	s = emphasisPattern.ReplaceAllString(s, "<em>$1</em>")
+	return underscorePattern.ReplaceAllString(s, "$1<em>$2</em>$3")
+}
+
+// エスケープ済みのURLのスキームがhttp、https、mailtoのいずれかか、スキームのない相対URLであればtrueを返す。
+func isAllowedUrl(escaped string) bool {
+	url := html.UnescapeString(escaped)
+	i := strings.IndexAny(url, ":/?#")
+	if i < 0 || url[i] != ':' {
+		return true
+	}
+	return allowedSchemes[strings.ToLower(url[:i])]
+}
+
+// バックスラッシュでエスケープされた記号を、装飾として扱われないように私用領域の文字へ置き換える。
+func protectEscapes(s string) string {
+	return escapePattern.ReplaceAllStringFunc(s, func(m string) string {
+		return string(rune(escapeBase + int(m[1])))
+	})
+}
+
+// protectEscapesで置き換えた文字を元の記号に戻す。escapeHtmlがtrueの場合はHTMLとしてエスケープする。
+func restoreEscapes(s string, escapeHtml bool) string {
+	builder := strings.Builder{}
+	for _, r := range s {
+		if escapeBase <= r && r < escapeBase+0x80 {
+			if escapeHtml {
+				builder.WriteString(html.EscapeString(string(r - escapeBase)))
+			} else {
+				builder.WriteRune(r - escapeBase)
+			}
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// リンクを「テキスト (URL)」の形式にする。テキストがURLと同じ場合はURLだけにする。
+func linkText(label, url string) string {
+	label = strings.TrimSpace(label)
+	if label == "" || label == url {
+		return url
+	}
+	return label + " (" + url + ")"
+}
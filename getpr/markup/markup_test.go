@@ -0,0 +1,196 @@
+package markup
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToPlain(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+	}{
+		{"**重要** な *指摘* と ~~取り消し~~", "重要 な 指摘 と 取り消し"},
+		{"`foo_bar` を __修正__ してください", "foo_bar を 修正 してください"},
+		{"snake_case_name はそのまま", "snake_case_name はそのまま"},
+		{"## 見出し ##\r\n本文", "見出し\r\n本文"},
+		{"[ドキュメント](https://example.com/doc) を参照", "ドキュメント (https://example.com/doc) を参照"},
+		{"[https://example.com](https://example.com)", "https://example.com"},
+		{"![画面](https://example.com/a.png)", "画面 (https://example.com/a.png)"},
+		{"[Go](https://en.wikipedia.org/wiki/Go_(programming_language)) を参照", "Go (https://en.wikipedia.org/wiki/Go_(programming_language)) を参照"},
+		{"<https://example.com>", "https://example.com"},
+		{"```go\r\nfunc **main**() {}\r\n```\r\n後", "func **main**() {}\r\n後"},
+		{"* 1つめ\r\n+ 2つめ\r\n1. 番号", "- 1つめ\r\n- 2つめ\r\n1. 番号"},
+		{"> 引用の **強調**", "> 引用の 強調"},
+		{"<details>\r\n<summary>詳細</summary>\r\n\r\n中身\r\n</details>", "詳細\r\n\r\n中身"},
+		{"1行目<br>2行目 &amp; \\*記号\\*", "1行目\r\n2行目 & *記号*"},
+		{"指摘\r\n\r\n\r\n~~\r\n\r\n回答", "指摘\r\n\r\n~~\r\n\r\n回答"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := MarkdownToPlain(fixture.input)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMarkdownToHtml(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+	}{
+		{"**重要** な `<code>`\r\n2行目", "<p><strong>重要</strong> な <code>&lt;code&gt;</code><br>\r\n2行目</p>"},
+		{"[doc](https://example.com/?a=1&b=2)", `<p><a href="https://example.com/?a=1&amp;b=2">doc</a></p>`},
+		{"# 見出し\r\n- a\r\n- b", "<h1>見出し</h1>\r\n<ul><li>a</li><li>b</li></ul>"},
+		{"```go\r\nif a < b {}\r\n```", `<pre><code class="language-go">if a &lt; b {}</code></pre>`},
+		{"> 引用", "<blockquote><p>引用</p></blockquote>"},
+		{"\\<b\\> と \\*", "<p>&lt;b&gt; と *</p>"},
+		{"<details><summary>詳細</summary></details>", "<p>&lt;details&gt;&lt;summary&gt;詳細&lt;/summary&gt;&lt;/details&gt;</p>"},
+		{"<https://example.com/?a=1&b=2>", `<p><a href="https://example.com/?a=1&amp;b=2">https://example.com/?a=1&amp;b=2</a></p>`},
+		{"if a < b then see <https://example.com>", `<p>if a &lt; b then see <a href="https://example.com">https://example.com</a></p>`},
+		{"1行目<br>2行目 <script>alert(1)</script>", "<p>1行目<br>2行目 &lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"[危険](javascript:alert(1)) と ![画像](JavaScript:x) と [相対](docs/a.md)", `<p>危険 と 画像 と <a href="docs/a.md">相対</a></p>`},
+		{"[mail](mailto:a@example.com)", `<p><a href="mailto:a@example.com">mail</a></p>`},
+		{"[Go](https://en.wikipedia.org/wiki/Go_(programming_language)) を参照", `<p><a href="https://en.wikipedia.org/wiki/Go_(programming_language)">Go</a> を参照</p>`},
+		{"1. a\r\n2. b\r\n- c\r\n* d", "<ol><li>a</li><li>b</li></ol>\r\n<ul><li>c</li></ul>\r\n<ul><li>d</li></ul>"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := MarkdownToHtml(fixture.input)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHtmlToPlain(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+	}{
+		{"<p><strong>重要</strong> な <em>指摘</em></p>", "重要 な 指摘"},
+		{`<p><a href="https://example.com/doc">ドキュメント</a> を参照</p>`, "ドキュメント (https://example.com/doc) を参照"},
+		{`<p><img src="https://example.com/a.png" alt="画面"></p>`, "画面 (https://example.com/a.png)"},
+		{"<pre><code>func main() {\n\treturn\n}</code></pre>", "func main() {\r\n\treturn\r\n}"},
+		{"<ul><li>a</li><li>b</li></ul><ol><li>c</li><li>d</li></ol>", "- a\r\n- b\r\n1. c\r\n2. d"},
+		{"<blockquote><p>引用</p></blockquote><p>本文</p>", "> 引用\r\n\r\n本文"},
+		{"<p>指摘</p>\n<p>~~</p>\n<p>1行目<br>2行目</p>", "指摘\r\n\r\n~~\r\n\r\n1行目\r\n2行目"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := HtmlToPlain(fixture.input)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSplitHtml(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected []string
+	}{
+		{"<p>指摘</p>\n<p>~~</p>\n<p>回答</p>", []string{"<p>指摘</p>", "<p>回答</p>"}},
+		{"<p>指摘 ~~ 途中</p>", []string{"<p>指摘 ~~ 途中</p>"}},
+		{"<p>~~</p><p>回答</p>", []string{"", "<p>回答</p>"}},
+		{"<p>指摘<br>\n~~<br>\n回答</p>", []string{"<p>指摘</p>", "回答"}},
+		{"<p>指摘1<br>~~ <br>回答1<br>~~<br>指摘2</p>", []string{"<p>指摘1</p>", "回答1", "指摘2"}},
+		{"<p>指摘</p><pre><code>~~\n</code></pre>", []string{"<p>指摘</p><pre><code>~~\n</code></pre>"}},
+		{"<p>指摘</p><PRE><p>~~</p></PRE><p>~~</p><p>回答</p>", []string{"<p>指摘</p><pre><p>~~</p></pre>", "<p>回答</p>"}},
+	}
+	isDelimiter := func(line string) bool { return line == "~~" }
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := SplitHtml(fixture.input, isDelimiter)
+			if !reflect.DeepEqual(actual, fixture.expected) {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
+
+func TestStripQuotes(t *testing.T) {
+	expected := "<p>修正しました。</p>\n<div>対応済み</div>"
+	actual := StripQuotes("<blockquote><p>指摘</p></blockquote>\n<p>修正しました。</p>\n<div><blockquote>指摘</blockquote>対応済み</div>")
+	if actual != expected {
+		t.Errorf("期待値は %q ですが実際には %q でした", expected, actual)
+	}
+}
+
+func TestRenderSuggestions(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+	}{
+		{`<p>定数にしてください。</p><pre><code class="language-suggestion">const limit = 10</code></pre>`, "<p>定数にしてください。</p><p>提案: <code>const limit = 10</code></p>"},
+		{`<pre class="prettyprint lang-suggestion">a &lt; b
+c
+</pre>`, "<p>提案:</p><pre><code>a &lt; b\nc</code></pre>"},
+		{`<pre><code class="language-suggestion"></code></pre>`, "<p>提案: (削除)</p>"},
+		{`<pre><code class="language-go">x</code></pre>`, `<pre><code class="language-go">x</code></pre>`},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := RenderSuggestions(fixture.input)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
+
+func TestStripSignature(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+	}{
+		{"<p>取り込みました。</p>\r\n<p>--<br>\r\nauthor@example.com</p>", "<p>取り込みました。</p>"},
+		{"<p>取り込みました。</p><p>-- </p><p>山田</p>", "<p>取り込みました。</p>"},
+		{"<p>見出し<br>--<br>本文</p>", "<p>見出し<br>--<br>本文</p>"},
+		{"<p>修正しました。</p><p>--</p><ol><li>1</li><li>2</li><li>3</li><li>4</li><li>5</li><li>6</li></ol>", "<p>修正しました。</p><p>--</p><ol><li>1</li><li>2</li><li>3</li><li>4</li><li>5</li><li>6</li></ol>"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := StripSignature(fixture.input)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReplaceTexts(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+	}{
+		{"<p>[must] 本文</p>", "<p>本文</p>"},
+		{"<p><strong>[must]</strong> 本文</p><p>種別: 設計</p>", "<p> 本文</p>"},
+		{"<p>変更しない</p>\n<p>本文</p>", "<p>変更しない</p>\n<p>本文</p>"},
+		{"<p>[must]<br>本文<br>\n種別: 設計</p>", "<p>本文</p>"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := ReplaceTexts(fixture.input, func(texts []string) []string {
+				for i, text := range texts {
+					switch {
+					case text == "[must]" || text == "種別: 設計":
+						texts[i] = ""
+					case strings.HasPrefix(text, "[must] "):
+						texts[i] = strings.TrimPrefix(text, "[must] ")
+					}
+				}
+				return texts
+			})
+			if actual != fixture.expected {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// 抽出したレビュー情報から、品質報告に使用するレビューの指標を算出するパッケージ。
+package metrics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+)
+
+// レビューの指標。算出できない指標はnil。
+type Metrics struct {
+	// プルリクエストのURL。複数のプルリクエストを集計した場合は空文字列。
+	Url string
+	// 指摘件数
+	Findings int
+	// 追加行数
+	Additions int
+	// 削除行数
+	Deletions int
+	// 全てのレビュー回のレビュー時間（分）の合計
+	ReviewMinutes int
+	// 変更行数（追加行数と削除行数の合計）1000行あたりの指摘件数
+	FindingsPerKloc *float64
+	// レビュー時間1時間あたりの指摘件数
+	FindingsPerHour *float64
+	// 指摘からレビュイーが最初に回答するまでの時間（分）の中央値
+	MedianFirstResponseMinutes *float64
+	// 解決状態を取得できる指摘のうち、解決済みの指摘の割合（%）
+	ResolvedRate *float64
+	// レビュアーごとの指摘件数
+	FindingsByReviewer map[string]int
+
+	// 中央値と割合を集計し直すための値
+	firstResponses []time.Duration
+	resolved       int
+	hasResolved    int
+}
+
+// プルリクエストのレビュー情報から指標を算出する。
+//
+// CSVの行と件数が一致するよう、CSVに書き出すスレッド（review.Thread.IsFinding）を指摘とみなす。
+// レビュイーが始めたスレッドも指摘に含め、レビュアーごとの件数はCSVのレビュアー列と同じユーザーに計上する。
+// レビュイーのコメントしかない指摘はレビュアーごとの件数には含めない。
+func Compute(pr *review.PullRequest) *Metrics {
+	metrics := &Metrics{
+		Url:                pr.Url,
+		Additions:          pr.DiffStats.Additions,
+		Deletions:          pr.DiffStats.Deletions,
+		FindingsByReviewer: map[string]int{},
+	}
+	for _, round := range pr.Rounds {
+		minutes, _ := strconv.Atoi(round.ReviewTime.ReviewMinutes)
+		metrics.ReviewMinutes += minutes
+	}
+	for _, thread := range pr.Threads {
+		if !thread.IsFinding() {
+			continue
+		}
+		metrics.Findings++
+		if reviewer := thread.Reviewer(); len(reviewer.Login) != 0 {
+			metrics.FindingsByReviewer[reviewer.Label()]++
+		}
+		if thread.HasResolvedStatus {
+			metrics.hasResolved++
+			if thread.Resolved {
+				metrics.resolved++
+			}
+		}
+		if response, ok := firstResponse(thread); ok {
+			metrics.firstResponses = append(metrics.firstResponses, response)
+		}
+	}
+	metrics.derive()
+	return metrics
+}
+
+// 複数のプルリクエストの指標を合算する。中央値と割合は全ての指摘から算出し直す。
+func Aggregate(all []*Metrics) *Metrics {
+	total := &Metrics{FindingsByReviewer: map[string]int{}}
+	for _, metrics := range all {
+		total.Findings += metrics.Findings
+		total.Additions += metrics.Additions
+		total.Deletions += metrics.Deletions
+		total.ReviewMinutes += metrics.ReviewMinutes
+		for reviewer, findings := range metrics.FindingsByReviewer {
+			total.FindingsByReviewer[reviewer] += findings
+		}
+		total.firstResponses = append(total.firstResponses, metrics.firstResponses...)
+		total.resolved += metrics.resolved
+		total.hasResolved += metrics.hasResolved
+	}
+	total.derive()
+	return total
+}
+
+// 件数から比率や中央値を算出する。
+func (metrics *Metrics) derive() {
+	if lines := metrics.Additions + metrics.Deletions; lines > 0 {
+		metrics.FindingsPerKloc = ratio(metrics.Findings*1000, lines)
+	}
+	if metrics.ReviewMinutes > 0 {
+		metrics.FindingsPerHour = ratio(metrics.Findings*60, metrics.ReviewMinutes)
+	}
+	if metrics.hasResolved > 0 {
+		metrics.ResolvedRate = ratio(metrics.resolved*100, metrics.hasResolved)
+	}
+	if len(metrics.firstResponses) > 0 {
+		metrics.MedianFirstResponseMinutes = median(metrics.firstResponses)
+	}
+}
+
+// スレッドの作成からレビュイーが最初に回答するまでの時間を返す。
+// 通常のコメントはデリミタの後に回答を書くため、回答日時が分からず対象外とする。
+// レビュイーが始めたスレッドは回答を待つ指摘ではないため対象外とする。
+func firstResponse(thread review.Thread) (time.Duration, bool) {
+	createdAt := thread.CreatedAt()
+	if !thread.Threaded || createdAt.IsZero() || thread.Comments[0].Author.Reviewee {
+		return 0, false
+	}
+	for _, comment := range thread.Comments[1:] {
+		if comment.Author.Reviewee && !comment.CreatedAt.IsZero() {
+			return comment.CreatedAt.Sub(createdAt), true
+		}
+	}
+	return 0, false
+}
+
+func ratio(numerator, denominator int) *float64 {
+	value := float64(numerator) / float64(denominator)
+	return &value
+}
+
+// 時間の中央値を分で返す。
+func median(durations []time.Duration) *float64 {
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	middle := len(sorted) / 2
+	value := sorted[middle].Minutes()
+	if len(sorted)%2 == 0 {
+		value = (sorted[middle-1].Minutes() + sorted[middle].Minutes()) / 2
+	}
+	return &value
+}
+
+// レビュアーごとの指摘件数を「ユーザー名:件数」の形式にしてユーザー名の順にセミコロンで繋ぐ。
+func (metrics *Metrics) JoinFindingsByReviewer() string {
+	reviewers := make([]string, 0, len(metrics.FindingsByReviewer))
+	for reviewer := range metrics.FindingsByReviewer {
+		reviewers = append(reviewers, reviewer)
+	}
+	sort.Strings(reviewers)
+	values := make([]string, 0, len(reviewers))
+	for _, reviewer := range reviewers {
+		values = append(values, reviewer+":"+strconv.Itoa(metrics.FindingsByReviewer[reviewer]))
+	}
+	return strings.Join(values, ";")
+}
+
+// 解決状態を取得できる指摘のうち、解決済みと未解決の指摘件数を返す。
+func (metrics *Metrics) Resolution() (int, int) {
+	return metrics.resolved, metrics.hasResolved - metrics.resolved
+}
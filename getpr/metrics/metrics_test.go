@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2024, 5, 1, hour, minute, 0, 0, time.UTC)
+}
+
+func newPullRequest(url string, additions int, minutes string, threads ...review.Thread) *review.PullRequest {
+	return &review.PullRequest{
+		Url:       url,
+		DiffStats: review.DiffStats{Additions: additions, Deletions: 100},
+		Threads:   threads,
+		Rounds:    []review.ReviewRound{{ReviewTimes: "1", ReviewTime: rvtime.ReviewTime{ReviewMinutes: minutes}}},
+	}
+}
+
+var (
+	alice  = review.Participant{Login: "alice"}
+	bob    = review.Participant{Login: "bob"}
+	author = review.Participant{Login: "author", Reviewee: true}
+)
+
+func TestCompute(t *testing.T) {
+	pr := newPullRequest("u1", 300, "30",
+		review.Thread{Comments: []review.Comment{{Author: alice, Body: "レビュー1回目\n10:00-10:30", CreatedAt: at(10, 0)}}},
+		review.Thread{Threaded: true, HasResolvedStatus: true, Resolved: true, Comments: []review.Comment{
+			{Author: alice, Body: "指摘1", CreatedAt: at(10, 0)},
+			{Author: author, Body: "対応1", CreatedAt: at(10, 30)},
+		}},
+		review.Thread{Threaded: true, HasResolvedStatus: true, Comments: []review.Comment{
+			{Author: bob, Body: "指摘2", CreatedAt: at(10, 0)},
+			{Author: author, Body: "対応2", CreatedAt: at(11, 30)},
+		}},
+		review.Thread{Comments: []review.Comment{{Author: alice, Body: "指摘3", CreatedAt: at(10, 10)}}},
+		// レビュイーが始めたスレッドもCSVと同じく指摘に含め、レビュアーの件数は返信したレビュアーに計上する
+		review.Thread{Threaded: true, Comments: []review.Comment{
+			{Author: author, Body: "補足", CreatedAt: at(9, 0)},
+			{Author: bob, Body: "確認しました", CreatedAt: at(9, 10)},
+		}},
+		// レビュイーのコメントしかない指摘はレビュアーごとの件数に含めない
+		review.Thread{Threaded: true, Comments: []review.Comment{{Author: author, Body: "メモ", CreatedAt: at(9, 0)}}},
+		// 本文のない通常のコメントはCSVに書き出さないため指摘に含めない
+		review.Thread{Comments: []review.Comment{{Author: alice, CreatedAt: at(9, 0)}}},
+	)
+	metrics := Compute(pr)
+
+	if metrics.Findings != 5 || metrics.JoinFindingsByReviewer() != "alice:2;bob:2" {
+		t.Errorf("指摘件数が期待通りではありません。実際の値：%v %v", metrics.Findings, metrics.JoinFindingsByReviewer())
+	}
+	expected := map[string]float64{"FindingsPerKloc": 12.5, "FindingsPerHour": 10, "MedianFirstResponseMinutes": 60, "ResolvedRate": 50}
+	actual := map[string]*float64{
+		"FindingsPerKloc":            metrics.FindingsPerKloc,
+		"FindingsPerHour":            metrics.FindingsPerHour,
+		"MedianFirstResponseMinutes": metrics.MedianFirstResponseMinutes,
+		"ResolvedRate":               metrics.ResolvedRate,
+	}
+	for name, value := range actual {
+		if value == nil || *value != expected[name] {
+			t.Errorf("%vが期待通りではありません。期待値：%v 実際の値：%v", name, expected[name], value)
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	first := Compute(newPullRequest("u1", 100, "60",
+		review.Thread{Threaded: true, Comments: []review.Comment{
+			{Author: alice, Body: "指摘1", CreatedAt: at(10, 0)},
+			{Author: author, Body: "対応1", CreatedAt: at(10, 10)},
+		}},
+	))
+	second := Compute(newPullRequest("u2", 0, "",
+		review.Thread{Threaded: true, Comments: []review.Comment{
+			{Author: bob, Body: "指摘2", CreatedAt: at(10, 0)},
+			{Author: author, Body: "対応2", CreatedAt: at(10, 50)},
+		}},
+	))
+	if second.FindingsPerHour != nil || second.ResolvedRate != nil {
+		t.Errorf("レビュー時間や解決状態がなければ算出しない想定です。実際の値：%v %v", second.FindingsPerHour, second.ResolvedRate)
+	}
+
+	total := Aggregate([]*Metrics{first, second})
+	if total.Findings != 2 || total.Deletions != 200 || total.ReviewMinutes != 60 {
+		t.Errorf("合計が期待通りではありません。実際の値：%+v", total)
+	}
+	// 中央値は全ての指摘から算出し直す
+	if total.MedianFirstResponseMinutes == nil || *total.MedianFirstResponseMinutes != 30 {
+		t.Errorf("回答までの時間の中央値が期待通りではありません。実際の値：%v", total.MedianFirstResponseMinutes)
+	}
+}
@@ -0,0 +1,118 @@
+package review
+
+import (
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
+)
+
+// レビュー回の読み取りの設定。各項目の意味は同名のコマンドライン引数と同じ。
+type AnalyzeOptions struct {
+	// 扱うレビュー回数。AllReviewTimesがtrueの場合は、このレビュー回数までのレビュー日時コメントのないレビュー回を推定する。
+	ReviewTimes string
+	// 全てのレビュー回を扱う場合はtrue
+	AllReviewTimes bool
+	// レビュー日時コメントが見つからない場合にレビュー時間を推定する場合はtrue
+	EstimateReviewTime bool
+	// レビュー時間を推定する際、同じレビューとみなすコメントの投稿間隔の上限
+	IdleGap time.Duration
+	// 日時を扱う際のタイムゾーン
+	Location *time.Location
+}
+
+// レビュー日時コメントからレビュー回を読み取り、各スレッドをレビュー回へ割り当てる。
+//
+// all-review-timesが指定されていれば全てのレビュー回を、指定されていなければreview-timesのレビュー回だけを扱う。
+// 全てのレビュー回を扱う場合、スレッドは作成日時からレビュー回へ割り当て、作成日時が取得できない場合は直前のレビュー日時コメントのレビュー回に割り当てる。
+// レビュー日時コメントが見つからない場合やコメントに不備がある場合は警告メッセージを追加する。
+func Analyze(pr *PullRequest, options AnalyzeOptions) {
+	loc := options.Location
+	rounds := make(rvtime.Rounds, 0)
+	// 作成日時が取得できないスレッドのために、直前のレビュー日時コメントのレビュー回を覚えておく
+	var currentReviewTimes string
+	for i := range pr.Threads {
+		thread := &pr.Threads[i]
+		if !thread.IsReviewTime() {
+			if thread.CreatedAt().IsZero() {
+				thread.ReviewTimes = currentReviewTimes
+			}
+			continue
+		}
+		// レビュー日時のスレッドに返信する形で書かれる場合も想定し、全てのコメントを確認する
+		for _, comment := range thread.Comments {
+			if round, ok := rvtime.ParseRound(comment.Body, comment.CreatedAt); ok {
+				rounds.Add(round)
+				currentReviewTimes = round.ReviewTimes
+			}
+		}
+	}
+
+	if options.EstimateReviewTime {
+		// 全てのレビュー回を扱う場合は、レビュー日時コメントのない途中のレビュー回も推定する
+		missing := []string{options.ReviewTimes}
+		if options.AllReviewTimes {
+			missing = rounds.Missing(options.ReviewTimes)
+		}
+		activities := pr.Activities()
+		for _, reviewTimes := range missing {
+			rounds.Estimate(reviewTimes, activities, options.IdleGap, loc)
+		}
+	}
+
+	pr.Rounds = make([]ReviewRound, 0)
+	if options.AllReviewTimes {
+		rounds.Sort()
+		for _, round := range rounds {
+			pr.Warnings = append(pr.Warnings, round.Warnings...)
+			pr.Rounds = append(pr.Rounds, newReviewRound(round))
+		}
+		for i, thread := range pr.Threads {
+			if thread.ReviewTimes == "" {
+				pr.Threads[i].ReviewTimes = rounds.Find(thread.CreatedAt(), loc)
+			}
+		}
+		return
+	}
+
+	if round, ok := rounds.Get(options.ReviewTimes); ok {
+		pr.Warnings = append(pr.Warnings, round.Warnings...)
+		pr.Rounds = append(pr.Rounds, newReviewRound(round))
+	} else {
+		pr.Warnings = append(pr.Warnings, rvtime.NotFoundWarning(options.ReviewTimes))
+	}
+	for i := range pr.Threads {
+		pr.Threads[i].ReviewTimes = options.ReviewTimes
+	}
+}
+
+// レビュー日時コメントを除き、レビュアー（レビュイー以外）がコメントを投稿した日時を返す。
+func (pr *PullRequest) Activities() []rvtime.Activity {
+	activities := make([]rvtime.Activity, 0)
+	for _, thread := range pr.Threads {
+		for _, comment := range thread.Comments {
+			if !comment.Author.Reviewee && !rvtime.IsReviewTimeComment(comment.Body) {
+				activities = append(activities, rvtime.Activity{Reviewer: comment.Author.Login, At: comment.CreatedAt})
+			}
+		}
+	}
+	return activities
+}
+
+// 指定されたレビュー回数のレビュー回を返す。見つからなければfalseを返す。
+func (pr *PullRequest) Round(reviewTimes string) (ReviewRound, bool) {
+	for _, round := range pr.Rounds {
+		if round.ReviewTimes == reviewTimes {
+			return round, true
+		}
+	}
+	return ReviewRound{}, false
+}
+
+func newReviewRound(round rvtime.Round) ReviewRound {
+	return ReviewRound{
+		ReviewTimes: round.ReviewTimes,
+		ReviewTime:  round.ReviewTime,
+		Sessions:    round.Sessions,
+		Estimated:   round.Estimated,
+	}
+}
@@ -0,0 +1,128 @@
+package review
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
+)
+
+func TestAnalyze(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 1, hour, minute, 0, 0, loc)
+	}
+	newPullRequest := func() *PullRequest {
+		return &PullRequest{
+			Threads: []Thread{
+				{Threaded: true, Comments: []Comment{
+					{Author: Participant{Login: "alice"}, Body: "指摘", CreatedAt: at(10, 0)},
+					{Author: Participant{Login: "author", Reviewee: true}, Body: "対応", CreatedAt: at(10, 5)},
+					{Author: Participant{Login: "alice"}, Body: "確認しました", CreatedAt: at(10, 20)},
+				}},
+				{Threaded: true, Comments: []Comment{
+					{Author: Participant{Login: "alice"}, Body: "レビュー2回目\n2024/5/1\n13:00-13:30", CreatedAt: at(13, 30)},
+				}},
+			},
+		}
+	}
+
+	t.Run("指定したレビュー回", func(t *testing.T) {
+		pr := newPullRequest()
+		Analyze(pr, AnalyzeOptions{ReviewTimes: "2", Location: loc})
+		if len(pr.Rounds) != 1 || pr.Rounds[0].ReviewTime.ReviewMinutes != "30" {
+			t.Errorf("レビュー回が期待通りではありません。実際の値：%v", pr.Rounds)
+		}
+		for _, thread := range pr.Threads {
+			if thread.ReviewTimes != "2" {
+				t.Errorf("全てのスレッドが指定したレビュー回に割り当てられていません。実際の値：%v", thread.ReviewTimes)
+			}
+		}
+	})
+
+	t.Run("レビュー日時コメントが見つからない", func(t *testing.T) {
+		pr := newPullRequest()
+		Analyze(pr, AnalyzeOptions{ReviewTimes: "1", Location: loc})
+		expected := []string{"レビュー1回目のレビュー日時コメントが見つかりません。"}
+		if len(pr.Rounds) != 0 || !reflect.DeepEqual(pr.Warnings, expected) {
+			t.Errorf("警告が期待通りではありません。期待値：%v 実際の値：%v", expected, pr.Warnings)
+		}
+	})
+
+	t.Run("レビュー時間の推定", func(t *testing.T) {
+		pr := newPullRequest()
+		Analyze(pr, AnalyzeOptions{ReviewTimes: "1", EstimateReviewTime: true, IdleGap: 30 * time.Minute, Location: loc})
+		round, ok := pr.Round("1")
+		// レビュイーのコメントとレビュー日時コメントは活動に含めない
+		if !ok || !round.Estimated || round.ReviewTime.ReviewStartTime != "10:00" || round.ReviewTime.ReviewEndTime != "10:20" {
+			t.Errorf("推定したレビュー回が期待通りではありません。実際の値：%v", round)
+		}
+	})
+
+	t.Run("指定したレビュー回の推定に前のレビュー回の活動を含めない", func(t *testing.T) {
+		pr := &PullRequest{Threads: []Thread{
+			{Threaded: true, Comments: []Comment{
+				{Author: Participant{Login: "alice"}, Body: "指摘", CreatedAt: at(10, 0)},
+			}},
+			{Threaded: true, Comments: []Comment{
+				{Author: Participant{Login: "alice"}, Body: "レビュー1回目\n2024/5/1\n9:30-10:30", CreatedAt: at(10, 30)},
+			}},
+			{Threaded: true, Comments: []Comment{
+				{Author: Participant{Login: "bob"}, Body: "再レビューの指摘", CreatedAt: at(15, 0)},
+			}},
+		}}
+		Analyze(pr, AnalyzeOptions{ReviewTimes: "2", EstimateReviewTime: true, IdleGap: 30 * time.Minute, Location: loc})
+		round, ok := pr.Round("2")
+		// 活動が1件だけのレビュー実施記録は最短の長さを計上する
+		if !ok || !round.Estimated || round.ReviewTime != (rvtime.ReviewTime{ReviewDate: "2024/5/1", ReviewStartTime: "14:55", ReviewEndTime: "15:00", ReviewMinutes: "5"}) {
+			t.Errorf("推定したレビュー回が期待通りではありません。実際の値：%v", round)
+		}
+	})
+
+	t.Run("全てのレビュー回で途中のレビュー回を推定", func(t *testing.T) {
+		pr := &PullRequest{Threads: []Thread{
+			{Threaded: true, Comments: []Comment{
+				{Author: Participant{Login: "alice"}, Body: "レビュー1回目\n2024/5/1\n9:30-10:30", CreatedAt: at(10, 30)},
+			}},
+			{Threaded: true, Comments: []Comment{
+				{Author: Participant{Login: "alice"}, Body: "2回目の指摘", CreatedAt: at(12, 45)},
+				{Author: Participant{Login: "alice"}, Body: "2回目の追記", CreatedAt: at(13, 0)},
+			}},
+			{Threaded: true, Comments: []Comment{
+				{Author: Participant{Login: "bob"}, Body: "3回目の指摘", CreatedAt: at(15, 0)},
+			}},
+			{Threaded: true, Comments: []Comment{
+				{Author: Participant{Login: "bob"}, Body: "レビュー3回目\n2024/5/1\n14:30-15:30", CreatedAt: at(15, 30)},
+			}},
+		}}
+		Analyze(pr, AnalyzeOptions{ReviewTimes: "1", AllReviewTimes: true, EstimateReviewTime: true, IdleGap: 30 * time.Minute, Location: loc})
+		if len(pr.Rounds) != 3 {
+			t.Fatalf("レビュー回が期待通りではありません。実際の値：%v", pr.Rounds)
+		}
+		round := pr.Rounds[1]
+		if round.ReviewTimes != "2" || !round.Estimated || round.ReviewTime != (rvtime.ReviewTime{ReviewDate: "2024/5/1", ReviewStartTime: "12:45", ReviewEndTime: "13:00", ReviewMinutes: "15"}) {
+			t.Errorf("推定したレビュー回が期待通りではありません。実際の値：%v", round)
+		}
+		if pr.Threads[1].ReviewTimes != "2" {
+			t.Errorf("推定したレビュー回にスレッドが割り当てられていません。実際の値：%v", pr.Threads[1].ReviewTimes)
+		}
+	})
+}
+
+func TestThreadUpdatedAt(t *testing.T) {
+	resolvedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	thread := Thread{
+		ResolvedAt: &resolvedAt,
+		Comments: []Comment{
+			{CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)},
+			{CreatedAt: time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)},
+		},
+	}
+	if actual := thread.CreatedAt(); !actual.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("作成日時が期待通りではありません。実際の値：%v", actual)
+	}
+	if actual := thread.UpdatedAt(); !actual.Equal(time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("更新日時が期待通りではありません。実際の値：%v", actual)
+	}
+}
@@ -0,0 +1,42 @@
+package review
+
+import (
+	"github.com/Fintan-contents/review-support-tool/getpr/markup"
+	"github.com/Fintan-contents/review-support-tool/getpr/tag"
+)
+
+// コメントのタグから指摘の重要度と種別を読み取り、コメントに設定する。
+// stripがtrueの場合は本文から読み取ったタグを取り除く。レビュー日時コメントのスレッドは対象外。
+// GitBucketのようにHTMLを取得できる場合はHTMLのテキストノードを行として読み取り、タグを取り除いたHTMLから本文を取り出し直す。
+func Classify(pr *PullRequest, rules *tag.Rules, strip bool) {
+	for i := range pr.Threads {
+		thread := &pr.Threads[i]
+		if thread.IsReviewTime() {
+			continue
+		}
+		for j := range thread.Comments {
+			comment := &thread.Comments[j]
+			if len(comment.Html) != 0 {
+				var t tag.Tag
+				html := markup.ReplaceTexts(comment.Html, func(texts []string) []string {
+					var lines []string
+					t, lines = rules.ExtractLines(texts)
+					return lines
+				})
+				comment.Severity = t.Severity
+				comment.Category = t.Category
+				if strip {
+					comment.Html = html
+					comment.Body = markup.ScrapeText(html)
+				}
+				continue
+			}
+			t, body := rules.Extract(comment.Body)
+			comment.Severity = t.Severity
+			comment.Category = t.Category
+			if strip {
+				comment.Body = body
+			}
+		}
+	}
+}
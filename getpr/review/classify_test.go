@@ -0,0 +1,34 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/tag"
+)
+
+func TestClassify(t *testing.T) {
+	pr := &PullRequest{
+		Threads: []Thread{
+			{Threaded: true, Comments: []Comment{
+				{Author: Participant{Login: "alice"}, Body: "[質問] なぜですか。"},
+				{Author: Participant{Login: "author", Reviewee: true}, Body: "[must] 理由を書きました。"},
+				{Author: Participant{Login: "alice"}, Body: "[nits] 誤字があります。"},
+			}},
+			{Comments: []Comment{
+				{Author: Participant{Login: "alice"}, Body: "レビュー1回目\n[must]"},
+			}},
+		},
+	}
+	Classify(pr, tag.DefaultRules(), true)
+
+	// レビュイーのコメントのタグは指摘の重要度に使わない
+	if severity, category := pr.Threads[0].Tag(); severity != "nits" || category != "質問" {
+		t.Errorf("指摘の重要度と種別が期待通りではありません。実際の値：%v/%v", severity, category)
+	}
+	if body := pr.Threads[0].Comments[0].Body; body != "なぜですか。" {
+		t.Errorf("タグを取り除く想定です。実際の値：%v", body)
+	}
+	if body := pr.Threads[1].Comments[0].Body; body != "レビュー1回目\n[must]" {
+		t.Errorf("レビュー日時コメントは変更しない想定です。実際の値：%v", body)
+	}
+}
@@ -0,0 +1,194 @@
+package review
+
+import (
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/reviewee"
+	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
+)
+
+// Gitホスティングサービスに依存しないプルリクエスト（マージリクエスト）のレビュー情報。
+// CSVやJSONといった出力はこの構造体から組み立てる。
+type PullRequest struct {
+	// プルリクエストのURL
+	Url string `json:"url"`
+	// プルリクエストの作成者
+	Author Participant `json:"author"`
+	// 差分の行数
+	DiffStats DiffStats `json:"diffStats"`
+	// スレッドの一覧。通常のコメントも1件のコメントからなるスレッドとして扱う。
+	Threads []Thread `json:"threads"`
+	// レビュー回の一覧。Analyzeで設定される。
+	Rounds []ReviewRound `json:"rounds"`
+	// 抽出時の警告メッセージ。Analyzeで設定される。
+	Warnings []string `json:"warnings"`
+}
+
+// 差分の行数
+type DiffStats struct {
+	// 追加行数。GitBucketは常に0。
+	Additions int `json:"additions"`
+	// 削除行数。GitBucketは常に0。
+	Deletions int `json:"deletions"`
+}
+
+// コメントの投稿者
+type Participant struct {
+	// ユーザー名
+	Login string `json:"login"`
+	// 表示名。取得できない場合は空文字列。
+	Name string `json:"name,omitempty"`
+	// レビュイーとみなす場合はtrue
+	Reviewee bool `json:"reviewee"`
+	// CSVファイルへ書き出す名前。ResolveUsersで設定される。
+	DisplayName string `json:"displayName,omitempty"`
+	// 所属する組織や部署。ResolveUsersで設定される。
+	Department string `json:"department,omitempty"`
+}
+
+// CSVファイルへ書き出す名前を返す。ResolveUsersで名前を設定していなければユーザー名を返す。
+func (participant Participant) Label() string {
+	if len(participant.DisplayName) != 0 {
+		return participant.DisplayName
+	}
+	return participant.Login
+}
+
+// ユーザー名と表示名から投稿者を構築する。ユーザー名がレビュイーに含まれているか、
+// 表示名がレビュイーとみなすコミット作成者の名前に含まれていればレビュイーとみなす。
+func NewParticipant(login string, name string, reviewees *reviewee.Reviewees) Participant {
+	return Participant{
+		Login:    login,
+		Name:     name,
+		Reviewee: reviewees.Contains(login) || reviewees.ContainsAuthorName(name),
+	}
+}
+
+// スレッド。スレッド形式でない通常のコメントは1件のコメントからなるスレッドとして扱う。
+type Thread struct {
+	// Gitホスティングサービスでのスレッドの識別子
+	Id string `json:"id"`
+	// 指摘のURL
+	Url string `json:"url"`
+	// スレッド形式の場合はtrue。falseの場合はコメント本文をデリミタで指摘事項と対応内容に分ける。
+	Threaded bool `json:"threaded"`
+	// APIで取得できる指摘の解決状態
+	Resolved bool `json:"resolved"`
+	// APIで指摘の解決状態を取得できる場合はtrue
+	HasResolvedStatus bool `json:"hasResolvedStatus"`
+	// 解決日時。未解決または取得できない場合はnil。
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	// 指摘が属するレビュー回数。Analyzeで設定される。
+	ReviewTimes string `json:"reviewTimes"`
+	// コメントの一覧。投稿順に並べる。
+	Comments []Comment `json:"comments"`
+	// 前回の抽出からの変更種別。since-stateを指定した場合のみstate.Markで設定される。
+	Change string `json:"change,omitempty"`
+}
+
+// 先頭のコメントがレビュー日時コメントであればtrueを返す。
+func (thread *Thread) IsReviewTime() bool {
+	return len(thread.Comments) > 0 && rvtime.IsReviewTimeComment(thread.Comments[0].Body)
+}
+
+// CSVに指摘として書き出すスレッドであればtrueを返す。
+// レビュー日時コメントを除き、スレッド形式はコメントがあるもの、通常のコメントは本文があるものを指摘とみなす。
+func (thread *Thread) IsFinding() bool {
+	if len(thread.Comments) == 0 || thread.IsReviewTime() {
+		return false
+	}
+	return thread.Threaded || len(thread.Comments[0].Body) > 0
+}
+
+// 指摘のレビュアーを返す。
+// スレッド形式はレビュイー以外で最初に本文のあるコメントを投稿したユーザー、通常のコメントは投稿したユーザー。
+// レビュイーのコメントしかなければゼロ値を返す。
+func (thread *Thread) Reviewer() Participant {
+	if !thread.Threaded {
+		if len(thread.Comments) == 0 {
+			return Participant{}
+		}
+		return thread.Comments[0].Author
+	}
+	for _, comment := range thread.Comments {
+		if len(comment.Body) > 0 && !comment.Author.Reviewee {
+			return comment.Author
+		}
+	}
+	return Participant{}
+}
+
+// スレッドの作成日時として先頭のコメントの作成日時を返す。取得できない場合はゼロ値を返す。
+func (thread *Thread) CreatedAt() time.Time {
+	if len(thread.Comments) == 0 {
+		return time.Time{}
+	}
+	return thread.Comments[0].CreatedAt
+}
+
+// スレッドの指摘の重要度と種別として、レビュアーのコメントのうち最初に読み取れたものをそれぞれ返す。
+func (thread *Thread) Tag() (string, string) {
+	var severity, category string
+	for _, comment := range thread.Comments {
+		if comment.Author.Reviewee {
+			continue
+		}
+		if severity == "" {
+			severity = comment.Severity
+		}
+		if category == "" {
+			category = comment.Category
+		}
+	}
+	return severity, category
+}
+
+// スレッドの更新日時として、コメントの作成・更新日時と解決日時のうち最も新しい日時を返す。
+func (thread *Thread) UpdatedAt() time.Time {
+	var updatedAt time.Time
+	if thread.ResolvedAt != nil {
+		updatedAt = *thread.ResolvedAt
+	}
+	for _, comment := range thread.Comments {
+		for _, t := range []time.Time{comment.CreatedAt, comment.UpdatedAt} {
+			if t.After(updatedAt) {
+				updatedAt = t
+			}
+		}
+	}
+	return updatedAt
+}
+
+// コメント
+type Comment struct {
+	// Gitホスティングサービスでのコメントの識別子
+	Id string `json:"id"`
+	// コメントのURL。取得できない場合は空文字列。
+	Url string `json:"url"`
+	// 投稿者
+	Author Participant `json:"author"`
+	// 本文。GitHubとGitLabはAPIで取得したマークダウン、GitBucketはHTMLをパースして得たテキスト。
+	Body string `json:"body"`
+	// HTMLで取得できる場合の本文。GitBucketのみ設定される。strip-tagsを指定した場合はタグを取り除く。
+	Html string `json:"html,omitempty"`
+	// 作成日時。取得できない場合はゼロ値。
+	CreatedAt time.Time `json:"createdAt"`
+	// 更新日時。取得できない場合はゼロ値。
+	UpdatedAt time.Time `json:"updatedAt"`
+	// タグから読み取った指摘の重要度。Classifyで設定される。
+	Severity string `json:"severity,omitempty"`
+	// タグから読み取った指摘の種別。Classifyで設定される。
+	Category string `json:"category,omitempty"`
+}
+
+// レビュー回ごとのレビュー日時情報
+type ReviewRound struct {
+	// レビュー回数
+	ReviewTimes string `json:"reviewTimes"`
+	// レビュー日時情報。複数のレビュー実施記録がある場合はそれらをまとめたもの。
+	ReviewTime rvtime.ReviewTime `json:"reviewTime"`
+	// レビュー実施記録の一覧
+	Sessions []rvtime.Session `json:"sessions"`
+	// レビュー日時コメントではなく活動日時から推定した場合はtrue
+	Estimated bool `json:"estimated"`
+}
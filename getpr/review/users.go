@@ -0,0 +1,45 @@
+package review
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/directory"
+)
+
+// プルリクエスト作成者とコメントの投稿者に、ユーザーディレクトリから氏名と所属を設定する。
+// ユーザーディレクトリに登録されていないユーザーは、useHostNamesがtrueであればGitホスティングサービスの表示名を設定し、警告に挙げる。
+// ユーザーディレクトリがnilの場合は、useHostNamesがtrueであれば表示名を設定するだけで警告は出さない。
+func ResolveUsers(pr *PullRequest, users *directory.Directory, useHostNames bool) {
+	missing := make(map[string]bool)
+	resolve := func(participant *Participant) {
+		if len(participant.Login) == 0 {
+			return
+		}
+		if users != nil {
+			if user, ok := users.Lookup(participant.Login); ok {
+				participant.DisplayName = user.Name
+				participant.Department = user.Department
+				return
+			}
+			missing[participant.Login] = true
+		}
+		if useHostNames {
+			participant.DisplayName = participant.Name
+		}
+	}
+	resolve(&pr.Author)
+	for i := range pr.Threads {
+		for j := range pr.Threads[i].Comments {
+			resolve(&pr.Threads[i].Comments[j].Author)
+		}
+	}
+	if len(missing) > 0 {
+		logins := make([]string, 0, len(missing))
+		for login := range missing {
+			logins = append(logins, login)
+		}
+		sort.Strings(logins)
+		pr.Warnings = append(pr.Warnings, "ユーザーディレクトリに登録されていないユーザーがいます。"+strings.Join(logins, ", "))
+	}
+}
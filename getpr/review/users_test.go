@@ -0,0 +1,59 @@
+package review
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/directory"
+)
+
+func TestResolveUsers(t *testing.T) {
+	users, err := directory.Read(strings.NewReader("tnakamura-xyz,中村 太郎,開発部\nsuzuki,鈴木 花子\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPullRequest := func() *PullRequest {
+		return &PullRequest{
+			Author: Participant{Login: "tnakamura-xyz", Name: "Taro Nakamura", Reviewee: true},
+			Threads: []Thread{{Comments: []Comment{
+				{Author: Participant{Login: "suzuki", Name: "Hanako Suzuki"}},
+				{Author: Participant{Login: "guest", Name: "Guest User"}},
+				{Author: Participant{Login: "bot"}},
+				{Author: Participant{Login: "guest", Name: "Guest User"}},
+			}}},
+		}
+	}
+	fixtures := []struct {
+		name         string
+		users        *directory.Directory
+		useHostNames bool
+		expected     []string
+		warnings     []string
+	}{
+		{"ユーザーディレクトリ", users, false, []string{"中村 太郎", "鈴木 花子", "guest", "bot", "guest"}, []string{"ユーザーディレクトリに登録されていないユーザーがいます。bot, guest"}},
+		{"表示名で補う", users, true, []string{"中村 太郎", "鈴木 花子", "Guest User", "bot", "Guest User"}, []string{"ユーザーディレクトリに登録されていないユーザーがいます。bot, guest"}},
+		{"表示名のみ", nil, true, []string{"Taro Nakamura", "Hanako Suzuki", "Guest User", "bot", "Guest User"}, nil},
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			pr := newPullRequest()
+			ResolveUsers(pr, fixture.users, fixture.useHostNames)
+			actual := []string{pr.Author.Label()}
+			for _, comment := range pr.Threads[0].Comments {
+				actual = append(actual, comment.Author.Label())
+			}
+			if !reflect.DeepEqual(actual, fixture.expected) {
+				t.Errorf("期待値は %v ですが実際には %v でした", fixture.expected, actual)
+			}
+			if !reflect.DeepEqual(pr.Warnings, fixture.warnings) {
+				t.Errorf("期待値は %v ですが実際には %v でした", fixture.warnings, pr.Warnings)
+			}
+		})
+	}
+	pr := newPullRequest()
+	ResolveUsers(pr, users, false)
+	if pr.Author.Department != "開発部" || pr.Threads[0].Comments[0].Author.Department != "" {
+		t.Errorf("所属が期待通りではありません: %v, %v", pr.Author, pr.Threads[0].Comments[0].Author)
+	}
+}
@@ -0,0 +1,72 @@
+package reviewee
+
+import "strings"
+
+// レビュイーとみなすユーザーの集合。
+// ユーザー名と、コミットに記録された作成者の名前は別々に保持し、それぞれユーザー名と表示名に照合する。
+type Reviewees struct {
+	names map[string]bool
+	// コミットに記録された作成者の名前。ユーザー名を取得できないGitLabのコミット作成者に使用する。
+	authorNames map[string]bool
+}
+
+// プルリクエスト作成者と、カンマ区切りで指定されたレビュイーからレビュイーの集合を構築する。
+func New(author string, reviewees string) *Reviewees {
+	r := &Reviewees{names: make(map[string]bool), authorNames: make(map[string]bool)}
+	r.Add(author)
+	r.Add(strings.Split(reviewees, ",")...)
+	return r
+}
+
+// レビュイーを追加する。空文字列は無視する。
+func (r *Reviewees) Add(names ...string) {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if len(name) > 0 {
+			r.names[name] = true
+		}
+	}
+}
+
+// コミットに記録された作成者の名前をレビュイーとして追加する。空文字列は無視する。
+func (r *Reviewees) AddAuthorName(names ...string) {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if len(name) > 0 {
+			r.authorNames[name] = true
+		}
+	}
+}
+
+// ユーザー名がレビュイーに含まれていればtrueを返す。
+func (r *Reviewees) Contains(name string) bool {
+	return r.names[name]
+}
+
+// 表示名がコミット作成者の名前に含まれていればtrueを返す。
+func (r *Reviewees) ContainsAuthorName(name string) bool {
+	return r.authorNames[name]
+}
+
+// スレッドで回答したレビュイーを出現順に記録する。
+type Respondents struct {
+	names []string
+}
+
+// 回答したレビュイーを追加する。既に追加済みであれば何もしない。
+func (respondents *Respondents) Add(name string) {
+	for _, n := range respondents.names {
+		if n == name {
+			return
+		}
+	}
+	respondents.names = append(respondents.names, name)
+}
+
+// 回答したレビュイーをカンマで繋いで返す。誰も回答していない場合はプルリクエスト作成者を返す。
+func (respondents *Respondents) String(author string) string {
+	if len(respondents.names) == 0 {
+		return author
+	}
+	return strings.Join(respondents.names, ",")
+}
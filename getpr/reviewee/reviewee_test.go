@@ -0,0 +1,68 @@
+package reviewee
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestContains(t *testing.T) {
+	reviewees := New("author", " alice, ,bob ")
+	reviewees.Add("carol", "")
+	fixtures := []struct {
+		input    string
+		expected bool
+	}{
+		{"author", true},
+		{"alice", true},
+		{"bob", true},
+		{"carol", true},
+		{"dave", false},
+		{"", false},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := reviewees.Contains(fixture.input)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %v ですが実際には %v でした", fixture.expected, actual)
+			}
+		})
+	}
+}
+
+func TestContainsAuthorName(t *testing.T) {
+	reviewees := New("author", "")
+	reviewees.AddAuthorName("Bob Smith", " ")
+	// コミット作成者の名前は表示名だけと照合し、ユーザー名とは照合しない
+	if !reviewees.ContainsAuthorName("Bob Smith") {
+		t.Errorf("コミット作成者の名前が表示名に一致しません。")
+	}
+	if reviewees.Contains("Bob Smith") {
+		t.Errorf("コミット作成者の名前がユーザー名に一致しました。")
+	}
+	if reviewees.ContainsAuthorName("author") || reviewees.ContainsAuthorName("") {
+		t.Errorf("ユーザー名が表示名に一致しました。")
+	}
+}
+
+func TestRespondentsString(t *testing.T) {
+	fixtures := []struct {
+		input    []string
+		expected string
+	}{
+		{[]string{}, "author"},
+		{[]string{"alice"}, "alice"},
+		{[]string{"bob", "author", "bob"}, "bob,author"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			respondents := &Respondents{}
+			for _, name := range fixture.input {
+				respondents.Add(name)
+			}
+			actual := respondents.String("author")
+			if actual != fixture.expected {
+				t.Errorf("期待値は %v ですが実際には %v でした", fixture.expected, actual)
+			}
+		})
+	}
+}
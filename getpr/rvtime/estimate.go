@@ -0,0 +1,129 @@
+package rvtime
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// レビュアーの活動（コメントやレビューの投稿）
+type Activity struct {
+	// レビュアーのユーザー名
+	Reviewer string
+	// 活動日時
+	At time.Time
+}
+
+// 推定したレビュー実施記録の最短の長さ。コメントが1件だけでも、読んで書くまでの時間がかかったとみなす。
+const minSessionDuration = 5 * time.Minute
+
+// レビュアーごとに活動日時を並べ、間隔がidleGapを超えたところで区切ってレビュー実施記録を推定する。
+// レビュー実施記録は開始日時の昇順に並べる。複数のレビュアーが並行してレビューした場合、レビュー時間はそれぞれ計上する。
+// 活動は作業の終わりに投稿されるため、minSessionDurationに満たないレビュー実施記録は開始日時を早めて補う。
+func EstimateSessions(activities []Activity, idleGap time.Duration, loc *time.Location) []Session {
+	byReviewer := make(map[string][]time.Time)
+	reviewers := make([]string, 0)
+	for _, activity := range activities {
+		if activity.At.IsZero() {
+			continue
+		}
+		if _, ok := byReviewer[activity.Reviewer]; !ok {
+			reviewers = append(reviewers, activity.Reviewer)
+		}
+		byReviewer[activity.Reviewer] = append(byReviewer[activity.Reviewer], activity.At)
+	}
+
+	type span struct{ start, end time.Time }
+	spans := make([]span, 0)
+	for _, reviewer := range reviewers {
+		times := byReviewer[reviewer]
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		current := span{times[0], times[0]}
+		for _, t := range times[1:] {
+			if t.Sub(current.end) > idleGap {
+				spans = append(spans, current)
+				current = span{t, t}
+			} else {
+				current.end = t
+			}
+		}
+		spans = append(spans, current)
+	}
+	for i := range spans {
+		if spans[i].end.Sub(spans[i].start) < minSessionDuration {
+			spans[i].start = spans[i].end.Add(-minSessionDuration)
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+
+	sessions := make([]Session, 0, len(spans))
+	for _, s := range spans {
+		start := s.start.In(loc)
+		end := s.end.In(loc)
+		sessions = append(sessions, Session{
+			Date:      start.Format("2006/1/2"),
+			StartTime: formatTime(start),
+			EndTime:   formatTime(end),
+			Minutes:   strconv.Itoa(int(end.Sub(start).Minutes())),
+		})
+	}
+	return sessions
+}
+
+// 指定されたレビュー回のレビュー日時コメントがなければ、活動日時から推定したレビュー回を追加する。
+// 推定には、レビュー回数が前のレビュー回の終了後から、後のレビュー回の開始前までの活動だけを使用する。
+// 境界には推定したレビュー回を含めず、レビュー日時コメントのあるレビュー回だけを使用する。
+// 推定に使える活動がない場合は何もしない。
+func (rounds *Rounds) Estimate(reviewTimes string, activities []Activity, idleGap time.Duration, loc *time.Location) {
+	if _, ok := rounds.Get(reviewTimes); ok {
+		return
+	}
+	from, to := rounds.window(reviewTimes, loc)
+	scoped := make([]Activity, 0, len(activities))
+	for _, activity := range activities {
+		if (!from.IsZero() && !activity.At.After(from)) || (!to.IsZero() && !activity.At.Before(to)) {
+			continue
+		}
+		scoped = append(scoped, activity)
+	}
+	sessions := EstimateSessions(scoped, idleGap, loc)
+	if len(sessions) == 0 {
+		return
+	}
+	*rounds = append(*rounds, Round{
+		ReviewTimes: reviewTimes,
+		ReviewTime:  Summarize(sessions),
+		Sessions:    sessions,
+		Estimated:   true,
+		Warnings:    []string{fmt.Sprintf("レビュー%s回目のレビュー日時コメントが見つからないため、コメントの投稿日時からレビュー時間を推定しました。", reviewTimes)},
+	})
+}
+
+// 指定されたレビュー回数の前後にある、レビュー日時コメントのあるレビュー回の間の期間を返す。
+// 前のレビュー回がなければ開始を、後のレビュー回がなければ終了をゼロ値とする。
+func (rounds Rounds) window(reviewTimes string, loc *time.Location) (time.Time, time.Time) {
+	n, _ := strconv.Atoi(reviewTimes)
+	var from, to time.Time
+	for _, round := range rounds {
+		if round.Estimated {
+			continue
+		}
+		m, _ := strconv.Atoi(round.ReviewTimes)
+		if m < n {
+			if endAt := round.EndAt(loc); endAt.After(from) {
+				from = endAt
+			}
+		} else if m > n {
+			if startAt := round.StartAt(loc); !startAt.IsZero() && (to.IsZero() || startAt.Before(to)) {
+				to = startAt
+			}
+		}
+	}
+	return from, to
+}
+
+// レビュー日時コメントと同じ「9:00」の形式で時刻を返す。
+func formatTime(t time.Time) string {
+	return fmt.Sprintf("%d:%02d", t.Hour(), t.Minute())
+}
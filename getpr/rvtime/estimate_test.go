@@ -0,0 +1,111 @@
+package rvtime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEstimateSessions(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, loc)
+	}
+	activities := []Activity{
+		{"alice", at(1, 10, 40)},
+		{"alice", at(1, 10, 0)},
+		{"alice", at(1, 10, 20)},
+		// 間隔が30分を超えるため別のレビュー実施記録になる
+		{"alice", at(1, 11, 20)},
+		{"bob", at(1, 10, 30).UTC()},
+		{"bob", at(1, 10, 55)},
+		// 日時が取得できない活動は無視する
+		{"bob", time.Time{}},
+		{"alice", at(2, 9, 0)},
+		{"alice", at(2, 9, 15)},
+	}
+	expected := []Session{
+		{"2024/5/1", "10:00", "10:40", "40"},
+		{"2024/5/1", "10:30", "10:55", "25"},
+		// 活動が1件だけの場合も最短の長さを計上する
+		{"2024/5/1", "11:15", "11:20", "5"},
+		{"2024/5/2", "9:00", "9:15", "15"},
+	}
+	actual := EstimateSessions(activities, 30*time.Minute, loc)
+	if len(actual) != len(expected) {
+		t.Fatalf("レビュー実施記録の件数が期待通りではありません。期待値：%v 実際の値：%v", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("レビュー実施記録が期待通りではありません。期待値：%v 実際の値：%v", expected[i], actual[i])
+		}
+	}
+}
+
+func TestRoundsEstimate(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	activities := []Activity{
+		{"alice", time.Date(2024, 5, 1, 10, 0, 0, 0, loc)},
+		{"alice", time.Date(2024, 5, 1, 10, 30, 0, 0, loc)},
+	}
+
+	rounds := make(Rounds, 0)
+	declared, _ := ParseRound("レビュー1回目\n2024/5/1\n9:00-9:30", time.Time{})
+	rounds.Add(declared)
+	rounds.Estimate("1", activities, 30*time.Minute, loc)
+	if len(rounds) != 1 || rounds[0].Estimated {
+		t.Errorf("レビュー日時コメントがある場合は推定しません。%v", rounds)
+	}
+
+	rounds.Estimate("2", activities, 30*time.Minute, loc)
+	round, ok := rounds.Get("2")
+	if !ok || !round.Estimated || round.ReviewTime != (ReviewTime{"2024/5/1", "10:00", "10:30", "30"}) || len(round.Warnings) != 1 {
+		t.Errorf("推定したレビュー回が期待通りではありません。%v", round)
+	}
+
+	rounds.Estimate("3", nil, 30*time.Minute, loc)
+	if _, ok := rounds.Get("3"); ok {
+		t.Errorf("活動がない場合は推定しません。%v", rounds)
+	}
+}
+
+func TestRoundsEstimateScoped(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 1, hour, minute, 0, 0, loc)
+	}
+	activities := []Activity{
+		// 1回目の活動
+		{"alice", at(9, 10)},
+		{"alice", at(9, 30)},
+		// 2回目の活動
+		{"alice", at(10, 0)},
+		{"alice", at(10, 30)},
+		// 3回目の活動
+		{"alice", at(14, 0)},
+	}
+	rounds := make(Rounds, 0)
+	for _, input := range []string{"レビュー1回目\n2024/5/1\n9:00-9:30", "レビュー3回目\n2024/5/1\n13:00-14:00"} {
+		round, _ := ParseRound(input, time.Time{})
+		rounds.Add(round)
+	}
+	rounds.Estimate("2", activities, 30*time.Minute, loc)
+	round, ok := rounds.Get("2")
+	if !ok || round.ReviewTime != (ReviewTime{"2024/5/1", "10:00", "10:30", "30"}) {
+		t.Errorf("前後のレビュー回の間の活動だけから推定する想定です。%v", round)
+	}
+}
+
+func TestRoundsMissing(t *testing.T) {
+	rounds := make(Rounds, 0)
+	for _, input := range []string{"レビュー1回目", "レビュー4回目"} {
+		round, _ := ParseRound(input, time.Time{})
+		rounds.Add(round)
+	}
+	if actual := rounds.Missing("1"); !reflect.DeepEqual(actual, []string{"2", "3"}) {
+		t.Errorf("期待値は %v ですが実際には %v でした", []string{"2", "3"}, actual)
+	}
+	if actual := rounds.Missing("5"); !reflect.DeepEqual(actual, []string{"2", "3", "5"}) {
+		t.Errorf("期待値は %v ですが実際には %v でした", []string{"2", "3", "5"}, actual)
+	}
+}
@@ -1,55 +1,347 @@
-package rvtime
-
-import (
-	"regexp"
-)
-
-var regexpMinute = regexp.MustCompile(`\d+`)
-var regexpDate = regexp.MustCompile(`\d{4}/\d{1,2}/\d{1,2}`)
-var regexpTime = regexp.MustCompile(`\d{1,2}:\d{1,2}`)
-var regexpSplit = regexp.MustCompile(`\r\n?|\n`)
-var reviewTimeCommentPattern = regexp.MustCompile(`^(?:- )?レビュー(\d+)回目`)
-
-// 現在のレビュー回数と一致するコメントならtrueを返す。
-func IsCurrentReviewTimeComment(input string, currentReviewTimes string) bool {
-	groups := reviewTimeCommentPattern.FindStringSubmatch(input)
-	return len(groups) > 1 && groups[1] == currentReviewTimes
-}
-
-// レビュー日時コメントならtrueを返す。
-func IsReviewTimeComment(input string) bool {
-	return reviewTimeCommentPattern.MatchString(input)
-}
-
-// レビュー日時コメントをパースする。
-func ParseReviewTime(input string, reviewTimes string) ReviewTime {
-	reviewTime := ReviewTime{}
-	if IsCurrentReviewTimeComment(input, reviewTimes) {
-		splitResult := regexpSplit.Split(input, -1)
-		if len(splitResult) > 1 {
-			reviewTime.ReviewDate = regexpDate.FindString(splitResult[1])
-		}
-		if len(splitResult) > 2 {
-			reviewTime.ReviewStartTime = regexpTime.FindString(splitResult[2])
-		}
-		if len(splitResult) > 3 {
-			reviewTime.ReviewEndTime = regexpTime.FindString(splitResult[3])
-		}
-		if len(splitResult) > 4 {
-			reviewTime.ReviewMinutes = regexpMinute.FindString(splitResult[4])
-		}
-	}
-	return reviewTime
-}
-
-// レビュー日時情報
-type ReviewTime struct {
-	// レビュー日付
-	ReviewDate string
-	// 開始時刻
-	ReviewStartTime string
-	// 終了時刻
-	ReviewEndTime string
-	// レビュー時間
-	ReviewMinutes string
-}
+package rvtime
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var regexpMinute = regexp.MustCompile(`\d+(?:\.\d+)?`)
+var regexpHourMinute = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(?:時間|h|hours?)\s*(?:(\d+)\s*(?:分|m|min|minutes?)?)?$`)
+var regexpDate = regexp.MustCompile(`\d{4}[/-]\d{1,2}[/-]\d{1,2}`)
+var regexpTime = regexp.MustCompile(`\d{1,2}:\d{1,2}`)
+var regexpRange = regexp.MustCompile(`(\d{1,2}:\d{1,2})\s*[-~]\s*(?:\d{4}[/-]\d{1,2}[/-]\d{1,2}\s*)?(\d{1,2}:\d{1,2})`)
+var regexpSplit = regexp.MustCompile(`\r\n?|\n`)
+var regexpLabel = regexp.MustCompile(`^([^\d:][^:]*?)\s*:\s*(.*)$`)
+var regexpListMarker = regexp.MustCompile(`^\s*(?:[-*+]|\d+\.)\s+`)
+var reviewTimeCommentPattern = regexp.MustCompile(`^(?:- )?(?:レビュー(\d+)回目|(?i:review)[ \t]*#?[ \t]*(\d+)[ \t]*:?[ \t]*(?:\r\n?|\n|$))`)
+
+// 全角の数字と記号を半角に変換するための置換。
+var normalizer = strings.NewReplacer(
+	"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+	"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+	"：", ":", "／", "/", "－", "-", "〜", "~", "～", "~",
+	"＃", "#", "　", " ",
+)
+
+// ラベルと項目の対応。ラベルは小文字で比較する。
+var labels = map[string]string{
+	"日付": labelDate, "レビュー日": labelDate, "実施日": labelDate, "date": labelDate,
+	"開始": labelStart, "開始時刻": labelStart, "開始時間": labelStart, "開始日時": labelStart, "start": labelStart,
+	"終了": labelEnd, "終了時刻": labelEnd, "終了時間": labelEnd, "終了日時": labelEnd, "end": labelEnd,
+	"時刻": labelRange, "時間帯": labelRange, "time": labelRange,
+	"レビュー時間": labelMinutes, "所要時間": labelMinutes, "時間": labelMinutes, "minutes": labelMinutes, "duration": labelMinutes,
+}
+
+const (
+	labelDate    = "date"
+	labelStart   = "start"
+	labelEnd     = "end"
+	labelRange   = "range"
+	labelMinutes = "minutes"
+)
+
+// 全角の数字と記号を半角に変換する。
+func normalize(input string) string {
+	return normalizer.Replace(input)
+}
+
+// 現在のレビュー回数と一致するコメントならtrueを返す。
+func IsCurrentReviewTimeComment(input string, currentReviewTimes string) bool {
+	reviewTimes, ok := GetReviewTimes(input)
+	return ok && reviewTimes == currentReviewTimes
+}
+
+// レビュー日時コメントならtrueを返す。
+func IsReviewTimeComment(input string) bool {
+	return reviewTimeCommentPattern.MatchString(normalize(input))
+}
+
+// レビュー日時コメントをパースする。
+func ParseReviewTime(input string, reviewTimes string) ReviewTime {
+	reviewTime, _ := ParseReviewTimeWithWarnings(input, reviewTimes)
+	return reviewTime
+}
+
+// レビュー日時コメントをパースする。コメントに不備があれば警告メッセージを返す。
+// 複数のレビュー実施記録が書かれている場合は、それらをまとめたレビュー日時情報を返す。
+func ParseReviewTimeWithWarnings(input string, reviewTimes string) (ReviewTime, []string) {
+	sessions, warnings := ParseSessions(input, reviewTimes)
+	return Summarize(sessions), warnings
+}
+
+// レビュー日時コメントをパースしてレビュー実施記録の一覧を返す。コメントに不備があれば警告メッセージを返す。
+//
+// 2行目以降は次のいずれの形式でも記述できる。
+//
+//   - 日付、開始時刻、終了時刻、レビュー時間を1行ずつ順番に書く（ラベルは省略できる）
+//   - 「開始: 10:00」のようにラベルを付けて任意の順番で書く
+//   - 「10:00-11:30」のように開始時刻と終了時刻を1行で書く
+//
+// 開始時刻と終了時刻が揃った後に日付や開始時刻が書かれていれば、次のレビュー実施記録とみなす。
+// 日付を省略したレビュー実施記録は直前のレビュー実施記録と同じ日付とみなす。
+// 日付は yyyy/m/d と yyyy-mm-dd の形式、数字は全角でも解釈する。
+// レビュー時間が書かれていない場合は開始時刻と終了時刻から算出し、終了時刻が開始時刻より前であれば日付を跨いだとみなす。
+func ParseSessions(input string, reviewTimes string) ([]Session, []string) {
+	if !IsCurrentReviewTimeComment(input, reviewTimes) {
+		return nil, nil
+	}
+	warnings := make([]string, 0)
+	warn := func(message string) {
+		warnings = append(warnings, fmt.Sprintf("レビュー%s回目のレビュー日時コメント：%s", reviewTimes, message))
+	}
+
+	sessions := []Session{{}}
+	lines := regexpSplit.Split(normalize(input), -1)
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(regexpListMarker.ReplaceAllString(line, ""))
+		if len(line) == 0 {
+			continue
+		}
+		label := ""
+		value := line
+		// 既知のラベルの場合だけラベルと値に分け、それ以外は「Start 10:00」のような行も含めて行全体を値とする
+		if groups := regexpLabel.FindStringSubmatch(line); len(groups) > 2 {
+			if known, ok := labels[strings.ToLower(strings.TrimSpace(groups[1]))]; ok {
+				label = known
+				value = groups[2]
+			}
+		}
+		if sessions[len(sessions)-1].startsNext(label, value, len(sessions) > 1) {
+			sessions = append(sessions, Session{})
+		}
+		if !sessions[len(sessions)-1].parseLine(label, value) {
+			warn(fmt.Sprintf("解釈できない行があります。「%s」", line))
+		}
+	}
+
+	for i := range sessions {
+		session := &sessions[i]
+		prefix := ""
+		if len(sessions) > 1 {
+			prefix = fmt.Sprintf("%d件目の", i+1)
+		}
+		if len(session.Date) == 0 && i > 0 {
+			session.Date = sessions[i-1].Date
+		}
+		if len(session.Date) == 0 {
+			warn(prefix + "レビュー日付が書かれていません。")
+		}
+		if len(session.StartTime) == 0 {
+			warn(prefix + "開始時刻が書かれていません。")
+		}
+		if len(session.EndTime) == 0 {
+			warn(prefix + "終了時刻が書かれていません。")
+		}
+		if len(session.Minutes) == 0 && session.isComplete() {
+			if minutes, ok := betweenMinutes(session.StartTime, session.EndTime); ok {
+				session.Minutes = strconv.Itoa(minutes)
+			} else {
+				warn(prefix + "開始時刻と終了時刻からレビュー時間を算出できません。")
+			}
+		}
+	}
+	return sessions, warnings
+}
+
+// レビュー実施記録の一覧をまとめたレビュー日時情報を返す。
+// 日付と開始時刻は最初の、終了時刻は最も遅く終了したレビュー実施記録のものを使用し、レビュー時間は合計する。
+// 終了日時を比較できないレビュー実施記録がある場合は、最後のレビュー実施記録の終了時刻を使用する。
+func Summarize(sessions []Session) ReviewTime {
+	if len(sessions) == 0 {
+		return ReviewTime{}
+	}
+	if len(sessions) == 1 {
+		session := sessions[0]
+		return ReviewTime{session.Date, session.StartTime, session.EndTime, session.Minutes}
+	}
+	var total int
+	var hasMinutes bool
+	for _, session := range sessions {
+		if minutes, err := strconv.Atoi(session.Minutes); err == nil {
+			total += minutes
+			hasMinutes = true
+		}
+	}
+	// 全てのレビュー実施記録の日付は補完済みのため、タイムゾーンによらず比較できる
+	last := sessions[len(sessions)-1]
+	var latest time.Time
+	for _, session := range sessions {
+		endAt := session.EndAt(time.UTC)
+		if endAt.IsZero() {
+			last = sessions[len(sessions)-1]
+			break
+		}
+		if endAt.After(latest) {
+			latest = endAt
+			last = session
+		}
+	}
+	reviewTime := ReviewTime{
+		ReviewDate:      sessions[0].Date,
+		ReviewStartTime: sessions[0].StartTime,
+		ReviewEndTime:   last.EndTime,
+	}
+	if hasMinutes {
+		reviewTime.ReviewMinutes = strconv.Itoa(total)
+	}
+	return reviewTime
+}
+
+// レビュー日時コメントが見つからない場合の警告メッセージを返す。
+func NotFoundWarning(reviewTimes string) string {
+	return fmt.Sprintf("レビュー%s回目のレビュー日時コメントが見つかりません。", reviewTimes)
+}
+
+// 開始時刻と終了時刻が揃っている状態で、次のレビュー実施記録の始まりとなる行が現れたらtrueを返す。
+// 2件目以降のレビュー実施記録は直前の日付を引き継ぐため、日付がなくても日付の行で区切る。
+func (session *Session) startsNext(label string, value string, inherited bool) bool {
+	if !session.isComplete() {
+		return false
+	}
+	switch label {
+	case labelStart, labelRange:
+		return true
+	case labelDate:
+		return len(session.Date) > 0 || inherited
+	case labelEnd, labelMinutes:
+		return false
+	}
+	if regexpTime.MatchString(value) {
+		return true
+	}
+	return regexpDate.MatchString(value) && (len(session.Date) > 0 || inherited)
+}
+
+// 開始時刻と終了時刻が揃っていればtrueを返す。
+func (session *Session) isComplete() bool {
+	return len(session.StartTime) > 0 && len(session.EndTime) > 0
+}
+
+// 1行分の値を読み取る。ラベルがない場合は値の内容から項目を判断する。読み取れなければfalseを返す。
+func (session *Session) parseLine(label string, value string) bool {
+	date := regexpDate.FindString(value)
+	if len(date) > 0 && (len(session.Date) == 0 || label == labelDate) {
+		session.Date = strings.ReplaceAll(date, "-", "/")
+	}
+	if label == labelDate {
+		return len(date) > 0
+	}
+	if groups := regexpRange.FindStringSubmatch(value); len(groups) > 2 && (label == "" || label == labelRange) {
+		session.StartTime = groups[1]
+		session.EndTime = groups[2]
+		return true
+	}
+	t := regexpTime.FindString(value)
+	switch label {
+	case labelStart:
+		session.StartTime = t
+		return len(t) > 0
+	case labelEnd:
+		session.EndTime = t
+		return len(t) > 0
+	case labelMinutes:
+		session.Minutes = parseMinutes(value)
+		return len(session.Minutes) > 0
+	}
+	if len(t) > 0 {
+		// ラベルがなければ開始時刻、終了時刻の順に埋める
+		if len(session.StartTime) == 0 {
+			session.StartTime = t
+		} else {
+			session.EndTime = t
+		}
+		return true
+	}
+	if len(date) > 0 {
+		return true
+	}
+	if minutes := parseMinutes(value); len(minutes) > 0 {
+		session.Minutes = minutes
+		return true
+	}
+	return false
+}
+
+// レビュー時間を分単位の文字列で返す。「1時間30分」や「1.5h」のような形式も解釈する。
+// 時間単位のない小数は分の端数とみなせないため、解釈できないものとして空文字列を返す。
+func parseMinutes(value string) string {
+	value = strings.TrimSpace(value)
+	if groups := regexpHourMinute.FindStringSubmatch(value); len(groups) > 2 {
+		hours, _ := strconv.ParseFloat(groups[1], 64)
+		minutes, _ := strconv.Atoi(groups[2])
+		return strconv.Itoa(int(math.Round(hours*60)) + minutes)
+	}
+	minutes := regexpMinute.FindString(value)
+	if strings.Contains(minutes, ".") {
+		return ""
+	}
+	return minutes
+}
+
+// 開始時刻から終了時刻までの分数を返す。終了時刻が開始時刻より前であれば日付を跨いだとみなす。
+// 時刻をパースできなければfalseを返す。
+func betweenMinutes(startTime string, endTime string) (int, bool) {
+	start, err := time.Parse("15:04", startTime)
+	if err != nil {
+		return 0, false
+	}
+	end, err := time.Parse("15:04", endTime)
+	if err != nil {
+		return 0, false
+	}
+	if end.Before(start) {
+		end = end.Add(24 * time.Hour)
+	}
+	return int(end.Sub(start).Minutes()), true
+}
+
+// レビュー日時情報
+type ReviewTime struct {
+	// レビュー日付
+	ReviewDate string
+	// 開始時刻
+	ReviewStartTime string
+	// 終了時刻
+	ReviewEndTime string
+	// レビュー時間
+	ReviewMinutes string
+}
+
+// 1回分のレビュー実施記録
+type Session struct {
+	// 日付
+	Date string
+	// 開始時刻
+	StartTime string
+	// 終了時刻
+	EndTime string
+	// レビュー時間
+	Minutes string
+}
+
+// 終了日時を返す。終了時刻が開始時刻より前であれば日付を跨いだとみなす。
+// 日付か時刻が書かれていない場合はゼロ値を返す。
+func (session Session) EndAt(loc *time.Location) time.Time {
+	endAt, err := time.ParseInLocation("2006/1/2 15:04", session.Date+" "+session.EndTime, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	start, err := time.Parse("15:04", session.StartTime)
+	if err != nil {
+		return endAt
+	}
+	if end, _ := time.Parse("15:04", session.EndTime); end.Before(start) {
+		// 日付は開始日のものなので、日付を跨いだ場合は終了日時を翌日にする
+		endAt = endAt.AddDate(0, 0, 1)
+	}
+	return endAt
+}
+
+// 「日付 開始時刻-終了時刻」の形式の文字列を返す。
+func (session Session) String() string {
+	return strings.TrimSpace(session.Date + " " + session.StartTime + "-" + session.EndTime)
+}
@@ -70,7 +70,7 @@ func TestParseReviewTime2(t *testing.T) {
 - 終了時刻：2023/4/14 11:30
 			`,
 			"1",
-			ReviewTime{"2023/4/14", "9:00", "11:30", ""}, true},
+			ReviewTime{"2023/4/14", "9:00", "11:30", "150"}, true},
 
 		{"レビュー時間と終了時刻が抜けているケース", `
 - レビュー1回目
@@ -152,3 +152,110 @@ type Fixture2 struct {
 	expected1   ReviewTime
 	expected2   bool
 }
+
+func TestParseReviewTimeWithWarnings(t *testing.T) {
+	fixtures := []struct {
+		name        string
+		input       string
+		reviewTimes string
+		expected    ReviewTime
+		warnings    int
+	}{
+		{"ISO形式の日付", "レビュー1回目\n2024-05-01\n10:00\n11:30", "1",
+			ReviewTime{"2024/05/01", "10:00", "11:30", "90"}, 0},
+		{"全角数字", "レビュー１回目\n日付：２０２４／５／１\n開始：１０：００\n終了：１１：００", "1",
+			ReviewTime{"2024/5/1", "10:00", "11:00", "60"}, 0},
+		{"英語のラベル", "Review #2\nDate: 2024-05-01\nStart: 10:00\nEnd: 10:45", "2",
+			ReviewTime{"2024/05/01", "10:00", "10:45", "45"}, 0},
+		{"順不同のラベル", "- レビュー1回目\n- 終了: 11:30\n- レビュー時間: 1時間15分\n- 開始: 10:00\n- 日付: 2024/5/1", "1",
+			ReviewTime{"2024/5/1", "10:00", "11:30", "75"}, 0},
+		{"開始時刻と終了時刻が1行", "レビュー3回目\n2024/5/1 10:00-11:30", "3",
+			ReviewTime{"2024/5/1", "10:00", "11:30", "90"}, 0},
+		{"開始時刻と終了時刻が1行（波ダッシュ）", "レビュー1回目\n日付: 2024/5/1\n時刻: 10:00〜10:30", "1",
+			ReviewTime{"2024/5/1", "10:00", "10:30", "30"}, 0},
+		{"終了時刻がない", "レビュー1回目\n2024/5/1\n10:00", "1",
+			ReviewTime{"2024/5/1", "10:00", "", ""}, 1},
+		{"解釈できない行", "レビュー1回目\n2024/5/1\n10:00-11:00\n備考: 特になし", "1",
+			ReviewTime{"2024/5/1", "10:00", "11:00", "60"}, 1},
+		{"ラベルでない英単語", "レビュー1回目\n2024/5/1\nStart 10:00\nEnd 11:00", "1",
+			ReviewTime{"2024/5/1", "10:00", "11:00", "60"}, 0},
+		{"ラベルでない英単語（日付なし）", "レビュー1回目\nStart 10:00\nEnd 11:00", "1",
+			ReviewTime{"", "10:00", "11:00", "60"}, 1},
+		{"小数の時間", "レビュー1回目\n2024/5/1\n10:00-11:40\nレビュー時間: 1.5h", "1",
+			ReviewTime{"2024/5/1", "10:00", "11:40", "90"}, 0},
+		{"単位のない小数", "レビュー1回目\n2024/5/1\n10:00-11:40\nレビュー時間: 1.5", "1",
+			ReviewTime{"2024/5/1", "10:00", "11:40", "100"}, 1},
+		{"日付を跨ぐ", "レビュー1回目\n2024/5/1\n23:30-0:45", "1",
+			ReviewTime{"2024/5/1", "23:30", "0:45", "75"}, 0},
+		{"複数のレビュー実施記録", "レビュー1回目\n2024/5/1 10:00-11:00\n13:00-13:30\n2024/5/2\n開始: 9:00\n終了: 9:40\nレビュー時間: 30", "1",
+			ReviewTime{"2024/5/1", "10:00", "9:40", "120"}, 0},
+		{"2件目の終了時刻がない", "レビュー1回目\n2024/5/1\n10:00\n11:00\n2024/5/2\n9:00", "1",
+			ReviewTime{"2024/5/1", "10:00", "", "60"}, 1},
+		{"レビュー回数が異なる", "Review 2\n2024/5/1\n10:00-11:00", "1",
+			ReviewTime{}, 0},
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			actual, warnings := ParseReviewTimeWithWarnings(fixture.input, fixture.reviewTimes)
+			if actual != fixture.expected {
+				t.Errorf("抽出した値が期待通りではありません。期待値：%v 実際の値：%v", fixture.expected, actual)
+			}
+			if len(warnings) != fixture.warnings {
+				t.Errorf("警告の件数が期待通りではありません。期待値：%v 実際の値：%v", fixture.warnings, warnings)
+			}
+		})
+	}
+}
+
+func TestParseSessions(t *testing.T) {
+	input := "レビュー1回目\n- 2024/5/1 22:30-23:30\n- 23:45-0:30\n- 2024/5/3 10:00-10:20"
+	expected := []Session{
+		{"2024/5/1", "22:30", "23:30", "60"},
+		{"2024/5/1", "23:45", "0:30", "45"},
+		{"2024/5/3", "10:00", "10:20", "20"},
+	}
+	actual, warnings := ParseSessions(input, "1")
+	if len(warnings) > 0 {
+		t.Errorf("警告は期待していません。実際の値：%v", warnings)
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("レビュー実施記録の件数が期待通りではありません。期待値：%v 実際の値：%v", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("レビュー実施記録が期待通りではありません。期待値：%v 実際の値：%v", expected[i], actual[i])
+		}
+	}
+	if reviewTime := Summarize(actual); reviewTime != (ReviewTime{"2024/5/1", "22:30", "10:20", "125"}) {
+		t.Errorf("レビュー日時情報が期待通りではありません。実際の値：%v", reviewTime)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	fixtures := []struct {
+		name     string
+		sessions []Session
+		expected ReviewTime
+	}{
+		{"後のレビュー実施記録が先に終わる", []Session{
+			{"2024/5/1", "10:00", "12:00", "120"},
+			{"2024/5/1", "11:00", "11:30", "30"},
+		}, ReviewTime{"2024/5/1", "10:00", "12:00", "150"}},
+		{"日付を跨ぐ", []Session{
+			{"2024/5/1", "23:00", "1:00", "120"},
+			{"2024/5/1", "23:30", "23:45", "15"},
+		}, ReviewTime{"2024/5/1", "23:00", "1:00", "135"}},
+		{"終了時刻がない", []Session{
+			{"2024/5/1", "10:00", "12:00", "120"},
+			{"2024/5/1", "11:00", "", ""},
+		}, ReviewTime{"2024/5/1", "10:00", "", "120"}},
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			actual := Summarize(fixture.sessions)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %v ですが実際には %v でした", fixture.expected, actual)
+			}
+		})
+	}
+}
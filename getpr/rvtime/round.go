@@ -0,0 +1,163 @@
+package rvtime
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// レビュー日時コメントのレビュー回数を返す。レビュー日時コメントでなければfalseを返す。
+func GetReviewTimes(input string) (string, bool) {
+	groups := reviewTimeCommentPattern.FindStringSubmatch(normalize(input))
+	if len(groups) == 0 {
+		return "", false
+	}
+	// 日本語の形式と英語の形式のうち、マッチした方のレビュー回数を返す
+	if len(groups[1]) > 0 {
+		return groups[1], true
+	}
+	return groups[2], true
+}
+
+// レビュー回ごとのレビュー日時情報
+type Round struct {
+	// レビュー回数
+	ReviewTimes string
+	// レビュー日時情報。複数のレビュー実施記録がある場合はそれらをまとめたもの。
+	ReviewTime ReviewTime
+	// レビュー実施記録の一覧
+	Sessions []Session
+	// レビュー日時コメントの投稿日時。取得できない場合はゼロ値。
+	PostedAt time.Time
+	// レビュー日時コメントの不備に関する警告メッセージ
+	Warnings []string
+	// レビュー日時コメントではなく活動日時から推定した場合はtrue
+	Estimated bool
+}
+
+// レビュー日時コメントをパースしてレビュー回を返す。レビュー日時コメントでなければfalseを返す。
+func ParseRound(input string, postedAt time.Time) (Round, bool) {
+	reviewTimes, ok := GetReviewTimes(input)
+	if !ok {
+		return Round{}, false
+	}
+	sessions, warnings := ParseSessions(input, reviewTimes)
+	return Round{
+		ReviewTimes: reviewTimes,
+		ReviewTime:  Summarize(sessions),
+		Sessions:    sessions,
+		PostedAt:    postedAt,
+		Warnings:    warnings,
+	}, true
+}
+
+// レビューの開始日時を返す。
+// レビュー日付と開始時刻が書かれていなければレビュー日時コメントの投稿日時を返す。
+func (round Round) StartAt(loc *time.Location) time.Time {
+	reviewTime := round.ReviewTime
+	if len(reviewTime.ReviewDate) > 0 && len(reviewTime.ReviewStartTime) > 0 {
+		startAt, err := time.ParseInLocation("2006/1/2 15:04", reviewTime.ReviewDate+" "+reviewTime.ReviewStartTime, loc)
+		if err == nil {
+			return startAt
+		}
+	}
+	return round.PostedAt
+}
+
+// レビューの終了日時を返す。複数のレビュー実施記録がある場合は最も遅い終了日時を返す。
+// 終了日時が書かれていなければ開始日時を返す。
+func (round Round) EndAt(loc *time.Location) time.Time {
+	var endAt time.Time
+	for _, session := range round.Sessions {
+		if t := session.EndAt(loc); t.After(endAt) {
+			endAt = t
+		}
+	}
+	if endAt.IsZero() {
+		return round.StartAt(loc)
+	}
+	return endAt
+}
+
+// レビュー回の一覧
+type Rounds []Round
+
+// レビュー回を追加する。
+// 同じレビュー回数のレビュー回が既にあれば、レビュー実施記録を追加した順に連結して1つのレビュー回にまとめる。
+func (rounds *Rounds) Add(round Round) {
+	for i, r := range *rounds {
+		if r.ReviewTimes == round.ReviewTimes {
+			r.Sessions = append(r.Sessions, round.Sessions...)
+			r.ReviewTime = Summarize(r.Sessions)
+			r.Warnings = append(r.Warnings, round.Warnings...)
+			if r.PostedAt.IsZero() || (!round.PostedAt.IsZero() && round.PostedAt.Before(r.PostedAt)) {
+				r.PostedAt = round.PostedAt
+			}
+			(*rounds)[i] = r
+			return
+		}
+	}
+	*rounds = append(*rounds, round)
+}
+
+// 指定されたレビュー回数のレビュー回を返す。見つからなければfalseを返す。
+func (rounds Rounds) Get(reviewTimes string) (Round, bool) {
+	for _, round := range rounds {
+		if round.ReviewTimes == reviewTimes {
+			return round, true
+		}
+	}
+	return Round{}, false
+}
+
+// 1回目から、指定されたレビュー回数とレビュー日時コメントのある最大のレビュー回数のうち大きい方までで、
+// レビュー回がないレビュー回数を昇順に返す。
+func (rounds Rounds) Missing(reviewTimes string) []string {
+	last, _ := strconv.Atoi(reviewTimes)
+	for _, round := range rounds {
+		if n, err := strconv.Atoi(round.ReviewTimes); err == nil && n > last {
+			last = n
+		}
+	}
+	missing := make([]string, 0)
+	for n := 1; n <= last; n++ {
+		if _, ok := rounds.Get(strconv.Itoa(n)); !ok {
+			missing = append(missing, strconv.Itoa(n))
+		}
+	}
+	return missing
+}
+
+// レビュー回数の昇順に並べ替える。
+func (rounds Rounds) Sort() {
+	sort.SliceStable(rounds, func(i, j int) bool {
+		a, _ := strconv.Atoi(rounds[i].ReviewTimes)
+		b, _ := strconv.Atoi(rounds[j].ReviewTimes)
+		return a < b
+	})
+}
+
+// 指摘の作成日時が属するレビュー回数を返す。
+// 開始日時が作成日時以前であるレビュー回のうち、最も遅く開始したものを選ぶ。
+// 該当するレビュー回がない場合や作成日時が取得できない場合は最初のレビュー回を返す。
+func (rounds Rounds) Find(createdAt time.Time, loc *time.Location) string {
+	if len(rounds) == 0 {
+		return ""
+	}
+	found := rounds[0].ReviewTimes
+	if createdAt.IsZero() {
+		return found
+	}
+	var latest time.Time
+	for _, round := range rounds {
+		startAt := round.StartAt(loc)
+		if startAt.IsZero() || startAt.After(createdAt) {
+			continue
+		}
+		if latest.IsZero() || !startAt.Before(latest) {
+			latest = startAt
+			found = round.ReviewTimes
+		}
+	}
+	return found
+}
@@ -0,0 +1,72 @@
+package rvtime
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetReviewTimes(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"- レビュー1回目\r\n- 日付：2023/4/14", "1", true},
+		{"レビュー12回目", "12", true},
+		{"まったく関係のないコメント", "", false},
+	}
+	for i, fixture := range fixtures {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual, ok := GetReviewTimes(fixture.input)
+			if actual != fixture.expected || ok != fixture.ok {
+				t.Errorf("期待値は %v, %v ですが実際には %v, %v でした", fixture.expected, fixture.ok, actual, ok)
+			}
+		})
+	}
+}
+
+func TestRoundsFind(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	rounds := make(Rounds, 0)
+	for _, input := range []string{
+		"- レビュー2回目\n- 2023/4/20\n- 13:00\n- 15:00\n- 120",
+		"- レビュー1回目\n- 2023/4/14\n- 9:00\n- 11:30\n- 150",
+		"- レビュー1回目\n- 2023/4/30\n- 9:00\n- 11:30\n- 150",
+		// 日付と開始時刻がない場合は投稿日時を開始日時とみなす
+		"- レビュー3回目",
+	} {
+		round, ok := ParseRound(input, time.Date(2023, 5, 1, 10, 0, 0, 0, loc))
+		if !ok {
+			t.Fatalf("レビュー日時コメントとして扱われませんでした。%v", input)
+		}
+		rounds.Add(round)
+	}
+	rounds.Sort()
+	if len(rounds) != 3 || rounds[0].ReviewTimes != "1" || rounds[1].ReviewTimes != "2" || rounds[2].ReviewTimes != "3" {
+		t.Fatalf("レビュー回が期待通りではありません。%v", rounds)
+	}
+	if len(rounds[0].Sessions) != 2 || rounds[0].ReviewTime.ReviewDate != "2023/4/14" || rounds[0].ReviewTime.ReviewMinutes != "300" {
+		t.Fatalf("同じレビュー回数のレビュー回がまとめられていません。%v", rounds[0])
+	}
+
+	fixtures := []struct {
+		createdAt time.Time
+		expected  string
+	}{
+		{time.Time{}, "1"},
+		{time.Date(2023, 4, 1, 0, 0, 0, 0, loc), "1"},
+		{time.Date(2023, 4, 14, 9, 0, 0, 0, loc), "1"},
+		{time.Date(2023, 4, 20, 3, 59, 0, 0, time.UTC), "1"},
+		{time.Date(2023, 4, 20, 4, 0, 0, 0, time.UTC), "2"},
+		{time.Date(2023, 5, 2, 0, 0, 0, 0, loc), "3"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual := rounds.Find(fixture.createdAt, loc)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %v ですが実際には %v でした", fixture.expected, actual)
+			}
+		})
+	}
+}
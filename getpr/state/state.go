@@ -0,0 +1,116 @@
+// 前回までに書き出した指摘を記録し、新しい指摘や変化のあった指摘だけを抽出するためのパッケージ。
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+)
+
+// 指摘の変更種別
+const (
+	// 新しい指摘
+	New = "new"
+	// 既存の指摘への新しい返信
+	Reply = "reply"
+	// 解決状態の変化
+	Status = "status"
+	// 既存のコメントの編集
+	Edited = "edited"
+)
+
+// 書き出し済みの指摘の状態。スレッドのIDをキーとする。
+type State struct {
+	Threads map[string]Thread `json:"threads"`
+}
+
+// 書き出し済みのスレッドの状態
+type Thread struct {
+	// 解決状態
+	Resolved bool `json:"resolved"`
+	// コメントのIDごとの本文のハッシュ値。GitBucketのように更新日時を取得できない場合も編集を検出できるよう、本文を比較する。
+	// 通常のコメントの本文にはデリミタの後に書いた回答も含む。
+	Comments map[string]string `json:"comments"`
+}
+
+// 状態のファイルを読み込む。ファイルが存在しない場合は空の状態を返し、全ての指摘を新しい指摘とみなす。
+func Load(path string) (*State, error) {
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &State{Threads: map[string]Thread{}}, nil
+	}
+	if err != nil {
+		return nil, errors.New("状態のファイルを読み込めません。since-stateの設定を見直してください。")
+	}
+	var state State
+	if err := json.Unmarshal(bs, &state); err != nil {
+		return nil, errors.New("状態のファイルの形式が不正です。")
+	}
+	if state.Threads == nil {
+		state.Threads = map[string]Thread{}
+	}
+	return &state, nil
+}
+
+// 状態をファイルへ保存する。ファイルには指摘のIDが含まれるため、所有者だけが読み書きできるようにする。
+func (state *State) Save(path string) error {
+	bs, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, bs, 0600); err != nil {
+		return errors.New("状態のファイルを保存できません。since-stateの設定を見直してください。")
+	}
+	return nil
+}
+
+// レビュー情報の各スレッドに、記録済みの状態からの変更種別を設定し、レビュー情報の状態で記録を更新する。
+//
+// 変化のないスレッドの変更種別は空にする。複数の変化がある場合は、新しい指摘、返信、解決状態、編集の順に優先する。
+func (state *State) Mark(pr *review.PullRequest) {
+	for i := range pr.Threads {
+		thread := &pr.Threads[i]
+		thread.Change = state.change(*thread)
+		current := Thread{Resolved: thread.Resolved, Comments: make(map[string]string, len(thread.Comments))}
+		for _, comment := range thread.Comments {
+			current.Comments[comment.Id] = hash(comment.Body)
+		}
+		state.Threads[thread.Id] = current
+	}
+}
+
+// 記録済みの状態からのスレッドの変更種別を返す。
+func (state *State) change(thread review.Thread) string {
+	previous, ok := state.Threads[thread.Id]
+	if !ok {
+		return New
+	}
+	edited := false
+	for _, comment := range thread.Comments {
+		previousHash, ok := previous.Comments[comment.Id]
+		if !ok {
+			return Reply
+		}
+		if previousHash != hash(comment.Body) {
+			edited = true
+		}
+	}
+	if previous.Resolved != thread.Resolved {
+		return Status
+	}
+	if edited {
+		return Edited
+	}
+	return ""
+}
+
+// コメント本文のハッシュ値を返す。
+func hash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+)
+
+var base = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func newPullRequest() *review.PullRequest {
+	return &review.PullRequest{Threads: []review.Thread{
+		{Id: "t1", Comments: []review.Comment{{Id: "c1", UpdatedAt: base}}},
+		{Id: "t2", Comments: []review.Comment{{Id: "c2", UpdatedAt: base}}},
+		{Id: "t3", Comments: []review.Comment{{Id: "c3", UpdatedAt: base}}},
+		{Id: "t4", Comments: []review.Comment{{Id: "c4", UpdatedAt: base}}},
+	}}
+}
+
+func TestMark(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := newPullRequest()
+	state.Mark(first)
+	for _, thread := range first.Threads {
+		if thread.Change != New {
+			t.Errorf("期待値は %v ですが実際には %v でした", New, thread.Change)
+		}
+	}
+	if err := state.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	// Windowsではファイルのモードを設定できないため確認しない
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("状態のファイルは所有者だけが読み書きできる想定です。%v", info.Mode())
+	}
+
+	state, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := newPullRequest()
+	second.Threads[1].Comments = append(second.Threads[1].Comments, review.Comment{Id: "c5", UpdatedAt: base})
+	second.Threads[2].Resolved = true
+	// GitBucketのように更新日時が変わらなくても、本文が変われば編集とみなす
+	second.Threads[3].Comments[0].Body = "編集後"
+	// 本文が変わらなければ、更新日時が変わっても編集とみなさない
+	second.Threads[0].Comments[0].UpdatedAt = base.Add(time.Hour)
+	second.Threads = append(second.Threads, review.Thread{Id: "t5"})
+	state.Mark(second)
+	expected := []string{"", Reply, Status, Edited, New}
+	for i, thread := range second.Threads {
+		if thread.Change != expected[i] {
+			t.Errorf("%s: 期待値は %v ですが実際には %v でした", thread.Id, expected[i], thread.Change)
+		}
+	}
+
+	third := newPullRequest()
+	state.Mark(third)
+	if third.Threads[2].Change != Status {
+		t.Errorf("期待値は %v ですが実際には %v でした", Status, third.Threads[2].Change)
+	}
+}
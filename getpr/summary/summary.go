@@ -0,0 +1,68 @@
+// 抽出したレビュー情報のまとめを、プルリクエストへ投稿するコメントにするパッケージ。
+package summary
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/metrics"
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+)
+
+// まとめのコメントを見分けるための目印。マークダウンでは表示されない。
+const Marker = "<!-- getpr:summary -->"
+
+// まとめのコメントをレビュー情報から取り除き、そのコメントのIDを返す。見つからない場合は空文字列を返す。
+//
+// まとめのコメントは通常のコメントとして取得されるため、取り除かなければ指摘とみなされる。
+func Exclude(pr *review.PullRequest) string {
+	var id string
+	threads := make([]review.Thread, 0, len(pr.Threads))
+	for _, thread := range pr.Threads {
+		if len(thread.Comments) > 0 && strings.Contains(thread.Comments[0].Body, Marker) {
+			if id == "" {
+				id = thread.Comments[0].Id
+			}
+			continue
+		}
+		threads = append(threads, thread)
+	}
+	pr.Threads = threads
+	return id
+}
+
+// レビュー情報から、指摘件数、解決済みと未解決の件数、レビュー時間、欠陥密度をまとめたコメントの本文を構築する。
+func Build(pr *review.PullRequest) string {
+	m := metrics.Compute(pr)
+	resolved, unresolved := m.Resolution()
+	resolution := "-"
+	if resolved+unresolved > 0 {
+		resolution = strconv.Itoa(resolved) + " / " + strconv.Itoa(unresolved)
+	}
+	rows := [][]string{
+		{"指摘件数", strconv.Itoa(m.Findings)},
+		{"解決済み / 未解決", resolution},
+		{"レビュー時間（分）", strconv.Itoa(m.ReviewMinutes)},
+		{"変更行数", strconv.Itoa(m.Additions + m.Deletions)},
+		{"欠陥密度（指摘件数/KLOC）", formatFloat(m.FindingsPerKloc)},
+		{"指摘件数/時間", formatFloat(m.FindingsPerHour)},
+	}
+	builder := &strings.Builder{}
+	builder.WriteString(Marker + "\n")
+	builder.WriteString("### レビューのまとめ\n\n")
+	builder.WriteString("| 項目 | 値 |\n")
+	builder.WriteString("| --- | --- |\n")
+	for _, row := range rows {
+		builder.WriteString("| " + row[0] + " | " + row[1] + " |\n")
+	}
+	builder.WriteString("\ngetprで抽出したレビュー情報から算出しています。再実行するとこのコメントを更新します。\n")
+	return builder.String()
+}
+
+// 小数第2位までの文字列に変換する。算出できない場合は「-」を返す。
+func formatFloat(value *float64) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*value, 'f', 2, 64)
+}
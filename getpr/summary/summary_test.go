@@ -0,0 +1,64 @@
+package summary
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/review"
+	"github.com/Fintan-contents/review-support-tool/getpr/rvtime"
+)
+
+var (
+	alice  = review.Participant{Login: "alice"}
+	author = review.Participant{Login: "author", Reviewee: true}
+)
+
+func newPullRequest() *review.PullRequest {
+	return &review.PullRequest{
+		Url:       "u",
+		DiffStats: review.DiffStats{Additions: 150, Deletions: 50},
+		Threads: []review.Thread{
+			{Id: "t1", Threaded: true, HasResolvedStatus: true, Resolved: true, Comments: []review.Comment{
+				{Id: "c1", Author: alice, Body: "指摘1"},
+				{Id: "c2", Author: author, Body: "対応1"},
+			}},
+			{Id: "t2", Comments: []review.Comment{{Id: "c3", Author: author, Body: Marker + "\n以前のまとめ"}}},
+			{Id: "t3", Threaded: true, HasResolvedStatus: true, Comments: []review.Comment{
+				{Id: "c4", Author: alice, Body: "指摘2", CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+			}},
+		},
+		Rounds: []review.ReviewRound{{ReviewTimes: "1", ReviewTime: rvtime.ReviewTime{ReviewMinutes: "30"}}},
+	}
+}
+
+func TestExclude(t *testing.T) {
+	pr := newPullRequest()
+	if id := Exclude(pr); id != "c3" {
+		t.Errorf("期待値は %v ですが実際には %v でした", "c3", id)
+	}
+	if len(pr.Threads) != 2 || pr.Threads[0].Id != "t1" || pr.Threads[1].Id != "t3" {
+		t.Errorf("まとめのコメントが取り除かれていません: %v", pr.Threads)
+	}
+	if id := Exclude(pr); id != "" {
+		t.Errorf("期待値は空文字列ですが実際には %v でした", id)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	pr := newPullRequest()
+	Exclude(pr)
+	expected := Marker + "\n" +
+		"### レビューのまとめ\n\n" +
+		"| 項目 | 値 |\n" +
+		"| --- | --- |\n" +
+		"| 指摘件数 | 2 |\n" +
+		"| 解決済み / 未解決 | 1 / 1 |\n" +
+		"| レビュー時間（分） | 30 |\n" +
+		"| 変更行数 | 200 |\n" +
+		"| 欠陥密度（指摘件数/KLOC） | 10.00 |\n" +
+		"| 指摘件数/時間 | 4.00 |\n" +
+		"\ngetprで抽出したレビュー情報から算出しています。再実行するとこのコメントを更新します。\n"
+	if actual := Build(pr); actual != expected {
+		t.Errorf("期待値は %v ですが実際には %v でした", expected, actual)
+	}
+}
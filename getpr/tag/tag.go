@@ -0,0 +1,264 @@
+// コメントの先頭に書かれたタグから、指摘の重要度と種別を読み取るパッケージ。
+package tag
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// タグの読み取り規則。
+type Rules struct {
+	// 重要度ごとの、重要度を表すタグの一覧
+	Severities map[string][]string `json:"severities"`
+	// 種別ごとの、種別を表すタグの一覧
+	Categories map[string][]string `json:"categories"`
+	// 「重要度:高」のように重要度を書く行のラベル
+	SeverityLabels []string `json:"severityLabels"`
+	// 「指摘種別:設計」のように種別を書く行のラベル
+	CategoryLabels []string `json:"categoryLabels"`
+}
+
+// 既定の読み取り規則を返す。
+func DefaultRules() *Rules {
+	return &Rules{
+		Severities: map[string][]string{
+			"must": {"must", "blocking", "必須", "🔴"},
+			"imo":  {"imo", "should", "non-blocking", "推奨", "🟡"},
+			"nits": {"nits", "nit", "nitpick", "軽微", "🟢"},
+		},
+		Categories: map[string][]string{
+			"指摘":   {"issue", "指摘"},
+			"質問":   {"question", "q", "質問", "❓"},
+			"提案":   {"suggestion", "提案", "💡"},
+			"称賛":   {"praise", "👍"},
+			"TODO": {"todo"},
+			"メモ":   {"note", "thought", "chore", "メモ"},
+		},
+		SeverityLabels: []string{"重要度", "severity"},
+		CategoryLabels: []string{"指摘種別", "種別", "分類", "category"},
+	}
+}
+
+// JSONファイルから読み取り規則を読み込む。パスが空の場合は既定の読み取り規則を返す。
+func LoadRules(path string) (*Rules, error) {
+	if path == "" {
+		return DefaultRules(), nil
+	}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("タグの読み取り規則のファイルを読み込めません。tag-rulesの設定を見直してください。")
+	}
+	var rules Rules
+	if err := json.Unmarshal(bs, &rules); err != nil {
+		return nil, errors.New("タグの読み取り規則のファイルの形式が不正です。")
+	}
+	return &rules, nil
+}
+
+// コメントから読み取ったタグ。読み取れなかった項目は空文字列。
+type Tag struct {
+	// 重要度
+	Severity string
+	// 種別
+	Category string
+}
+
+// 同じ種類のタグが既に読み取られていなければ設定する。
+func (t *Tag) set(k kind, value string) {
+	if k == severity && t.Severity == "" {
+		t.Severity = value
+	} else if k == category && t.Category == "" {
+		t.Category = value
+	}
+}
+
+// タグの種類
+type kind int
+
+const (
+	severity kind = iota
+	category
+)
+
+// 先頭の [must] や【質問】のような括弧で囲んだタグ
+var bracketPattern = regexp.MustCompile(`^[\[【]([^\]】\r\n]+)[\]】][ \t　]*`)
+
+// Conventional Comments の「issue (blocking, if-minor): 」のようなラベルと装飾
+var conventionalPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z-]*)[ \t]*(?:\(([^)\r\n]*)\))?:(?:[ \t]+|\r?\n|$)`)
+
+// コメントの先頭のタグと、「指摘種別:設計」のようなラベル付きの行から重要度と種別を読み取る。
+// 読み取ったタグとラベル付きの行を取り除いた本文を合わせて返す。
+//
+// 先頭のタグは括弧で囲んだタグ、Conventional Comments のラベル、絵文字の順に、読み取れる限り繰り返し読み取る。
+// 同じ種類のタグが複数ある場合は先に書かれたものを使用する。
+func (rules *Rules) Extract(body string) (Tag, string) {
+	markers := rules.markers()
+	var result Tag
+	set := result.set
+
+	rest := markers.prefix(strings.TrimLeft(body, " \t　"), set)
+
+	lines := strings.Split(rest, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if k, value, ok := rules.label(strings.TrimRight(line, "\r"), markers); ok {
+			set(k, value)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	// 末尾の行を取り除いた場合に改行が残らないようにする
+	return result, strings.TrimRight(strings.Join(kept, "\n"), "\r\n")
+}
+
+// HTMLのテキストノードのように行に分かれた本文から、Extractと同じく重要度と種別を読み取る。
+// 読み取ったタグを取り除いた行を返す。タグだけの行とラベル付きの行は空文字列にし、行数は変えない。
+// タグだけの行に続く行からも、先頭のタグを続けて読み取る。改行を含む行は、改行で区切った行ごとにラベル付きの行か判定する。
+func (rules *Rules) ExtractLines(lines []string) (Tag, []string) {
+	markers := rules.markers()
+	var result Tag
+	set := result.set
+
+	kept := make([]string, len(lines))
+	copy(kept, lines)
+	for i, line := range kept {
+		kept[i] = strings.TrimLeft(markers.prefix(strings.TrimLeft(line, " \t　"), set), " \t　")
+		if kept[i] != "" {
+			break
+		}
+	}
+	for i, line := range kept {
+		// 改行を含む行は、Extractと同じく改行で区切った行ごとにラベル付きの行か判定する
+		parts := strings.Split(line, "\n")
+		rest := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if k, value, ok := rules.label(strings.TrimRight(part, "\r"), markers); ok {
+				set(k, value)
+				continue
+			}
+			rest = append(rest, part)
+		}
+		kept[i] = strings.TrimRight(strings.Join(rest, "\n"), "\r\n")
+	}
+	return result, kept
+}
+
+// 先頭のタグを読み取れる限り繰り返し読み取ってsetに渡し、タグを取り除いた残りを返す。
+func (markers markers) prefix(rest string, set func(kind, string)) string {
+	for {
+		if match := bracketPattern.FindStringSubmatch(rest); match != nil {
+			if m, ok := markers[normalize(match[1])]; ok {
+				set(m.kind, m.value)
+				rest = rest[len(match[0]):]
+				continue
+			}
+		}
+		if match := conventionalPattern.FindStringSubmatch(rest); match != nil {
+			if m, ok := markers[normalize(match[1])]; ok {
+				set(m.kind, m.value)
+				for _, decoration := range strings.Split(match[2], ",") {
+					if m, ok := markers[normalize(decoration)]; ok {
+						set(m.kind, m.value)
+					}
+				}
+				rest = rest[len(match[0]):]
+				continue
+			}
+		}
+		if emoji, m, ok := markers.emojiPrefix(rest); ok {
+			set(m.kind, m.value)
+			rest = strings.TrimLeft(rest[len(emoji):], " \t　")
+			continue
+		}
+		break
+	}
+	return rest
+}
+
+// 「指摘種別:設計」のようなラベル付きの行であれば、種類と値を返す。
+// 値が読み取り規則のタグであればタグの重要度や種別を、そうでなければ値をそのまま返す。
+func (rules *Rules) label(line string, markers markers) (kind, string, bool) {
+	name, value, found := strings.Cut(strings.Replace(strings.TrimSpace(line), "：", ":", 1), ":")
+	if !found {
+		return 0, "", false
+	}
+	name, value = normalize(name), strings.TrimSpace(value)
+	if value == "" {
+		return 0, "", false
+	}
+	for _, labels := range []struct {
+		kind   kind
+		labels []string
+	}{{severity, rules.SeverityLabels}, {category, rules.CategoryLabels}} {
+		for _, label := range labels.labels {
+			if normalize(label) != name {
+				continue
+			}
+			if m, ok := markers[normalize(value)]; ok && m.kind == labels.kind {
+				return m.kind, m.value, true
+			}
+			return labels.kind, value, true
+		}
+	}
+	return 0, "", false
+}
+
+// タグから重要度や種別を引くための表
+type markers map[string]marker
+
+type marker struct {
+	kind  kind
+	value string
+}
+
+func (rules *Rules) markers() markers {
+	result := markers{}
+	// 同じタグが複数の重要度や種別に書かれていても結果が変わらないように、名前の順に登録する
+	for _, group := range []struct {
+		kind   kind
+		values map[string][]string
+	}{{severity, rules.Severities}, {category, rules.Categories}} {
+		names := make([]string, 0, len(group.values))
+		for name := range group.values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, tag := range group.values[name] {
+				if _, ok := result[normalize(tag)]; !ok {
+					result[normalize(tag)] = marker{group.kind, name}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// 本文が文字や数字で始まらないタグ（絵文字）で始まっていれば、そのタグを返す。
+func (markers markers) emojiPrefix(body string) (string, marker, bool) {
+	var longest string
+	for tag := range markers {
+		first, _ := utf8.DecodeRuneInString(tag)
+		if unicode.IsLetter(first) || unicode.IsDigit(first) {
+			continue
+		}
+		if strings.HasPrefix(body, tag) && len(tag) > len(longest) {
+			longest = tag
+		}
+	}
+	if longest == "" {
+		return "", marker{}, false
+	}
+	return longest, markers[longest], true
+}
+
+// 大文字と小文字、前後の空白を区別せずにタグを比較するために正規化する。
+func normalize(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
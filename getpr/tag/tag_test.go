@@ -0,0 +1,65 @@
+package tag
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		severity string
+		category string
+		body     string
+	}{
+		{"[must] nilチェックが漏れています。", "must", "", "nilチェックが漏れています。"},
+		{"【質問】【IMO】この処理は必要ですか。", "imo", "質問", "この処理は必要ですか。"},
+		{"[nits][must] 先に書いたタグを使う", "nits", "", "先に書いたタグを使う"},
+		{"issue (blocking, if-minor): 例外を握りつぶしています。", "must", "指摘", "例外を握りつぶしています。"},
+		{"suggestion: 共通化しませんか。", "", "提案", "共通化しませんか。"},
+		{"question:\r\n共通化しませんか。", "", "質問", "共通化しませんか。"},
+		{"💡🟢 変数名を短くできます。", "nits", "提案", "変数名を短くできます。"},
+		{"命名を見直してください。\r\n指摘種別：設計\r\n重要度: must", "must", "設計", "命名を見直してください。"},
+		{"[WIP] 未知のタグは残す", "", "", "[WIP] 未知のタグは残す"},
+		{"note that: 文中のコロンは残す", "", "", "note that: 文中のコロンは残す"},
+		{"レビュー1回目\r\n2024/5/1", "", "", "レビュー1回目\r\n2024/5/1"},
+	}
+	rules := DefaultRules()
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			tag, body := rules.Extract(fixture.input)
+			if tag.Severity != fixture.severity || tag.Category != fixture.category {
+				t.Errorf("タグが期待通りではありません。期待値：%v/%v 実際の値：%v/%v", fixture.severity, fixture.category, tag.Severity, tag.Category)
+			}
+			if body != fixture.body {
+				t.Errorf("本文が期待通りではありません。期待値：%q 実際の値：%q", fixture.body, body)
+			}
+		})
+	}
+}
+
+func TestExtractLines(t *testing.T) {
+	fixtures := []struct {
+		input    []string
+		severity string
+		category string
+		lines    []string
+	}{
+		{[]string{"[must] nilチェックが漏れています。"}, "must", "", []string{"nilチェックが漏れています。"}},
+		{[]string{"[must]", "suggestion:", "共通化しませんか。"}, "must", "提案", []string{"", "", "共通化しませんか。"}},
+		{[]string{"命名を見直してください。", "指摘種別：設計", "コード\n重要度: must"}, "must", "設計", []string{"命名を見直してください。", "", "コード"}},
+		{[]string{"[WIP] 未知のタグは残す", "[must] 先頭以外は残す"}, "", "", []string{"[WIP] 未知のタグは残す", "[must] 先頭以外は残す"}},
+	}
+	rules := DefaultRules()
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			tag, lines := rules.ExtractLines(fixture.input)
+			if tag.Severity != fixture.severity || tag.Category != fixture.category {
+				t.Errorf("タグが期待通りではありません。期待値：%v/%v 実際の値：%v/%v", fixture.severity, fixture.category, tag.Severity, tag.Category)
+			}
+			if fmt.Sprintf("%q", lines) != fmt.Sprintf("%q", fixture.lines) {
+				t.Errorf("行が期待通りではありません。期待値：%q 実際の値：%q", fixture.lines, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package text
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	suggestionPattern = regexp.MustCompile("^[ \t]*```+[ \t]*suggestion\\b")
+	fenceStartPattern = regexp.MustCompile("^[ \t]*(```+|~~~+)")
+)
+
+// 改行で分割する。CRLFとLFのどちらにも対応する。
+func splitLines(comment string) []string {
+	return strings.Split(strings.ReplaceAll(comment, Crlf, "\n"), "\n")
+}
+
+// 行をCRLFで繋ぎ、前後の空行を取り除く。
+func joinLines(lines []string) string {
+	return strings.Trim(strings.Join(lines, Crlf), Crlf)
+}
+
+// コードブロックを閉じる行であればtrueを返す。開いた行と同じ記号を同じ数以上並べた行で閉じる。
+func closesFence(line string, fence string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, fence) && len(strings.Trim(line, fence[:1])) == 0
+}
+
+// 引用（行頭が>）の行を取り除く。引用の直後の空行も取り除き、コードブロックの中の行は残す。
+func StripQuotes(comment string) string {
+	lines := splitLines(comment)
+	result := make([]string, 0, len(lines))
+	var fence string
+	quoted := false
+	for _, line := range lines {
+		if fence == "" {
+			if match := fenceStartPattern.FindStringSubmatch(line); match != nil {
+				fence = match[1]
+			}
+		} else if closesFence(line, fence) {
+			fence = ""
+		} else {
+			result = append(result, line)
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(line, " \t"), ">") {
+			quoted = true
+			continue
+		}
+		if quoted && strings.TrimSpace(line) == "" {
+			continue
+		}
+		quoted = false
+		result = append(result, line)
+	}
+	return joinLines(result)
+}
+
+// GitHubの変更の提案（```suggestion のコードブロック）を「提案: `コード`」の形式にする。
+// 複数行の提案は「提案:」の後に言語の指定のないコードブロックを続け、空の提案（行の削除）は「提案: (削除)」にする。
+// 提案のコードブロックは、開いた行以上の長さの囲みの行で閉じる。
+func RenderSuggestions(comment string) string {
+	lines := splitLines(comment)
+	result := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		match := suggestionPattern.FindString(lines[i])
+		if match == "" {
+			result = append(result, lines[i])
+			continue
+		}
+		fence := strings.TrimSpace(strings.TrimSuffix(match, "suggestion"))
+		code := make([]string, 0)
+		for i++; i < len(lines) && !closesFence(lines[i], fence); i++ {
+			code = append(code, lines[i])
+		}
+		switch len(code) {
+		case 0:
+			result = append(result, "提案: (削除)")
+		case 1:
+			result = append(result, "提案: `"+code[0]+"`")
+		default:
+			result = append(result, "提案:", "```")
+			result = append(result, code...)
+			result = append(result, "```")
+		}
+	}
+	return joinLines(result)
+}
+
+// 署名の区切り（-- の行）から末尾までを取り除く。区切りの後が5行を超える場合は署名とみなさない。
+func StripSignature(comment string) string {
+	lines := splitLines(comment)
+	for i := len(lines) - 1; i >= 0 && len(lines)-i <= 6; i-- {
+		if strings.TrimRight(lines[i], " \t") == "--" && (i == 0 || strings.TrimSpace(lines[i-1]) == "") {
+			return joinLines(lines[:i])
+		}
+	}
+	return joinLines(lines)
+}
@@ -0,0 +1,69 @@
+package text
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStripQuotes(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+	}{
+		{"> nilチェックが漏れています。\r\n\r\n修正しました。", "修正しました。"},
+		{"> 1点目\n> 2点目\n対応しました。\n> 3点目\n\nこちらも対応しました。", "対応しました。\r\nこちらも対応しました。"},
+		{"```\n> go test\n```\n>> 引用", "```\r\n> go test\r\n```"},
+		{"引用なし", "引用なし"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := StripQuotes(fixture.input)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRenderSuggestions(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+	}{
+		{"こう書けます。\r\n```suggestion\r\nreturn nil\r\n```", "こう書けます。\r\n提案: `return nil`"},
+		{"```suggestion\nif err != nil {\n\treturn err\n}\n```\n以上です。", "提案:\r\n```\r\nif err != nil {\r\n\treturn err\r\n}\r\n```\r\n以上です。"},
+		{"不要です。\n````suggestion\n````", "不要です。\r\n提案: (削除)"},
+		{"```go\nreturn nil\n```", "```go\r\nreturn nil\r\n```"},
+		// 開いた行より短い囲みは提案のコードとして扱い、開いた行以上の長さで末尾に空白のある囲みで閉じる
+		{"````suggestion\n```go\nx := 1\n```\n`````  \n以上です。", "提案:\r\n```\r\n```go\r\nx := 1\r\n```\r\n```\r\n以上です。"},
+		{"```suggestion\nreturn nil\n``` \t\n以上です。", "提案: `return nil`\r\n以上です。"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := RenderSuggestions(fixture.input)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
+
+func TestStripSignature(t *testing.T) {
+	fixtures := []struct {
+		input    string
+		expected string
+	}{
+		{"修正しました。\r\n\r\n-- \r\n山田太郎\r\n開発部", "修正しました。"},
+		{"修正しました。\n\n--\n山田", "修正しました。"},
+		{"見出し\n--\n本文", "見出し\r\n--\r\n本文"},
+		{"修正しました。\n\n--\n1\n2\n3\n4\n5\n6", "修正しました。\r\n\r\n--\r\n1\r\n2\r\n3\r\n4\r\n5\r\n6"},
+	}
+	for i, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			actual := StripSignature(fixture.input)
+			if actual != fixture.expected {
+				t.Errorf("期待値は %q ですが実際には %q でした", fixture.expected, actual)
+			}
+		})
+	}
+}
@@ -1,11 +1,93 @@
 package text
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+)
 
 const Crlf = "\r\n"
 
-// コメントをデリミタで分割する
-func SplitComment(comment string, delimiter string) (string, string) {
-	reviewerComment, revieweeComment, _ := strings.Cut(Crlf+comment+Crlf, Crlf+delimiter+Crlf)
-	return strings.TrimSpace(reviewerComment), strings.TrimSpace(revieweeComment)
+// レビュアーのコメントとレビュイーのコメントを分けるデリミタ。
+type Delimiter struct {
+	// 行全体と比較する文字列の一覧
+	literals []string
+	// 行全体に一致させる正規表現
+	pattern *regexp.Regexp
+}
+
+// 文字列の一覧と正規表現からデリミタを構築する。文字列はカンマを含めてそのまま行と比較し、どちらも空にできる。
+func NewDelimiter(literals []string, pattern string) (*Delimiter, error) {
+	delimiter := &Delimiter{}
+	for _, literal := range literals {
+		if literal = strings.TrimSpace(literal); len(literal) > 0 {
+			delimiter.literals = append(delimiter.literals, literal)
+		}
+	}
+	if len(pattern) > 0 {
+		compiled, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, err
+		}
+		delimiter.pattern = compiled
+	}
+	return delimiter, nil
+}
+
+// 前後の空白を除いた行がデリミタであればtrueを返す。
+func (delimiter *Delimiter) Match(line string) bool {
+	line = strings.TrimSpace(line)
+	if len(line) == 0 {
+		return false
+	}
+	for _, literal := range delimiter.literals {
+		if line == literal {
+			return true
+		}
+	}
+	return delimiter.pattern != nil && delimiter.pattern.MatchString(line)
+}
+
+// コメントをデリミタの行で分割する。改行はCRLFにそろえ、コードブロックの中の行はデリミタとみなさない。
+func (delimiter *Delimiter) Split(comment string) []string {
+	parts := make([]string, 0, 2)
+	current := make([]string, 0)
+	var fence string
+	for _, line := range splitLines(comment) {
+		if len(fence) > 0 {
+			if closesFence(line, fence) {
+				fence = ""
+			}
+		} else if delimiter.Match(line) {
+			parts = append(parts, strings.TrimSpace(strings.Join(current, Crlf)))
+			current = current[:0]
+			continue
+		} else if match := fenceStartPattern.FindStringSubmatch(line); match != nil {
+			fence = match[1]
+		}
+		current = append(current, line)
+	}
+	return append(parts, strings.TrimSpace(strings.Join(current, Crlf)))
+}
+
+// コメントをデリミタで分割し、レビュアーのコメントとレビュイーのコメントを返す。
+// 分割した部分はレビュアーとレビュイーのコメントが交互に並んでいるものとみなし、それぞれseparatorで繋ぐ。
+func SplitComment(comment string, delimiter *Delimiter, separator string) (string, string) {
+	return Alternate(delimiter.Split(comment), separator)
+}
+
+// 交互に並んだレビュアーとレビュイーのコメントを、それぞれseparatorで繋ぐ。空のコメントは除く。
+func Alternate(parts []string, separator string) (string, string) {
+	reviewerComments := make([]string, 0, len(parts))
+	revieweeComments := make([]string, 0, len(parts))
+	for i, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+		if i%2 == 0 {
+			reviewerComments = append(reviewerComments, part)
+		} else {
+			revieweeComments = append(revieweeComments, part)
+		}
+	}
+	return strings.Join(reviewerComments, separator), strings.Join(revieweeComments, separator)
 }
@@ -17,12 +17,23 @@ func TestSplitComment(t *testing.T) {
 		{"---\r\nbar", "", "bar"},
 		{"", "", ""},
 		{"---", "", ""},
-		{"foo\r\n\r\n---\r\n\r\nbar\r\n\r\n---\r\n\r\nbaz\r\n", "foo", "bar\r\n\r\n---\r\n\r\nbaz"},
+		{"foo\r\n\r\n---\r\n\r\nbar\r\n\r\n---\r\n\r\nbaz\r\n", "foo\r\n(追記)baz", "bar"},
+		{"foo\n  ---  \nbar\nbaz", "foo", "bar\r\nbaz"},
+		{"foo\n~~\nbar", "foo", "bar"},
+		{"foo\n```\n---\n```\n---\nbar", "foo\r\n```\r\n---\r\n```", "bar"},
+		{"foo\n====\nbar\n---\nbaz\n=\nqux", "foo\r\n(追記)baz", "bar\r\n(追記)qux"},
+		{"foo --- bar", "foo --- bar", ""},
+		// カンマを含むデリミタもそのまま比較する
+		{"foo\n--,--\nbar", "foo", "bar"},
+		{"foo\n--\nbar", "foo\r\n--\r\nbar", ""},
+	}
+	delimiter, err := NewDelimiter([]string{"---", " ~~ ", "--,--"}, "=+")
+	if err != nil {
+		t.Fatal(err)
 	}
-	const delimiter = "---"
 	for i, fixture := range fixtures {
 		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
-			actual1, actual2 := SplitComment(fixture.input, delimiter)
+			actual1, actual2 := SplitComment(fixture.input, delimiter, Crlf+"(追記)")
 			if actual1 != fixture.expected1 {
 				t.Errorf("期待値は %v ですが実際には %v でした", fixture.expected1, actual1)
 			} else if actual2 != fixture.expected2 {
@@ -31,3 +42,9 @@ func TestSplitComment(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDelimiter(t *testing.T) {
+	if _, err := NewDelimiter([]string{"~~"}, "[a-"); err == nil {
+		t.Error("不正な正規表現でエラーが発生しませんでした")
+	}
+}
@@ -0,0 +1,190 @@
+// GitHub、GitLab、GiteaのWebhookを検証し、レビュー情報を抽出し直すべきプルリクエスト（マージリクエスト）を読み取るパッケージ。
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/summary"
+)
+
+// 署名やトークンを検証できない場合のエラー
+var ErrUnauthorized = errors.New("Webhookの署名を検証できません。webhook-secretの設定を見直してください。")
+
+// レビュー情報を抽出し直すきっかけとなったイベント
+type Event struct {
+	// Gitホスティングサービス。github、gitlab、giteaのいずれかの値。
+	Target string
+	// オーガニゼーション。GitLabは空。
+	Org string
+	// リポジトリ名（GitHub、Gitea）、またはプロジェクトID（GitLab）
+	Repo string
+	// プルリクエスト（マージリクエスト）のID
+	Pull string
+	// イベントの種類と操作。ログに書き出すためのもの。
+	Action string
+}
+
+// リクエストのヘッダーと本文から署名やトークンを検証し、イベントを読み取る。
+//
+// プルリクエストのマージやクローズ、レビューのイベントのみを対象とし、それ以外のイベントはnilを返す。
+// 署名やトークンを検証できない場合はErrUnauthorizedを返す。
+func Parse(header http.Header, body []byte, secret string) (*Event, error) {
+	switch {
+	case header.Get("X-Gitea-Event") != "":
+		// GiteaはGitHub互換のヘッダーも送信するため、GitHubより先に判定する
+		if !verifySignature(header.Get("X-Gitea-Signature"), "", body, secret) {
+			return nil, ErrUnauthorized
+		}
+		return parseGitea(header.Get("X-Gitea-Event"), body)
+	case header.Get("X-GitHub-Event") != "":
+		if !verifySignature(header.Get("X-Hub-Signature-256"), "sha256=", body, secret) {
+			return nil, ErrUnauthorized
+		}
+		return parseGitHub(header.Get("X-GitHub-Event"), body)
+	case header.Get("X-Gitlab-Event") != "":
+		if subtle.ConstantTimeCompare([]byte(header.Get("X-Gitlab-Token")), []byte(secret)) != 1 {
+			return nil, ErrUnauthorized
+		}
+		return parseGitLab(header.Get("X-Gitlab-Event"), body)
+	}
+	return nil, errors.New("GitHub、GitLab、GiteaのWebhookではありません。")
+}
+
+// 本文のHMAC-SHA256が署名と一致すればtrueを返す。署名はprefixに続けて16進数で書かれている。
+func verifySignature(signature string, prefix string, body []byte, secret string) bool {
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil || !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// GitHubのWebhookの本文のうち、getprが使用する項目
+type gitHubPayload struct {
+	Action      string `json:"action"`
+	PullRequest struct {
+		Number int  `json:"number"`
+		Merged bool `json:"merged"`
+	} `json:"pull_request"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+}
+
+// プルリクエストがマージやクローズされたとき（pull_requestのclosed）と、レビューが送信されたとき（pull_request_reviewのsubmitted）を対象とする。
+func parseGitHub(event string, body []byte) (*Event, error) {
+	var payload gitHubPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("Webhookの本文の形式が不正です。")
+	}
+	action := event + " " + payload.Action
+	switch {
+	case event == "pull_request" && payload.Action == "closed":
+		if payload.PullRequest.Merged {
+			action = event + " merged"
+		}
+	case event == "pull_request_review" && payload.Action == "submitted":
+	default:
+		return nil, nil
+	}
+	return &Event{
+		Target: "github",
+		Org:    payload.Repository.Owner.Login,
+		Repo:   payload.Repository.Name,
+		Pull:   strconv.Itoa(payload.PullRequest.Number),
+		Action: action,
+	}, nil
+}
+
+// プルリクエストがマージやクローズされたとき（pull_requestのclosed）と、レビューが送信されたとき（pull_request_approved、pull_request_rejected、pull_request_comment）を対象とする。
+// 本文の形式はGitHubと同じ。
+func parseGitea(event string, body []byte) (*Event, error) {
+	var payload gitHubPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("Webhookの本文の形式が不正です。")
+	}
+	action := event + " " + payload.Action
+	switch {
+	case event == "pull_request" && payload.Action == "closed":
+		if payload.PullRequest.Merged {
+			action = event + " merged"
+		}
+	case event == "pull_request_approved", event == "pull_request_rejected", event == "pull_request_comment":
+	default:
+		return nil, nil
+	}
+	return &Event{
+		Target: "gitea",
+		Org:    payload.Repository.Owner.Login,
+		Repo:   payload.Repository.Name,
+		Pull:   strconv.Itoa(payload.PullRequest.Number),
+		Action: action,
+	}, nil
+}
+
+// GitLabのWebhookの本文のうち、getprが使用する項目
+type gitLabPayload struct {
+	Project struct {
+		Id int `json:"id"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		Iid    int    `json:"iid"`
+		Action string `json:"action"`
+		// コメントの対象の種類（Note Hook）。マージリクエストへのコメントはMergeRequest。
+		NoteableType string `json:"noteable_type"`
+		// コメントの本文（Note Hook）
+		Note string `json:"note"`
+	} `json:"object_attributes"`
+	// コメントの対象のマージリクエスト（Note Hook）
+	MergeRequest struct {
+		Iid int `json:"iid"`
+	} `json:"merge_request"`
+}
+
+// マージリクエストがマージやクローズ、承認されたときと、マージリクエストにコメント（レビューコメントを含む）が投稿されたときを対象とする。
+// ただし、getprが投稿したまとめのコメントは対象外とする。
+func parseGitLab(event string, body []byte) (*Event, error) {
+	if event != "Merge Request Hook" && event != "Note Hook" {
+		return nil, nil
+	}
+	var payload gitLabPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("Webhookの本文の形式が不正です。")
+	}
+	if event == "Note Hook" {
+		// post-summaryで投稿したまとめのコメントで抽出し直すと、まとめの更新が繰り返されるため対象外にする
+		if payload.ObjectAttributes.NoteableType != "MergeRequest" || strings.Contains(payload.ObjectAttributes.Note, summary.Marker) {
+			return nil, nil
+		}
+		return &Event{
+			Target: "gitlab",
+			Repo:   strconv.Itoa(payload.Project.Id),
+			Pull:   strconv.Itoa(payload.MergeRequest.Iid),
+			Action: "note merge_request",
+		}, nil
+	}
+	switch payload.ObjectAttributes.Action {
+	case "merge", "close", "approved", "approval":
+	default:
+		return nil, nil
+	}
+	return &Event{
+		Target: "gitlab",
+		Repo:   strconv.Itoa(payload.Project.Id),
+		Pull:   strconv.Itoa(payload.ObjectAttributes.Iid),
+		Action: "merge_request " + payload.ObjectAttributes.Action,
+	}, nil
+}
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Fintan-contents/review-support-tool/getpr/summary"
+)
+
+const secret = "secret"
+
+// 本文をシークレットで署名したHMAC-SHA256を16進数で返す。
+func sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func header(values map[string]string) http.Header {
+	h := http.Header{}
+	for key, value := range values {
+		h.Set(key, value)
+	}
+	return h
+}
+
+func TestParse(t *testing.T) {
+	fixtures := []struct {
+		name     string
+		header   http.Header
+		body     string
+		expected *Event
+	}{
+		{
+			"GitHubのマージ",
+			header(map[string]string{"X-GitHub-Event": "pull_request"}),
+			`{"action":"closed","pull_request":{"number":3,"merged":true},"repository":{"name":"repo","owner":{"login":"org"}}}`,
+			&Event{Target: "github", Org: "org", Repo: "repo", Pull: "3", Action: "pull_request merged"},
+		},
+		{
+			"GitHubのレビュー",
+			header(map[string]string{"X-GitHub-Event": "pull_request_review"}),
+			`{"action":"submitted","pull_request":{"number":3},"repository":{"name":"repo","owner":{"login":"org"}}}`,
+			&Event{Target: "github", Org: "org", Repo: "repo", Pull: "3", Action: "pull_request_review submitted"},
+		},
+		{
+			"GitHubの対象外のイベント",
+			header(map[string]string{"X-GitHub-Event": "push"}),
+			`{}`,
+			nil,
+		},
+		{
+			// GiteaはGitHub互換のヘッダーも送信する
+			"Giteaのマージ",
+			header(map[string]string{"X-Gitea-Event": "pull_request", "X-GitHub-Event": "pull_request"}),
+			`{"action":"closed","pull_request":{"number":3,"merged":true},"repository":{"name":"repo","owner":{"login":"org"}}}`,
+			&Event{Target: "gitea", Org: "org", Repo: "repo", Pull: "3", Action: "pull_request merged"},
+		},
+		{
+			"Giteaのレビュー",
+			header(map[string]string{"X-Gitea-Event": "pull_request_comment", "X-GitHub-Event": "pull_request_comment"}),
+			`{"action":"reviewed","pull_request":{"number":3},"repository":{"name":"repo","owner":{"login":"org"}}}`,
+			&Event{Target: "gitea", Org: "org", Repo: "repo", Pull: "3", Action: "pull_request_comment reviewed"},
+		},
+		{
+			"Giteaのコメント",
+			header(map[string]string{"X-Gitea-Event": "issue_comment", "X-GitHub-Event": "issue_comment"}),
+			`{"action":"created","issue":{"number":3},"repository":{"name":"repo","owner":{"login":"org"}}}`,
+			nil,
+		},
+		{
+			"GitLabの承認",
+			header(map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": secret}),
+			`{"project":{"id":12},"object_attributes":{"iid":5,"action":"approved"}}`,
+			&Event{Target: "gitlab", Repo: "12", Pull: "5", Action: "merge_request approved"},
+		},
+		{
+			"GitLabのレビューコメント",
+			header(map[string]string{"X-Gitlab-Event": "Note Hook", "X-Gitlab-Token": secret}),
+			`{"object_kind":"note","project":{"id":12},"object_attributes":{"noteable_type":"MergeRequest","type":"DiffNote"},"merge_request":{"iid":5}}`,
+			&Event{Target: "gitlab", Repo: "12", Pull: "5", Action: "note merge_request"},
+		},
+		{
+			"GitLabのまとめのコメント",
+			header(map[string]string{"X-Gitlab-Event": "Note Hook", "X-Gitlab-Token": secret}),
+			`{"object_kind":"note","project":{"id":12},"object_attributes":{"noteable_type":"MergeRequest","note":"## レビューのまとめ\n` + summary.Marker + `"},"merge_request":{"iid":5}}`,
+			nil,
+		},
+		{
+			"GitLabのイシューへのコメント",
+			header(map[string]string{"X-Gitlab-Event": "Note Hook", "X-Gitlab-Token": secret}),
+			`{"object_kind":"note","project":{"id":12},"object_attributes":{"noteable_type":"Issue"},"issue":{"iid":5}}`,
+			nil,
+		},
+		{
+			"GitLabの対象外の操作",
+			header(map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": secret}),
+			`{"project":{"id":12},"object_attributes":{"iid":5,"action":"open"}}`,
+			nil,
+		},
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			body := []byte(fixture.body)
+			if fixture.header.Get("X-Gitea-Event") != "" {
+				fixture.header.Set("X-Gitea-Signature", sign(body))
+			} else if fixture.header.Get("X-GitHub-Event") != "" {
+				fixture.header.Set("X-Hub-Signature-256", "sha256="+sign(body))
+			}
+			event, err := Parse(fixture.header, body, secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(event, fixture.expected) {
+				t.Errorf("期待値は %v ですが実際には %v でした", fixture.expected, event)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	body := []byte(`{}`)
+	fixtures := []struct {
+		name     string
+		header   http.Header
+		expected string
+	}{
+		{"GitHubの署名の誤り", header(map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": "sha256=" + sign([]byte(`{"action":"closed"}`))}), ErrUnauthorized.Error()},
+		{"GitHubの署名なし", header(map[string]string{"X-GitHub-Event": "pull_request"}), ErrUnauthorized.Error()},
+		{"GitLabのトークンの誤り", header(map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": "wrong"}), ErrUnauthorized.Error()},
+		// GitHub互換の署名が正しくても、Giteaの署名で検証する
+		{"Giteaの署名の誤り", header(map[string]string{"X-Gitea-Event": "pull_request", "X-Gitea-Signature": sign([]byte(`{"action":"closed"}`)), "X-GitHub-Event": "pull_request", "X-Hub-Signature-256": "sha256=" + sign(body)}), ErrUnauthorized.Error()},
+		{"Giteaの署名なし", header(map[string]string{"X-Gitea-Event": "pull_request"}), ErrUnauthorized.Error()},
+		{"不明な送信元", header(map[string]string{}), "GitHub、GitLab、GiteaのWebhookではありません。"},
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			_, err := Parse(fixture.header, body, secret)
+			if err == nil || err.Error() != fixture.expected {
+				t.Errorf("期待値は %v ですが実際には %v でした", fixture.expected, err)
+			}
+		})
+	}
+}